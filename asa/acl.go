@@ -67,7 +67,7 @@ type UserACL struct {
 func (s *AccessControlListService) GetUserACL(ctx context.Context) (*UserACLListResponse, *Response, error) {
 	url := "acls"
 	res := new(UserACLListResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"AccessControlList", "GetUserACL"}, url, nil, res)
 
 	return res, resp, err
 }
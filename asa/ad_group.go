@@ -257,7 +257,7 @@ type AdGroupListResponse struct {
 func (s *AdGroupService) CreateAdGroup(ctx context.Context, campaignID int64, adGroup *AdGroup) (*AdGroupResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups", campaignID)
 	res := new(AdGroupResponse)
-	resp, err := s.client.post(ctx, url, adGroup, res)
+	resp, err := s.client.post(ctx, operationName{"AdGroups", "CreateAdGroup"}, url, adGroup, res)
 
 	return res, resp, err
 }
@@ -268,18 +268,19 @@ type GetAllAdGroupsQuery struct {
 	Offset int32 `url:"offset,omitempty"`
 }
 
-// AdGroupUpdateRequest is the response to ad group update requests
+// AdGroupUpdateRequest is the response to ad group update requests. Nil and empty fields are left unchanged, so
+// AutomatedKeywordsOptIn: Bool(false) opts out of automated keywords while a nil value keeps the current setting.
 //
 // https://developer.apple.com/documentation/apple_search_ads/adgroupupdate
 type AdGroupUpdateRequest struct {
-	AutomatedKeywordsOptIn bool              `json:"automatedKeywordsOptIn,omitempty"`
+	AutomatedKeywordsOptIn *bool             `json:"automatedKeywordsOptIn,omitempty"`
 	CpaGoal                *Money            `json:"cpaGoal,omitempty"`
 	DefaultBidAmount       *Money            `json:"defaultBidAmount,omitempty"`
-	EndTime                DateTime          `json:"endTime,omitempty"`
+	EndTime                *DateTime         `json:"endTime,omitempty"`
 	Name                   string            `json:"name,omitempty"`
-	StartTime              DateTime          `json:"startTime,omitempty"`
+	StartTime              *DateTime         `json:"startTime,omitempty"`
 	Status                 AdGroupStatus     `json:"status,omitempty"`
-	TargetingDimensions    *TargetDimensions `json:"targetingDimensions"`
+	TargetingDimensions    *TargetDimensions `json:"targetingDimensions,omitempty"`
 }
 
 // ConditionOperator is the operator values compare attributes to a list of specified values.
@@ -365,7 +366,7 @@ type Pagination struct {
 func (s *AdGroupService) FindAdGroups(ctx context.Context, campaignID int64, selector *Selector) (*AdGroupListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/find", campaignID)
 	res := new(AdGroupListResponse)
-	resp, err := s.client.post(ctx, url, selector, res)
+	resp, err := s.client.post(ctx, operationName{"AdGroups", "FindAdGroups"}, url, selector, res)
 
 	return res, resp, err
 }
@@ -376,7 +377,7 @@ func (s *AdGroupService) FindAdGroups(ctx context.Context, campaignID int64, sel
 func (s *AdGroupService) GetAdGroup(ctx context.Context, campaignID int64, adGroupID int64) (*AdGroupResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d", campaignID, adGroupID)
 	res := new(AdGroupResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"AdGroups", "GetAdGroup"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -387,7 +388,7 @@ func (s *AdGroupService) GetAdGroup(ctx context.Context, campaignID int64, adGro
 func (s *AdGroupService) GetAllAdGroups(ctx context.Context, campaignID int64, params *GetAllAdGroupsQuery) (*AdGroupListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups", campaignID)
 	res := new(AdGroupListResponse)
-	resp, err := s.client.get(ctx, url, &params, res)
+	resp, err := s.client.get(ctx, operationName{"AdGroups", "GetAllAdGroups"}, url, &params, res)
 
 	return res, resp, err
 }
@@ -398,7 +399,7 @@ func (s *AdGroupService) GetAllAdGroups(ctx context.Context, campaignID int64, p
 func (s *AdGroupService) UpdateAdGroup(ctx context.Context, campaignID int64, adGroupID int64, req *AdGroupUpdateRequest) (*AdGroupResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d", campaignID, adGroupID)
 	res := new(AdGroupResponse)
-	resp, err := s.client.put(ctx, url, req, res)
+	resp, err := s.client.put(ctx, operationName{"AdGroups", "UpdateAdGroup"}, url, req, res)
 
 	return res, resp, err
 }
@@ -408,7 +409,27 @@ func (s *AdGroupService) UpdateAdGroup(ctx context.Context, campaignID int64, ad
 // https://developer.apple.com/documentation/apple_search_ads/delete_an_adgroup
 func (s *AdGroupService) DeleteAdGroup(ctx context.Context, campaignID int64, adGroupID int64) (*Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d", campaignID, adGroupID)
-	resp, err := s.client.delete(ctx, url, nil)
+	resp, err := s.client.delete(ctx, operationName{"AdGroups", "DeleteAdGroup"}, url, nil)
 
 	return resp, err
 }
+
+// listAllAdGroups pages through GetAllAdGroups and returns every ad group of a campaign.
+func (s *AdGroupService) listAllAdGroups(ctx context.Context, campaignID int64) ([]*AdGroup, error) {
+	var adGroups []*AdGroup
+
+	for offset := 0; ; {
+		res, _, err := s.GetAllAdGroups(ctx, campaignID, &GetAllAdGroupsQuery{Limit: findPageLimit, Offset: int32(offset)})
+		if err != nil {
+			return nil, err
+		}
+
+		adGroups = append(adGroups, res.AdGroups...)
+
+		if !hasNextPage(res.Pagination, offset, len(res.AdGroups)) {
+			return adGroups, nil
+		}
+
+		offset += len(res.AdGroups)
+	}
+}
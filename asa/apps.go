@@ -58,7 +58,7 @@ type AppInfo struct {
 func (s *AppService) SearchApps(ctx context.Context, params *SearchAppsQuery) (*AppInfoListResponse, *Response, error) {
 	url := "search/apps"
 	res := new(AppInfoListResponse)
-	resp, err := s.client.get(ctx, url, &params, res)
+	resp, err := s.client.get(ctx, operationName{"App", "SearchApps"}, url, &params, res)
 
 	return res, resp, err
 }
@@ -20,8 +20,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httputil"
@@ -45,10 +47,16 @@ const (
 
 // Client is the root instance of the Apple Search Ads API.
 type Client struct {
-	client    *http.Client
-	baseURL   *url.URL
-	UserAgent string
-	httpDebug bool
+	client      *http.Client
+	baseURL     *url.URL
+	environment string
+	UserAgent   string
+	httpDebug   bool
+	validate    bool
+	cache       *ResponseCache
+	retry       RetryPolicy
+	timeout     time.Duration
+	middleware  []Middleware
 
 	common service
 
@@ -63,7 +71,8 @@ type Client struct {
 	AccessControlList *AccessControlListService
 }
 
-// NewClient creates a new Client instance.
+// NewClient creates a new Client instance talking to the production API. Use NewClientWithOptions to configure
+// anything beyond the HTTP client.
 func NewClient(httpClient *http.Client) *Client {
 	if httpClient == nil {
 		httpClient = &http.Client{
@@ -76,9 +85,10 @@ func NewClient(httpClient *http.Client) *Client {
 	baseURL, _ := url.Parse(defaultBaseURL)
 
 	c := &Client{
-		client:    httpClient,
-		baseURL:   baseURL,
-		UserAgent: userAgent,
+		client:      httpClient,
+		baseURL:     baseURL,
+		environment: EnvironmentProduction,
+		UserAgent:   userAgent,
 	}
 
 	c.common.client = c
@@ -214,6 +224,12 @@ func appendingQueryOptions(s string, opt interface{}) (string, error) {
 		return s, err
 	}
 
+	if values, ok := opt.(url.Values); ok {
+		u.RawQuery = values.Encode()
+
+		return u.String(), nil
+	}
+
 	qs, err := query.Values(opt)
 	if err != nil {
 		return s, err
@@ -224,105 +240,60 @@ func appendingQueryOptions(s string, opt interface{}) (string, error) {
 	return u.String(), nil
 }
 
-// get sends a GET request to the API as configured.
-func (c *Client) get(ctx context.Context, url string, query interface{}, v interface{}, options ...requestOption) (*Response, error) {
-	var err error
-	if query != nil {
-		url, err = appendingQueryOptions(url, query)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	req, err := c.newRequest(ctx, "GET", url, nil, options...)
-	if err != nil {
-		return nil, err
-	}
+// rawResult makes do return the response with its body unread.
+type rawResult struct{}
 
-	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		return resp, err
-	}
+// Do sends a request to an endpoint of the API, typically one that this package does not model yet, and decodes the
+// JSON response body into out, unless out is nil. path is relative to the base URL of the client, query is nil, a
+// url.Values or a struct with "url" tags, and body is nil or encoded as JSON. The request goes through the same
+// authentication, middleware, retries, cache and error decoding as the service methods.
+func (c *Client) Do(ctx context.Context, method string, path string, query interface{}, body interface{}, out interface{}) (*Response, error) {
+	return c.call(ctx, operationName{method: "Do"}.operation(method, path, query, body, out), bodyOptions(body)...)
+}
 
-	return resp, err
+// DoRaw is like Do, but returns the response with its body unread, for endpoints that do not answer JSON or whose
+// responses should be streamed. The caller must close the body. Error responses are decoded and their body closed as
+// with Do.
+func (c *Client) DoRaw(ctx context.Context, method string, path string, query interface{}, body interface{}) (*Response, error) {
+	return c.call(ctx, operationName{method: "DoRaw"}.operation(method, path, query, body, rawResult{}), bodyOptions(body)...)
 }
 
-// post sends a POST request to the API as configured.
-func (c *Client) post(ctx context.Context, url string, body interface{}, v interface{}) (*Response, error) {
-	req, err := c.newRequest(ctx, "POST", url, body, withContentType("application/json"))
-	if err != nil {
-		return nil, err
+func bodyOptions(body interface{}) []requestOption {
+	if body == nil {
+		return nil
 	}
 
-	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		return resp, err
-	}
+	return []requestOption{withContentType("application/json")}
+}
 
-	return resp, err
+// get sends a GET request to the API as configured.
+func (c *Client) get(ctx context.Context, name operationName, url string, query interface{}, v interface{}, options ...requestOption) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodGet, url, query, nil, v), options...)
 }
 
 // post sends a POST request to the API as configured.
-func (c *Client) postWithQuery(ctx context.Context, url string, query interface{}, body interface{}, v interface{}) (*Response, error) {
-	var err error
-	if query != nil {
-		url, err = appendingQueryOptions(url, query)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	req, err := c.newRequest(ctx, "POST", url, body, withContentType("application/json"))
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		return resp, err
-	}
+func (c *Client) post(ctx context.Context, name operationName, url string, body interface{}, v interface{}) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodPost, url, nil, body, v), withContentType("application/json"))
+}
 
-	return resp, err
+// post sends a POST request to the API as configured.
+func (c *Client) postWithQuery(ctx context.Context, name operationName, url string, query interface{}, body interface{}, v interface{}) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodPost, url, query, body, v), withContentType("application/json"))
 }
 
 // post sends a PUT request to the API as configured.
-func (c *Client) put(ctx context.Context, url string, body interface{}, v interface{}) (*Response, error) {
-	req, err := c.newRequest(ctx, "PUT", url, body, withContentType("application/json"))
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		return resp, err
-	}
-
-	return resp, err
+func (c *Client) put(ctx context.Context, name operationName, url string, body interface{}, v interface{}) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodPut, url, nil, body, v), withContentType("application/json"))
 }
 
 // patch sends a PATCH request to the API as configured.
-func (c *Client) patch(ctx context.Context, url string, body *requestBody, v interface{}) (*Response, error) {
-	req, err := c.newRequest(ctx, "PATCH", url, body, withContentType("application/json"))
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		return resp, err
-	}
-
-	return resp, err
+func (c *Client) patch(ctx context.Context, name operationName, url string, body *requestBody, v interface{}) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodPatch, url, nil, body, v), withContentType("application/json"))
 }
 
 // delete sends a DELETE request to the API as configured.
-func (c *Client) delete(ctx context.Context, url string, body *requestBody) (*Response, error) {
-	req, err := c.newRequest(ctx, "DELETE", url, body, withContentType("application/json"))
-	if err != nil {
-		return nil, err
-	}
-
-	return c.do(ctx, req, nil)
+func (c *Client) delete(ctx context.Context, name operationName, url string, body *requestBody) (*Response, error) {
+	return c.call(ctx, name.operation(http.MethodDelete, url, nil, body, nil), withContentType("application/json"))
 }
 
 func (c *Client) newRequest(ctx context.Context, method string, path string, body interface{}, options ...requestOption) (*http.Request, error) {
@@ -364,31 +335,115 @@ func (c *Client) newRequest(ctx context.Context, method string, path string, bod
 }
 
 func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
-	respCh := make(chan *http.Response, 1)
+	var lookup *cacheLookup
+
+	_, raw := v.(rawResult)
+
+	switch {
+	case c.cache == nil:
+	case raw || ctx.Value(noCacheKey{}) != nil:
+		// raw bodies are handed to the caller unread, and some callers need the current state, so they bypass the
+		// cache; mutations still invalidate it
+		path := c.relativePath(req.URL)
+		lookup = &cacheLookup{path: path, mutation: isMutatingRequest(req.Method, path)}
+	default:
+		lookup = c.cache.lookup(req, c.relativePath(req.URL), c.cacheScope())
+		if lookup.fresh != nil {
+			response := newResponse(lookup.fresh.httpResponse(req, cacheStatusHit))
+
+			return response, decodeResponseBody(response.Body, v)
+		}
+
+		lookup.prepare(req)
+	}
+
+	resp, err := c.send(ctx, req)
+	if resp == nil {
+		return nil, err
+	}
+
+	body := resp.Body
+
+	if lookup != nil && err == nil {
+		resp = c.cache.store(lookup, req, resp)
+	}
+
+	response := newResponse(resp)
+
+	if err == nil {
+		err = checkResponse(response)
+	}
+
+	if raw && err == nil {
+		return response, nil
+	}
+
+	defer closeDesc(body)
+
+	if err != nil {
+		return response, err
+	}
+
+	return response, decodeResponseBody(resp.Body, v)
+}
+
+// send executes req and returns the raw response. Network errors and transient statuses are retried as allowed by
+// the retry policy of the client; once retries are exhausted the last response is returned as is. Non-idempotent
+// requests are only retried after 429 Too Many Requests, which the API answers without applying the request, unless
+// the policy opts in.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+
+	policy := c.retryPolicy(ctx)
+	retryable := policy.retryable(req.Method, c.relativePath(req.URL))
+
+	attempts := 0
 	op := func() error {
+		if attempts > 0 {
+			if resp != nil {
+				drainResponse(resp)
+				resp = nil
+			}
+
+			if err := rewindBody(req); err != nil {
+				return backoff.Permanent(err)
+			}
+		}
+
+		attempts++
+
 		if c.httpDebug {
 			if dump, err := httputil.DumpRequest(req, true); err == nil {
 				fmt.Printf("DEBUG request uri=%s\n%s\n", req.URL, dump) // nolint: forbidigo
 			}
 		}
 
-		resp, err := c.client.Do(req) // nolint: bodyclose
+		r, err := c.client.Do(req) // nolint: bodyclose
 		if err != nil {
 			select {
 			case <-ctx.Done():
 				return backoff.Permanent(ctx.Err())
 			default:
+			}
+
+			if !retryable {
 				return backoff.Permanent(err)
 			}
+
+			return err
 		}
 
 		if c.httpDebug {
-			if dump, err := httputil.DumpResponse(resp, true); err == nil {
+			if dump, err := httputil.DumpResponse(r, true); err == nil {
 				fmt.Printf("DEBUG response uri=%s\n%s\n", req.URL, dump) // nolint: forbidigo
 			}
 		}
 
-		respCh <- resp
+		resp = r
+
+		if isTransientStatus(r.StatusCode) && (retryable || r.StatusCode == http.StatusTooManyRequests) {
+			return fmt.Errorf("%w: %s", errTransientStatus, r.Status)
+		}
 
 		return nil
 	}
@@ -399,31 +454,60 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*Res
 		}
 	}
 
-	err := backoff.RetryNotify(op, backoff.NewExponentialBackOff(), notify)
+	err := backoff.RetryNotify(op, policy.backOff(ctx), notify)
 
-	resp := <-respCh
+	switch {
+	case err == nil, resp != nil && errors.Is(err, errTransientStatus):
+		return resp, nil
+	case resp != nil:
+		drainResponse(resp)
+	}
 
-	defer closeDesc(resp.Body)
+	return nil, err
+}
 
-	response := newResponse(resp)
+// rewindBody resets the body of req before it is sent again.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
 
+	if req.GetBody == nil {
+		return errBodyNotRewindable
+	}
+
+	body, err := req.GetBody()
 	if err != nil {
-		return response, err
+		return err
 	}
 
-	if err := checkResponse(response); err != nil {
-		return response, err
+	req.Body = body
+
+	return nil
+}
+
+func drainResponse(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func decodeResponseBody(body io.Reader, v interface{}) error {
+	if v == nil {
+		return nil
 	}
 
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			_, err = io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-		}
+	if w, ok := v.(io.Writer); ok {
+		_, err := io.Copy(w, body)
+
+		return err
 	}
 
-	return response, err
+	return json.NewDecoder(body).Decode(v)
+}
+
+// relativePath returns the path of u relative to the base URL of the client, e.g. "campaigns/1".
+func (c *Client) relativePath(u *url.URL) string {
+	return strings.TrimPrefix(strings.TrimPrefix(u.Path, c.baseURL.Path), "/")
 }
 
 func newResponse(r *http.Response) *Response {
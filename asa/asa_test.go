@@ -26,6 +26,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -74,7 +75,7 @@ func TestNilContextReturnsError(t *testing.T) {
 
 	defer server.Close()
 
-	_, err := client.get(nil, "test", nil, nil) // nolint: staticcheck
+	_, err := client.get(nil, operationName{}, "test", nil, nil) // nolint: staticcheck
 	assert.Error(t, err)
 }
 
@@ -87,7 +88,7 @@ func TestGet(t *testing.T) {
 	defer server.Close()
 
 	var unmarshaled mockPayload
-	resp, err := client.get(context.Background(), "test", nil, &unmarshaled)
+	resp, err := client.get(context.Background(), operationName{}, "test", nil, &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -108,7 +109,7 @@ func TestGetWithQuery(t *testing.T) {
 
 	var unmarshaled mockPayload
 	// Testing absolute URL path
-	resp, err := client.get(context.Background(), server.URL+"/test", &params, &unmarshaled)
+	resp, err := client.get(context.Background(), operationName{}, server.URL+"/test", &params, &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -122,7 +123,7 @@ func TestGetWithQuery_Error(t *testing.T) {
 	defer server.Close()
 
 	badQueryValue := []string{"horses"}
-	resp, err := client.get(context.Background(), server.URL, &badQueryValue, nil)
+	resp, err := client.get(context.Background(), operationName{}, server.URL, &badQueryValue, nil)
 
 	assert.Error(t, err)
 	assert.Nil(t, resp)
@@ -135,7 +136,7 @@ func TestGetError(t *testing.T) {
 	defer server.Close()
 
 	var unmarshaled mockPayload
-	resp, err := client.get(context.Background(), "test", nil, &unmarshaled)
+	resp, err := client.get(context.Background(), operationName{}, "test", nil, &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -151,7 +152,7 @@ func TestPost(t *testing.T) {
 	body := mockBody{"TEST"}
 
 	var unmarshaled mockPayload
-	resp, err := client.post(context.Background(), "test", newRequestBody(body), &unmarshaled)
+	resp, err := client.post(context.Background(), operationName{}, "test", newRequestBody(body), &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -167,7 +168,7 @@ func TestPatch(t *testing.T) {
 	body := mockBody{"TEST"}
 
 	var unmarshaled mockPayload
-	resp, err := client.patch(context.Background(), "test", newRequestBody(body), &unmarshaled)
+	resp, err := client.patch(context.Background(), operationName{}, "test", newRequestBody(body), &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -183,7 +184,7 @@ func TestPut(t *testing.T) {
 	body := mockBody{"TEST"}
 
 	var unmarshaled mockPayload
-	resp, err := client.put(context.Background(), "test", newRequestBody(body), &unmarshaled)
+	resp, err := client.put(context.Background(), operationName{}, "test", newRequestBody(body), &unmarshaled)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -198,7 +199,7 @@ func TestDelete(t *testing.T) {
 
 	body := mockBody{"TEST"}
 
-	resp, err := client.delete(context.Background(), "test", newRequestBody(body))
+	resp, err := client.delete(context.Background(), operationName{}, "test", newRequestBody(body))
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -283,6 +284,76 @@ func TestAppendingQueryOptions(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotMethod, gotQuery, gotBody, gotType string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotQuery, gotBody, gotType = r.Method+" "+r.URL.Path, r.URL.RawQuery, string(body), r.Header.Get("Content-Type")
+
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"errors":[{"code":"NOT_FOUND","detail":"no such endpoint"}]}`)
+
+			return
+		}
+
+		fmt.Fprintln(w, marshaledMockPayload)
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+	assert.NoError(t, err)
+
+	var out mockPayload
+
+	resp, err := client.Do(context.Background(), http.MethodPost, "campaigns/1/brandnew", url.Values{"limit": {"5"}}, map[string]string{"name": "x"}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "TEST", out.Value)
+	assert.Equal(t, "POST /campaigns/1/brandnew", gotMethod)
+	assert.Equal(t, "limit=5", gotQuery)
+	assert.JSONEq(t, `{"name":"x"}`, gotBody)
+	assert.Equal(t, "application/json", gotType)
+
+	_, err = client.Do(context.Background(), http.MethodGet, "brandnew", &GetAllCampaignQuery{Limit: 2}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "limit=2", gotQuery)
+	assert.Equal(t, "", gotType)
+
+	_, err = client.Do(context.Background(), http.MethodGet, "missing", nil, nil, &out)
+
+	var errResp *ErrorResponse
+	assert.True(t, errors.As(err, &errResp))
+	assert.Equal(t, "NOT_FOUND", errResp.Errors[0].Code)
+}
+
+func TestDoRaw(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(marshaledMockPayload, http.StatusOK, true)
+	defer server.Close()
+
+	cache := NewResponseCache(NewLRUCacheBackend(10), CacheRule{Method: http.MethodGet, PathPrefix: "raw", TTL: time.Hour})
+	client.SetCache(cache)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.DoRaw(context.Background(), http.MethodGet, "raw", nil, nil)
+		assert.NoError(t, err)
+		assert.False(t, resp.Cached())
+		assert.Equal(t, 10, resp.Rate.Remaining)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, resp.Body.Close())
+		assert.Equal(t, marshaledMockPayload+"\n", string(body))
+	}
+}
+
 func newServer(raw string, status int, addRateLimit bool) (*Client, *httptest.Server) { // nolint: unparam
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if addRateLimit {
@@ -292,9 +363,35 @@ func newServer(raw string, status int, addRateLimit bool) (*Client, *httptest.Se
 		fmt.Fprintln(w, raw)
 	}))
 
-	base, _ := url.Parse(server.URL)
-	client := NewClient(server.Client())
-	client.baseURL = base
+	client, _ := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+
+	return client, server
+}
+
+// newMuxServer returns a client wired to a test server answering each "METHOD path" key of routes
+// with its raw body. Unknown routes are answered with 404 Not Found.
+func newMuxServer(routes map[string]string) (*Client, *httptest.Server) {
+	return newHandlerServer(muxHandler(routes))
+}
+
+// muxHandler answers each "METHOD path" key of routes with its raw body, and unknown routes with 404 Not Found.
+func muxHandler(routes map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, raw)
+	}
+}
+
+// newHandlerServer returns a client wired to a test server running handler.
+func newHandlerServer(handler http.Handler) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	client, _ := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
 
 	return client, server
 }
@@ -0,0 +1,492 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditRecordIDSize = 16
+	auditFilePerm     = 0o600
+)
+
+// ErrAuditFailed happens when a mutating call could not be written to the audit sink. The call itself was sent and
+// its response is returned along with the error.
+var ErrAuditFailed = errors.New("audit record could not be written")
+
+var errMissingEntityID = errors.New("bulk request item has no id")
+
+// AuditRecord is the journal entry of a mutating call.
+type AuditRecord struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor,omitempty"`
+	OrgID      string    `json:"orgId,omitempty"`
+	Service    string    `json:"service,omitempty"`
+	Method     string    `json:"method"`
+	HTTPMethod string    `json:"httpMethod"`
+	Path       string    `json:"path"`
+	// Before holds the entities changed by the call as the API returned them before the change, one per entity of a
+	// bulk call in the order of the request. It is empty for creates.
+	Before []json.RawMessage `json:"before,omitempty"`
+	// BeforeUnavailable is set for calls changing entities that the journal does not know how to read, whose state
+	// before the change is not recorded.
+	BeforeUnavailable bool            `json:"beforeUnavailable,omitempty"`
+	BeforeError       string          `json:"beforeError,omitempty"`
+	Request           json.RawMessage `json:"request,omitempty"`
+	Response          json.RawMessage `json:"response,omitempty"`
+	StatusCode        int             `json:"statusCode,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	DryRun            bool            `json:"dryRun,omitempty"`
+}
+
+// Name returns the qualified name of the service method, e.g. "Campaigns.UpdateCampaign".
+func (r *AuditRecord) Name() string {
+	if r.Service == "" {
+		return r.Method
+	}
+
+	return r.Service + "." + r.Method
+}
+
+// AuditSink stores audit records. Implementations must be safe for concurrent use and should only ever append.
+type AuditSink interface {
+	Write(record *AuditRecord) error
+}
+
+// JSONLinesAuditSink writes each audit record as a line of JSON.
+type JSONLinesAuditSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewJSONLinesAuditSink writes audit records to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{w: w}
+}
+
+// OpenAuditFile opens the JSON Lines journal at path for appending, creating it and its directory if needed.
+func OpenAuditFile(path string) (*JSONLinesAuditSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), cacheDirPerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, auditFilePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONLinesAuditSink{w: file, closer: file}, nil
+}
+
+// Write appends record as a single line.
+func (s *JSONLinesAuditSink) Write(record *AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(line, '\n'))
+
+	return err
+}
+
+// Close closes the file of a sink opened with OpenAuditFile.
+func (s *JSONLinesAuditSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// ReadAuditRecords reads the records of a JSON Lines journal.
+func ReadAuditRecords(r io.Reader) ([]*AuditRecord, error) {
+	var records []*AuditRecord
+
+	dec := json.NewDecoder(r)
+
+	for {
+		record := &AuditRecord{}
+
+		err := dec.Decode(record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+
+		records = append(records, record)
+	}
+}
+
+type auditActorKey struct{}
+
+// ContextWithAuditActor labels the mutating calls made with ctx with actor in the audit journal, overriding the
+// actor of the Auditor.
+func ContextWithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// Auditor is a middleware journaling every mutating call to a sink, with the state of the changed entities before the
+// call, the payload, the response, the time, the organization and an actor label.
+type Auditor struct {
+	Sink AuditSink
+	// Actor labels the calls in the journal, unless their context carries another actor.
+	Actor string
+	// OrgID is the organization the client acts for, as given to the AuthTransport.
+	OrgID string
+	// Now returns the time of the records, time.Now when nil.
+	Now func() time.Time
+}
+
+// NewAuditor creates an Auditor writing to sink, e.g. a journal opened with OpenAuditFile.
+func NewAuditor(sink AuditSink, actor string, orgID string) *Auditor {
+	return &Auditor{Sink: sink, Actor: actor, OrgID: orgID, Now: time.Now}
+}
+
+// WithAuditor journals the mutating calls of the client with auditor.
+func WithAuditor(auditor *Auditor) ClientOption {
+	return func(c *Client) error {
+		c.Use(auditor.Middleware())
+
+		return nil
+	}
+}
+
+// Middleware returns the middleware journaling mutating calls.
+func (a *Auditor) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (*Response, error) {
+			if !op.Mutating() {
+				return next(ctx, op)
+			}
+
+			record, err := a.newRecord(ctx, op)
+			if err != nil {
+				return nil, err
+			}
+
+			var readable bool
+
+			record.Before, readable, err = captureBefore(ctx, next, op)
+			record.BeforeUnavailable = !readable
+
+			if err != nil {
+				record.BeforeError = err.Error()
+			}
+
+			resp, err := next(ctx, op)
+			completeAuditRecord(record, op, resp, err)
+
+			if werr := a.Sink.Write(record); werr != nil {
+				if err != nil {
+					return resp, err
+				}
+
+				return resp, fmt.Errorf("%w: %s", ErrAuditFailed, werr.Error())
+			}
+
+			return resp, err
+		}
+	}
+}
+
+func (a *Auditor) newRecord(ctx context.Context, op *Operation) (*AuditRecord, error) {
+	id := make([]byte, auditRecordIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	request, err := marshalOperationBody(op.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := a.Actor
+	if ctxActor, ok := ctx.Value(auditActorKey{}).(string); ok {
+		actor = ctxActor
+	}
+
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+
+	return &AuditRecord{
+		ID:         hex.EncodeToString(id),
+		Time:       now(),
+		Actor:      actor,
+		OrgID:      a.OrgID,
+		Service:    op.Service,
+		Method:     op.Method,
+		HTTPMethod: op.HTTPMethod,
+		Path:       op.Path,
+		Request:    request,
+	}, nil
+}
+
+func completeAuditRecord(record *AuditRecord, op *Operation, resp *Response, err error) {
+	if resp != nil && resp.Response != nil {
+		record.StatusCode = resp.StatusCode
+		record.DryRun = resp.DryRun()
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+
+		return
+	}
+
+	if _, raw := op.Result.(rawResult); op.Result != nil && !raw {
+		record.Response, _ = json.Marshal(op.Result)
+	}
+}
+
+// auditRead is how the audit journal reads the entities changed by a service method before the change: one by one
+// with the Get method, or with a single Find call per page of identifiers at findPath, formatted with the campaign
+// identifier. Creates change no existing entity and need no read.
+type auditRead struct {
+	get      string
+	find     string
+	findPath string
+	// wrapSelector is set for Find calls taking the selector in a {"selector": ...} object.
+	wrapSelector bool
+	creates      bool
+}
+
+// auditReads returns how the entities changed by each audited service method are read.
+func auditReads() map[string]auditRead {
+	return map[string]auditRead{
+		"Campaigns.CreateCampaign":                 {creates: true},
+		"Campaigns.UpdateCampaign":                 {get: "GetCampaign"},
+		"Campaigns.DeleteCampaign":                 {get: "GetCampaign"},
+		"AdGroups.CreateAdGroup":                   {creates: true},
+		"AdGroups.UpdateAdGroup":                   {get: "GetAdGroup"},
+		"AdGroups.DeleteAdGroup":                   {get: "GetAdGroup"},
+		"CreativeSets.UpdateCreativeSets":          {get: "GetCreativeSetVariation"},
+		"CreativeSets.CreateAdGroupCreativeSets":   {creates: true},
+		"CreativeSets.AssignCreativeSetsToAdGroup": {creates: true},
+		"CreativeSets.UpdateAdGroupCreativeSets":   {find: "FindAdGroupCreativeSets", findPath: "campaigns/%d/adgroupcreativesets/find", wrapSelector: true},
+		"CreativeSets.DeleteAdGroupCreativeSets":   {find: "FindAdGroupCreativeSets", findPath: "campaigns/%d/adgroupcreativesets/find", wrapSelector: true},
+		"Keywords.CreateTargetingKeywords":         {creates: true},
+		"Keywords.CreateNegativeKeywords":          {creates: true},
+		"Keywords.CreateAdGroupNegativeKeywords":   {creates: true},
+		"Keywords.UpdateTargetingKeywords":         {find: "FindTargetingKeywords", findPath: "campaigns/%d/adgroups/targetingkeywords/find"},
+		"Keywords.UpdateNegativeKeywords":          {find: "FindNegativeKeywords", findPath: "campaigns/%d/negativekeywords/find"},
+		"Keywords.UpdateAdGroupNegativeKeywords":   {find: "FindAdGroupNegativeKeywords", findPath: "campaigns/%d/adgroups/negativekeywords/find"},
+		"Keywords.DeleteNegativeKeywords":          {find: "FindNegativeKeywords", findPath: "campaigns/%d/negativekeywords/find"},
+		"Keywords.DeleteAdGroupNegativeKeywords":   {find: "FindAdGroupNegativeKeywords", findPath: "campaigns/%d/adgroups/negativekeywords/find"},
+	}
+}
+
+// captureBefore fetches the entities op is about to change through next, bypassing the response cache so that the
+// state is current. It reports false when op changes entities that cannot be read.
+func captureBefore(ctx context.Context, next Handler, op *Operation) ([]json.RawMessage, bool, error) {
+	read, ok := auditReads()[op.Service+"."+op.Method]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if read.creates {
+		return nil, true, nil
+	}
+
+	paths, err := entityPaths(op)
+	if err != nil {
+		return nil, true, err
+	}
+
+	ctx = withoutCache(ctx)
+
+	if read.find != "" {
+		before, err := findBefore(ctx, next, op, read, paths)
+
+		return before, true, err
+	}
+
+	before := make([]json.RawMessage, 0, len(paths))
+
+	for _, path := range paths {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+
+		get := &Operation{Service: op.Service, Method: read.get, HTTPMethod: http.MethodGet, Path: path, Result: &envelope}
+		if _, err := next(ctx, get); err != nil {
+			return before, true, fmt.Errorf("%s %s: %w", read.get, path, err)
+		}
+
+		before = append(before, envelope.Data)
+	}
+
+	return before, true, nil
+}
+
+// findBefore reads the entities at paths with one Find call per page of identifiers, and returns them in the order of
+// paths. Entities that are not found are left nil and reported in the error.
+func findBefore(ctx context.Context, next Handler, op *Operation, read auditRead, paths []string) ([]json.RawMessage, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	findPath := fmt.Sprintf(read.findPath, pathIDs(paths[0])["campaigns"])
+	found := make(map[int64]json.RawMessage, len(paths))
+
+	for start := 0; start < len(paths); start += findPageLimit {
+		end := start + findPageLimit
+		if end > len(paths) {
+			end = len(paths)
+		}
+
+		ids := make([]string, 0, end-start)
+		for _, path := range paths[start:end] {
+			ids = append(ids, strconv.FormatInt(pathIDs(path)[entityResource(path)], 10))
+		}
+
+		selector := &Selector{
+			Conditions: []*Condition{{Field: "id", Operator: ConditionOperatorIn, Values: ids}},
+			Pagination: &Pagination{Limit: uint32(len(ids))},
+		}
+
+		var body interface{} = selector
+		if read.wrapSelector {
+			body = &FindAdGroupCreativeSetRequest{Selector: selector}
+		}
+
+		var envelope struct {
+			Data []json.RawMessage `json:"data"`
+		}
+
+		find := &Operation{Service: op.Service, Method: read.find, HTTPMethod: http.MethodPost, Path: findPath, Body: body, Result: &envelope}
+		if _, err := next(ctx, find); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", read.find, findPath, err)
+		}
+
+		for _, entity := range envelope.Data {
+			var id struct {
+				ID int64 `json:"id"`
+			}
+
+			if err := json.Unmarshal(entity, &id); err == nil {
+				found[id.ID] = entity
+			}
+		}
+	}
+
+	before := make([]json.RawMessage, len(paths))
+
+	var missing []string
+
+	for i, path := range paths {
+		if entity, ok := found[pathIDs(path)[entityResource(path)]]; ok {
+			before[i] = entity
+		} else {
+			missing = append(missing, path)
+		}
+	}
+
+	if len(missing) > 0 {
+		return before, fmt.Errorf("%s: not found: %s", read.find, strings.Join(missing, ", "))
+	}
+
+	return before, nil
+}
+
+// entityPaths returns the paths of the entities changed by op. Bulk calls name their entities in the body, either as
+// a list of identifiers or as a list of objects with an "id".
+func entityPaths(op *Operation) ([]string, error) {
+	var prefix string
+
+	switch {
+	case strings.HasSuffix(op.Path, "/delete/bulk"):
+		prefix = strings.TrimSuffix(op.Path, "delete/bulk")
+	case strings.HasSuffix(op.Path, "/bulk"):
+		prefix = strings.TrimSuffix(op.Path, "bulk")
+	default:
+		return []string{op.Path}, nil
+	}
+
+	ids, err := bulkEntityIDs(op.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = prefix + id
+	}
+
+	return paths, nil
+}
+
+func bulkEntityIDs(body interface{}) ([]string, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(items))
+
+	for _, item := range items {
+		var entity struct {
+			ID json.Number `json:"id"`
+		}
+
+		if err := json.Unmarshal(item, &entity); err == nil {
+			if entity.ID == "" {
+				return nil, errMissingEntityID
+			}
+
+			ids = append(ids, entity.ID.String())
+
+			continue
+		}
+
+		var id json.Number
+		if err := json.Unmarshal(item, &id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id.String())
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,279 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAuditSink struct {
+	records []*AuditRecord
+	err     error
+}
+
+func (s *memoryAuditSink) Write(record *AuditRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.records = append(s.records, record)
+
+	return nil
+}
+
+func TestAuditorRecordsUpdates(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns/1": `{"data":{"id":1,"name":"Old"}}`,
+		"PUT /campaigns/1": `{"data":{"id":1,"name":"New"}}`,
+		"POST /campaigns/1/adgroups/targetingkeywords/find":  `{"data":[{"id":12,"status":"ACTIVE"},{"id":11,"status":"ACTIVE"}]}`,
+		"PUT /campaigns/1/adgroups/2/targetingkeywords/bulk": `{"data":[{"id":11,"status":"PAUSED"},{"id":12,"status":"PAUSED"}]}`,
+		"POST /campaigns/1/negativekeywords/find":            `{"data":[{"id":21,"text":"free"}]}`,
+		"POST /campaigns/1/negativekeywords/delete/bulk":     `{"data":1}`,
+		"POST /campaigns/1/adgroups":                         `{"data":{"id":3,"name":"Created"}}`,
+		"GET /campaigns/1/adgroups/3":                        `{"data":{"id":3,"name":"Created"}}`,
+	})
+	defer server.Close()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	sink := &memoryAuditSink{}
+	auditor := NewAuditor(sink, "scheduler", "1234")
+	auditor.Now = func() time.Time { return now }
+	client.Use(auditor.Middleware())
+
+	ctx := ContextWithAuditActor(context.Background(), "jane@example.com")
+
+	_, _, err := client.Campaigns.UpdateCampaign(ctx, 1, &UpdateCampaignRequest{Campaign: &CampaignUpdate{Name: "New"}})
+	require.NoError(t, err)
+
+	_, _, err = client.Keywords.UpdateTargetingKeywords(context.Background(), 1, 2, []*KeywordUpdateRequest{
+		{ID: 11, Status: KeywordStatusPaused},
+		{ID: 12, Status: KeywordStatusPaused},
+	})
+	require.NoError(t, err)
+
+	_, _, err = client.Keywords.DeleteNegativeKeywords(context.Background(), 1, []int64{21})
+	require.NoError(t, err)
+
+	_, _, err = client.AdGroups.CreateAdGroup(context.Background(), 1, &AdGroup{Name: "Created"})
+	require.NoError(t, err)
+
+	_, _, err = client.AdGroups.GetAdGroup(context.Background(), 1, 3)
+	require.NoError(t, err)
+
+	require.Len(t, sink.records, 4)
+
+	update := sink.records[0]
+	assert.Len(t, update.ID, 32)
+	assert.Equal(t, now, update.Time)
+	assert.Equal(t, "jane@example.com", update.Actor)
+	assert.Equal(t, "1234", update.OrgID)
+	assert.Equal(t, "Campaigns.UpdateCampaign", update.Name())
+	assert.Equal(t, http.MethodPut, update.HTTPMethod)
+	assert.Equal(t, "campaigns/1", update.Path)
+	require.Len(t, update.Before, 1)
+	assert.JSONEq(t, `{"id":1,"name":"Old"}`, string(update.Before[0]))
+	assert.Contains(t, string(update.Request), `"name":"New"`)
+	assert.Contains(t, string(update.Response), `"name":"New"`)
+	assert.Equal(t, http.StatusOK, update.StatusCode)
+	assert.Empty(t, update.Error)
+
+	bulk := sink.records[1]
+	assert.Equal(t, "scheduler", bulk.Actor)
+	require.Len(t, bulk.Before, 2)
+	assert.JSONEq(t, `{"id":11,"status":"ACTIVE"}`, string(bulk.Before[0]))
+	assert.JSONEq(t, `{"id":12,"status":"ACTIVE"}`, string(bulk.Before[1]))
+
+	deletion := sink.records[2]
+	assert.Equal(t, "Keywords.DeleteNegativeKeywords", deletion.Name())
+	require.Len(t, deletion.Before, 1)
+	assert.JSONEq(t, `{"id":21,"text":"free"}`, string(deletion.Before[0]))
+	assert.JSONEq(t, `[21]`, string(deletion.Request))
+
+	creation := sink.records[3]
+	assert.Equal(t, "AdGroups.CreateAdGroup", creation.Name())
+	assert.Empty(t, creation.Before)
+	assert.Empty(t, creation.BeforeError)
+	assert.False(t, creation.BeforeUnavailable)
+}
+
+func TestAuditorRecordsAdGroupCreativeSets(t *testing.T) {
+	t.Parallel()
+
+	client, server, received := newRecordingServer(map[string]string{
+		"POST /campaigns/1/adgroupcreativesets/find":                   `{"data":[{"id":5,"adGroupId":2,"status":"ACTIVE"},{"id":6,"adGroupId":2,"status":"ACTIVE"}]}`,
+		"PUT /campaigns/1/adgroups/2/adgroupcreativesets/5":            `{"data":{"id":5,"adGroupId":2,"status":"PAUSED"}}`,
+		"POST /campaigns/1/adgroups/2/adgroupcreativesets/delete/bulk": `{"data":1}`,
+		"PUT /campaigns/1/custom":                                      `{"data":{}}`,
+	})
+	defer server.Close()
+
+	sink := &memoryAuditSink{}
+	client.Use(NewAuditor(sink, "", "").Middleware())
+
+	_, _, err := client.CreativeSets.UpdateAdGroupCreativeSets(context.Background(), 1, 2, 5, &AdGroupCreativeSetUpdate{Status: AdGroupStatusPaused})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"selector":{"conditions":[{"field":"id","operator":"IN","values":["5"]}],"pagination":{"limit":1,"offset":0}}}`, received()["POST /campaigns/1/adgroupcreativesets/find"])
+
+	_, _, err = client.CreativeSets.DeleteAdGroupCreativeSets(context.Background(), 1, 2, []int64{6, 7})
+	require.NoError(t, err)
+
+	_, err = client.Do(context.Background(), http.MethodPut, "campaigns/1/custom", nil, map[string]string{}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, sink.records, 3)
+	assert.JSONEq(t, `{"id":5,"adGroupId":2,"status":"ACTIVE"}`, string(sink.records[0].Before[0]))
+	assert.False(t, sink.records[0].BeforeUnavailable)
+
+	deletion := sink.records[1]
+	require.Len(t, deletion.Before, 2)
+	assert.JSONEq(t, `{"id":6,"adGroupId":2,"status":"ACTIVE"}`, string(deletion.Before[0]))
+	assert.Nil(t, deletion.Before[1])
+	assert.Contains(t, deletion.BeforeError, "not found: campaigns/1/adgroups/2/adgroupcreativesets/7")
+
+	assert.True(t, sink.records[2].BeforeUnavailable)
+	assert.Empty(t, sink.records[2].Before)
+}
+
+func TestAuditorBypassesCache(t *testing.T) {
+	t.Parallel()
+
+	var gets int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+
+		fmt.Fprintln(w, `{"data":{"id":1,"name":"Old"}}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+	client.SetCache(NewResponseCache(nil, CacheRule{Method: http.MethodGet, PathPrefix: "campaigns", TTL: time.Hour}))
+
+	sink := &memoryAuditSink{}
+	client.Use(NewAuditor(sink, "", "").Middleware())
+
+	_, _, err := client.Campaigns.GetCampaign(context.Background(), 1)
+	require.NoError(t, err)
+
+	_, _, err = client.Campaigns.UpdateCampaign(context.Background(), 1, &UpdateCampaignRequest{Campaign: &CampaignUpdate{Name: "New"}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gets), "the state before the change is read from the API")
+}
+
+func TestAuditorRecordsFailures(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{})
+	defer server.Close()
+
+	sink := &memoryAuditSink{}
+	client.Use(NewAuditor(sink, "", "").Middleware())
+
+	_, err := client.Campaigns.DeleteCampaign(context.Background(), 1)
+	assert.Error(t, err)
+
+	require.Len(t, sink.records, 1)
+	assert.Contains(t, sink.records[0].BeforeError, "GetCampaign campaigns/1")
+	assert.Equal(t, http.StatusNotFound, sink.records[0].StatusCode)
+	assert.Equal(t, err.Error(), sink.records[0].Error)
+	assert.Empty(t, sink.records[0].Response)
+}
+
+func TestAuditorSinkFailure(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"POST /campaigns": `{"data":{"id":1}}`,
+	})
+	defer server.Close()
+
+	sink := &memoryAuditSink{err: errors.New("disk full")}
+	client.Use(NewAuditor(sink, "", "").Middleware())
+
+	res, _, err := client.Campaigns.CreateCampaign(context.Background(), &Campaign{Name: "Campaign"})
+	assert.ErrorIs(t, err, ErrAuditFailed)
+	assert.Contains(t, err.Error(), "disk full")
+	assert.Equal(t, int64(1), res.Campaign.ID)
+}
+
+func TestAuditorUnderDryRun(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns/1": `{"data":{"id":1,"name":"Old"}}`,
+	})
+	defer server.Close()
+
+	var journal bytes.Buffer
+
+	client.Use(NewAuditor(NewJSONLinesAuditSink(&journal), "", "").Middleware(), NewDryRun().Middleware())
+
+	_, _, err := client.Campaigns.UpdateCampaign(context.Background(), 1, &UpdateCampaignRequest{Campaign: &CampaignUpdate{Name: "New"}})
+	require.NoError(t, err)
+
+	records, err := ReadAuditRecords(&journal)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.True(t, records[0].DryRun)
+	assert.JSONEq(t, `{"id":1,"name":"Old"}`, string(records[0].Before[0]))
+}
+
+func TestOpenAuditFileAppends(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit", "journal.jsonl")
+
+	for _, method := range []string{"CreateCampaign", "DeleteCampaign"} {
+		sink, err := OpenAuditFile(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Write(&AuditRecord{Service: "Campaigns", Method: method}))
+		require.NoError(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	records, err := ReadAuditRecords(file)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "Campaigns.CreateCampaign", records[0].Name())
+	assert.Equal(t, "Campaigns.DeleteCampaign", records[1].Name())
+
+	line, err := json.Marshal(records[1])
+	require.NoError(t, err)
+	assert.NotContains(t, string(line), "before")
+}
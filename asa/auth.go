@@ -18,28 +18,68 @@ package asa
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/x509"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/dgrijalva/jwt-go/v4"
 )
 
-// ErrMissingPEM happens when the bytes cannot be decoded as a PEM block.
-var ErrMissingPEM = errors.New("no PEM blob found")
-
-// ErrInvalidPrivateKey happens when a key cannot be parsed as a ECDSA PKCS8 private key.
-var ErrInvalidPrivateKey = errors.New("key could not be parsed as a valid ecdsa.PrivateKey")
-
 // ErrHTTPTokenBadRequest happens when apple generate token http request failed.
 var ErrHTTPTokenBadRequest = errors.New("generate auth token failed with")
 
+// ErrInvalidClient happens when Apple rejects the client ID or client secret of a token request.
+var ErrInvalidClient = errors.New("invalid_client")
+
+// ErrInvalidGrant happens when Apple rejects the grant of a token request.
+var ErrInvalidGrant = errors.New("invalid_grant")
+
+// OAuthError is the error response of the Apple OAuth token endpoint.
+//
+// It matches ErrHTTPTokenBadRequest, and ErrInvalidClient or ErrInvalidGrant by code, with errors.Is.
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	// Body is the raw response body when it is not an OAuth error document.
+	Body string `json:"-"`
+}
+
+func (e *OAuthError) Error() string {
+	message := e.Code
+	if message == "" {
+		message = e.Body
+	}
+
+	if e.Description != "" {
+		message += ": " + e.Description
+	}
+
+	return fmt.Sprintf("http error %s status %d: %s", ErrHTTPTokenBadRequest, e.StatusCode, message)
+}
+
+// Is matches ErrHTTPTokenBadRequest and the sentinel error of the OAuth error code.
+func (e *OAuthError) Is(target error) bool {
+	switch target {
+	case ErrHTTPTokenBadRequest:
+		return true
+	case ErrInvalidClient, ErrInvalidGrant:
+		return e.Code == target.Error()
+	default:
+		return false
+	}
+}
+
 // AuthTransport is an http.RoundTripper implementation that stores the JWT created.
 // If the token expires, the Rotate function should be called to update the stored token.
 type AuthTransport struct {
@@ -50,7 +90,8 @@ type AuthTransport struct {
 
 type jwtGenerator interface {
 	Token() (string, error)
-	AccessToken() (string, error)
+	AccessToken(ctx context.Context) (string, error)
+	Invalidate(ctx context.Context, rejected string)
 	IsTokenValid() bool
 	IsAccessTokenValid() bool
 	Client() *authClient
@@ -61,11 +102,16 @@ type standardJWTGenerator struct {
 	issuerID       string
 	clientID       string
 	expireDuration time.Duration
-	privateKey     *ecdsa.PrivateKey
+	signer         crypto.Signer
+	clientSecret   string
 
+	// mu serializes refreshes of the access token.
+	mu          sync.Mutex
 	accessToken *accessToken
 	token       string
 	client      *authClient
+	store       TokenStore
+	storeKey    string
 }
 
 type accessToken struct {
@@ -79,52 +125,65 @@ type accessToken struct {
 
 // NewTokenConfig returns a new AuthTransport instance that customizes the Authentication header of the request during transport.
 // It can be customized further by supplying a custom http.RoundTripper instance to the Transport field.
+//
+// NewTokenConfig is equivalent to NewAuthTransport with the matching options.
 func NewTokenConfig(orgID string, keyID string, teamID string, clientID string, expireDuration time.Duration, privateKey []byte) (*AuthTransport, error) {
-	key, err := parsePrivateKey(privateKey)
+	return NewAuthTransport(
+		WithOrgID(orgID),
+		WithKeyID(keyID),
+		WithTeamID(teamID),
+		WithClientID(clientID),
+		WithTokenExpiry(expireDuration),
+		WithPrivateKey(privateKey),
+	)
+}
+
+// RoundTrip implements the http.RoundTripper interface to set the Authorization header.
+//
+// When the API rejects the access token with 401 Unauthorized before it expires, the token and the client secret
+// it was minted from are dropped, and the request is retried once with a new token. Requests with a body are only
+// retried when the body can be rewound through GetBody, as set by http.NewRequest.
+func (t AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.jwtGenerator.AccessToken(req.Context())
 	if err != nil {
 		return nil, err
 	}
 
-	gen := &standardJWTGenerator{
-		keyID:          keyID,
-		issuerID:       teamID,
-		clientID:       clientID,
-		privateKey:     key,
-		expireDuration: expireDuration,
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-AP-Context", fmt.Sprintf("orgId=%s", t.orgID))
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
 	}
 
-	return &AuthTransport{
-		Transport:    newTransport(),
-		jwtGenerator: gen,
-		orgID:        orgID,
-	}, err
-}
+	retry := req.Clone(req.Context())
 
-func parsePrivateKey(blob []byte) (*ecdsa.PrivateKey, error) {
-	block, _ := pem.Decode(blob)
-	if block == nil {
-		return nil, ErrMissingPEM
-	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
 
-	parsedKey, err := x509.ParseECPrivateKey(block.Bytes)
-	if err != nil {
-		return nil, err
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil // nolint: nilerr
+		}
+
+		retry.Body = body
 	}
 
-	return parsedKey, nil
-}
+	drainResponse(resp)
 
-// RoundTrip implements the http.RoundTripper interface to set the Authorization header.
-func (t AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	token, err := t.jwtGenerator.AccessToken()
+	t.jwtGenerator.Invalidate(req.Context(), token)
+
+	token, err = t.jwtGenerator.AccessToken(req.Context())
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("X-AP-Context", fmt.Sprintf("orgId=%s", t.orgID))
+	retry.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	return t.transport().RoundTrip(req)
+	return t.transport().RoundTrip(retry)
 }
 
 // Client returns a new http.Client instance for use with apple_search_ads.Client.
@@ -140,7 +199,24 @@ func (t *AuthTransport) transport() http.RoundTripper {
 	return t.Transport
 }
 
+// cacheScope identifies the organization and the API client the requests are sent for. The client ID is hashed so
+// that it is not written to disk cache backends.
+func (t AuthTransport) cacheScope() string {
+	var clientID string
+	if g, ok := t.jwtGenerator.(*standardJWTGenerator); ok {
+		clientID = g.clientID
+	}
+
+	sum := sha256.Sum256([]byte(clientID))
+
+	return fmt.Sprintf("orgId=%s client=%s", t.orgID, hex.EncodeToString(sum[:]))
+}
+
 func (g *standardJWTGenerator) Token() (string, error) {
+	if g.clientSecret != "" {
+		return g.clientSecret, nil
+	}
+
 	if g.IsTokenValid() {
 		return g.token, nil
 	}
@@ -148,7 +224,7 @@ func (g *standardJWTGenerator) Token() (string, error) {
 	t := jwt.NewWithClaims(jwt.SigningMethodES256, g.claims())
 	t.Header["kid"] = g.keyID
 
-	token, err := t.SignedString(g.privateKey)
+	token, err := t.SignedString(g.signer)
 	if err != nil {
 		return "", err
 	}
@@ -158,17 +234,97 @@ func (g *standardJWTGenerator) Token() (string, error) {
 	return token, nil
 }
 
-func (g *standardJWTGenerator) AccessToken() (string, error) {
+// AccessToken returns the cached access token, or the one shared through the token store, and refreshes it once it
+// expires. Concurrent callers wait for a single refresh.
+func (g *standardJWTGenerator) AccessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	if g.IsAccessTokenValid() {
 		return g.accessToken.AccessToken, nil
 	}
 
+	if g.store == nil {
+		return g.refreshAccessToken(ctx)
+	}
+
+	if g.loadStoredToken(ctx) {
+		return g.accessToken.AccessToken, nil
+	}
+
+	unlock, err := g.store.Lock(ctx, g.storeKey)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = unlock() }()
+
+	// another process may have refreshed the token while this one waited for the lock
+	if g.loadStoredToken(ctx) {
+		return g.accessToken.AccessToken, nil
+	}
+
+	token, err := g.refreshAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// the token is valid even when it cannot be shared, the next process will refresh its own
+	_ = g.store.Store(ctx, g.storeKey, &StoredToken{
+		AccessToken:  g.accessToken.AccessToken,
+		TokenType:    g.accessToken.TokenType,
+		Scope:        g.accessToken.Scope,
+		ExpiresAfter: g.accessToken.expiresAfter,
+	})
+
+	return token, nil
+}
+
+// Invalidate drops the access token rejected by the API together with the client secret it was minted from, unless
+// a concurrent request already replaced it.
+func (g *standardJWTGenerator) Invalidate(ctx context.Context, rejected string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.accessToken == nil || g.accessToken.AccessToken != rejected {
+		return
+	}
+
+	g.accessToken = nil
+	g.token = ""
+
+	if g.store == nil {
+		return
+	}
+
+	if stored, err := g.store.Load(ctx, g.storeKey); err == nil && stored.AccessToken == rejected {
+		_ = g.store.Delete(ctx, g.storeKey)
+	}
+}
+
+func (g *standardJWTGenerator) loadStoredToken(ctx context.Context) bool {
+	stored, err := g.store.Load(ctx, g.storeKey)
+	if err != nil || !stored.Valid(time.Now()) {
+		return false
+	}
+
+	g.accessToken = &accessToken{
+		AccessToken:  stored.AccessToken,
+		TokenType:    stored.TokenType,
+		Scope:        stored.Scope,
+		expiresAfter: stored.ExpiresAfter,
+	}
+
+	return true
+}
+
+func (g *standardJWTGenerator) refreshAccessToken(ctx context.Context) (string, error) {
 	token, err := g.Token()
 	if err != nil {
 		return "", err
 	}
 
-	accessTkn, err := g.generateAccessToken(token)
+	accessTkn, err := g.generateAccessToken(ctx, token)
 	if err != nil {
 		return "", err
 	}
@@ -181,51 +337,91 @@ func (g *standardJWTGenerator) AccessToken() (string, error) {
 func (g *standardJWTGenerator) Client() *authClient {
 	if g.client == nil {
 		g.client = &authClient{
-			client:  &http.Client{},
+			client:  &http.Client{Timeout: defaultTimeout},
 			baseURL: defaultAuthURL,
+			retry:   DefaultRetryPolicy(),
 		}
 	}
 
 	return g.client
 }
 
+// authClient sends token requests to the token endpoint at baseURL.
 type authClient struct {
 	client  *http.Client
 	baseURL string
+	retry   RetryPolicy
 }
 
-func (g *standardJWTGenerator) generateAccessToken(token string) (*accessToken, error) {
+// generateAccessToken exchanges the client secret for an access token, retrying network errors, throttling and
+// server errors.
+func (g *standardJWTGenerator) generateAccessToken(ctx context.Context, token string) (*accessToken, error) {
 	authClient := g.Client()
-	url := fmt.Sprintf("%s/oauth2/token?grant_type=client_credentials&client_id=%s&client_secret=%s&scope=searchadsorg", authClient.baseURL, g.clientID, token)
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, nil)
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {g.clientID},
+		"client_secret": {token},
+		"scope":         {"searchadsorg"},
+	}.Encode()
+
+	var accessTkn *accessToken
+
+	op := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, authClient.baseURL, strings.NewReader(form))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	req.Header.Add("Host", "appleid.apple.com")
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := authClient.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return backoff.Permanent(ctx.Err())
+			}
 
-	resp, err := authClient.client.Do(req)
+			return err
+		}
+		defer resp.Body.Close()
 
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			oauthErr := newOAuthError(resp.StatusCode, b)
+			if isTransientStatus(resp.StatusCode) {
+				return oauthErr
+			}
+
+			return backoff.Permanent(oauthErr)
+		}
 
-	b, _ := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode >= http.StatusBadRequest {
-		return nil, fmt.Errorf("http error %w: %s", ErrHTTPTokenBadRequest, string(b))
+		accessTkn = &accessToken{}
+		if err := json.Unmarshal(b, accessTkn); err != nil {
+			return backoff.Permanent(err)
+		}
+
+		return nil
 	}
 
-	accessToken := &accessToken{}
-	if err := json.Unmarshal(b, accessToken); err != nil {
+	if err := backoff.Retry(op, authClient.retry.backOff(ctx)); err != nil {
 		return nil, err
 	}
 
-	accessToken.expiresAfter = time.Now().Add(time.Second * time.Duration(accessToken.ExpiresIn))
+	accessTkn.expiresAfter = time.Now().Add(time.Second * time.Duration(accessTkn.ExpiresIn))
+
+	return accessTkn, nil
+}
+
+func newOAuthError(status int, body []byte) *OAuthError {
+	oauthErr := &OAuthError{StatusCode: status}
+	if err := json.Unmarshal(body, oauthErr); err != nil || oauthErr.Code == "" {
+		oauthErr.Body = strings.TrimSpace(string(body))
+	}
 
-	return accessToken, nil
+	return oauthErr
 }
 
 func (g *standardJWTGenerator) IsTokenValid() bool {
@@ -235,7 +431,7 @@ func (g *standardJWTGenerator) IsTokenValid() bool {
 
 	parsed, err := jwt.Parse(
 		g.token,
-		jwt.KnownKeyfunc(jwt.SigningMethodES256, g.privateKey),
+		jwt.KnownKeyfunc(jwt.SigningMethodES256, g.signer),
 		jwt.WithAudience("https://appleid.apple.com"),
 		jwt.WithIssuer(g.issuerID),
 	)
@@ -17,11 +17,16 @@ along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
 package asa
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -135,17 +140,156 @@ LWfXSp67hz35UIbgO6NANWf4tzZ6fhTThA==
 		baseURL: base.String(),
 	}
 
-	tok, err := tokenConfig.jwtGenerator.AccessToken()
+	tok, err := tokenConfig.jwtGenerator.AccessToken(context.Background())
 	assert.NoError(t, err)
 
 	components := strings.Split(tok, ".")
 	assert.Equal(t, 5, len(components))
 
-	tokCached, err := tokenConfig.jwtGenerator.AccessToken()
+	tokCached, err := tokenConfig.jwtGenerator.AccessToken(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, tok, tokCached)
 }
 
+func TestGenerateAccessTokenRequest(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/auth/oauth2/token", r.URL.Path)
+		assert.Empty(t, r.URL.RawQuery)
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		assert.Equal(t, "searchadsorg", r.PostForm.Get("scope"))
+
+		fmt.Fprintln(w, `{"access_token":"access","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	auth, err := NewAuthTransport(
+		WithClientID("client"),
+		WithClientSecret("secret"),
+		WithTokenURL(server.URL+"/auth/oauth2/token"),
+		WithTokenHTTPClient(server.Client()),
+		WithTokenRetryPolicy(RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond}),
+	)
+	assert.NoError(t, err)
+
+	token, err := auth.jwtGenerator.AccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access", token)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestGenerateAccessTokenOAuthError(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"error":"invalid_client","error_description":"unknown client"}`)
+	}))
+	defer server.Close()
+
+	auth, err := NewAuthTransport(
+		WithClientID("client"),
+		WithClientSecret("secret"),
+		WithTokenURL(server.URL),
+		WithTokenRetryPolicy(RetryPolicy{MaxRetries: 3, InitialInterval: time.Millisecond}),
+	)
+	assert.NoError(t, err)
+
+	_, err = auth.jwtGenerator.AccessToken(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidClient)
+	assert.ErrorIs(t, err, ErrHTTPTokenBadRequest)
+	assert.False(t, errors.Is(err, ErrInvalidGrant))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	var oauthErr *OAuthError
+	assert.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, http.StatusBadRequest, oauthErr.StatusCode)
+	assert.Equal(t, "unknown client", oauthErr.Description)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err = auth.RoundTrip(req) // nolint: bodyclose
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAuthTransportUnauthorizedRetry(t *testing.T) {
+	t.Parallel()
+
+	var minted int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, atomic.AddInt32(&minted, 1))
+	}))
+	defer tokenServer.Close()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"error":{"errors":[{"messageCode":"UNAUTHORIZED","message":"Invalid token"}]}}`)
+
+			return
+		}
+
+		fmt.Fprintln(w, `{"data":[{"id":1}]}`)
+	}))
+	defer apiServer.Close()
+
+	auth, err := NewAuthTransport(WithClientID("client"), WithClientSecret("secret"), WithTokenURL(tokenServer.URL))
+	assert.NoError(t, err)
+
+	client := NewClient(auth.Client())
+	client.baseURL, _ = url.Parse(apiServer.URL)
+
+	campaigns, _, err := client.Campaigns.FindCampaigns(context.Background(), &Selector{Pagination: &Pagination{Limit: 1}})
+	assert.NoError(t, err)
+	assert.Len(t, campaigns.Campaigns, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&minted))
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.NotEmpty(t, bodies[1])
+
+	// a body that cannot be rewound is not sent twice
+	auth.jwtGenerator.Invalidate(context.Background(), "token-2")
+	atomic.StoreInt32(&minted, 0)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, apiServer.URL, ioutil.NopCloser(strings.NewReader("{}")))
+	resp, err := auth.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+	assert.Len(t, bodies, 3)
+}
+
 func TestAuthClient(t *testing.T) {
 	t.Parallel()
 
@@ -170,10 +314,14 @@ func (g *mockJWTGenerator) Client() *authClient {
 	return g.client
 }
 
-func (g *mockJWTGenerator) AccessToken() (string, error) {
+func (g *mockJWTGenerator) AccessToken(context.Context) (string, error) {
 	return g.accessToken.AccessToken, nil
 }
 
+func (g *mockJWTGenerator) Invalidate(context.Context, string) {
+	g.accessToken = &accessToken{}
+}
+
 func (g *mockJWTGenerator) IsTokenValid() bool {
 	return true
 }
@@ -0,0 +1,604 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// percent is the divisor turning a percentage into a ratio.
+const percent = 100
+
+// ErrInvalidBidRule happens when a bid rule configuration cannot be evaluated.
+var ErrInvalidBidRule = errors.New("invalid bid rule")
+
+// BidRuleLevel is the kind of entity a bid rule applies to.
+type BidRuleLevel string
+
+const (
+	// BidRuleLevelKeyword is for rules evaluated against keyword-level reports that change keyword bids.
+	BidRuleLevelKeyword BidRuleLevel = "KEYWORD"
+	// BidRuleLevelAdGroup is for rules evaluated against ad group-level reports that change ad group default bids.
+	BidRuleLevelAdGroup BidRuleLevel = "AD_GROUP"
+)
+
+// BidRuleMetric is a report metric a bid rule condition is evaluated on.
+type BidRuleMetric string
+
+const (
+	// BidRuleMetricAvgCPA is for the average cost per acquisition.
+	BidRuleMetricAvgCPA BidRuleMetric = "avgCPA"
+	// BidRuleMetricAvgCPT is for the average cost per tap.
+	BidRuleMetricAvgCPT BidRuleMetric = "avgCPT"
+	// BidRuleMetricLocalSpend is for the spend in the currency of the campaign.
+	BidRuleMetricLocalSpend BidRuleMetric = "localSpend"
+	// BidRuleMetricImpressions is for the number of impressions.
+	BidRuleMetricImpressions BidRuleMetric = "impressions"
+	// BidRuleMetricTaps is for the number of taps.
+	BidRuleMetricTaps BidRuleMetric = "taps"
+	// BidRuleMetricInstalls is for the number of installs.
+	BidRuleMetricInstalls BidRuleMetric = "installs"
+	// BidRuleMetricConversionRate is for the ratio of installs to taps.
+	BidRuleMetricConversionRate BidRuleMetric = "conversionRate"
+	// BidRuleMetricTTR is for the tap-through rate.
+	BidRuleMetricTTR BidRuleMetric = "ttr"
+)
+
+// BidRuleCondition compares a report metric with a value. The supported operators are
+// ConditionOperatorGreaterThan, ConditionOperatorLessThan, ConditionOperatorEquals and ConditionOperatorNotEqual.
+type BidRuleCondition struct {
+	Metric   BidRuleMetric     `json:"metric"`
+	Operator ConditionOperator `json:"operator"`
+	Value    float64           `json:"value"`
+}
+
+// BidRuleAction is the change a bid rule makes when all its conditions match.
+type BidRuleAction struct {
+	// AdjustPercent changes the bid by a percentage of the current bid, e.g. -10 lowers the bid by 10%.
+	AdjustPercent float64 `json:"adjustPercent,omitempty"`
+	// MinBid and MaxBid bound the new bid. Zero disables the bound.
+	MinBid float64 `json:"minBid,omitempty"`
+	MaxBid float64 `json:"maxBid,omitempty"`
+	// FloorAtRecommendedMin keeps the new bid at or above the bidMin of the keyword bid recommendation.
+	FloorAtRecommendedMin bool `json:"floorAtRecommendedMin,omitempty"`
+	// CeilingAtRecommendedMax keeps the new bid at or below the bidMax of the keyword bid recommendation.
+	CeilingAtRecommendedMax bool `json:"ceilingAtRecommendedMax,omitempty"`
+	// Pause pauses the keyword or ad group instead of changing its bid.
+	Pause bool `json:"pause,omitempty"`
+}
+
+// BidRule is a named set of conditions on the metrics of the last LookbackDays days and the action to take when
+// they all match. A rule needs at least one condition, so that it never matches every entity by accident.
+type BidRule struct {
+	Name         string              `json:"name"`
+	Level        BidRuleLevel        `json:"level"`
+	LookbackDays int                 `json:"lookbackDays"`
+	Conditions   []*BidRuleCondition `json:"conditions"`
+	Action       *BidRuleAction      `json:"action"`
+}
+
+// BidRuleConfig is the configuration of a BidRuleEngine. Rules are evaluated in order and only the first matching
+// rule changes an entity.
+type BidRuleConfig struct {
+	// CampaignIDs are the campaigns whose keywords and ad groups are evaluated.
+	CampaignIDs []int64 `json:"campaignIds"`
+	// MaxChanges caps the number of changes applied in one run. Zero means no cap.
+	MaxChanges int        `json:"maxChanges,omitempty"`
+	Rules      []*BidRule `json:"rules"`
+}
+
+// LoadBidRuleConfig reads a JSON bid rule configuration and validates it.
+func LoadBidRuleConfig(r io.Reader) (*BidRuleConfig, error) {
+	config := &BidRuleConfig{}
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks that every rule of the configuration can be evaluated.
+func (c *BidRuleConfig) Validate() error {
+	if c.MaxChanges < 0 {
+		return fmt.Errorf("%w: negative maxChanges %d", ErrInvalidBidRule, c.MaxChanges)
+	}
+
+	for i, rule := range c.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rules[%d] %q: %w", i, rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *BidRule) validate() error {
+	switch {
+	case r.Level != BidRuleLevelKeyword && r.Level != BidRuleLevelAdGroup:
+		return fmt.Errorf("%w: unknown level %q", ErrInvalidBidRule, r.Level)
+	case r.LookbackDays <= 0:
+		return fmt.Errorf("%w: lookbackDays must be positive", ErrInvalidBidRule)
+	case len(r.Conditions) == 0:
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidBidRule)
+	case r.Action == nil:
+		return fmt.Errorf("%w: missing action", ErrInvalidBidRule)
+	case !r.Action.Pause && r.Action.AdjustPercent == 0:
+		return fmt.Errorf("%w: action must either pause or adjust the bid", ErrInvalidBidRule)
+	}
+
+	for _, condition := range r.Conditions {
+		if _, ok := spendRowMetric(&SpendRow{}, condition.Metric); !ok {
+			return fmt.Errorf("%w: unknown metric %q", ErrInvalidBidRule, condition.Metric)
+		}
+
+		switch condition.Operator {
+		case ConditionOperatorGreaterThan, ConditionOperatorLessThan, ConditionOperatorEquals, ConditionOperatorNotEqual:
+		default:
+			return fmt.Errorf("%w: unsupported operator %q", ErrInvalidBidRule, condition.Operator)
+		}
+	}
+
+	return nil
+}
+
+// BidChange is a change proposed by a bid rule for a keyword or an ad group.
+type BidChange struct {
+	Rule       string                    `json:"rule"`
+	Level      BidRuleLevel              `json:"level"`
+	CampaignID int64                     `json:"campaignId"`
+	AdGroupID  int64                     `json:"adGroupId"`
+	KeywordID  int64                     `json:"keywordId,omitempty"`
+	Name       string                    `json:"name,omitempty"`
+	OldBid     *Money                    `json:"oldBid,omitempty"`
+	NewBid     *Money                    `json:"newBid,omitempty"`
+	Pause      bool                      `json:"pause,omitempty"`
+	Metrics    map[BidRuleMetric]float64 `json:"metrics,omitempty"`
+	Applied    bool                      `json:"applied"`
+	Error      string                    `json:"error,omitempty"`
+
+	matchType        KeywordMatchType
+	modificationTime DateTime
+}
+
+// String returns a one-line description of the change.
+func (c *BidChange) String() string {
+	target := fmt.Sprintf("ad group %d", c.AdGroupID)
+	if c.Level == BidRuleLevelKeyword {
+		target = fmt.Sprintf("keyword %d %q in ad group %d", c.KeywordID, c.Name, c.AdGroupID)
+	}
+
+	if c.Pause {
+		return fmt.Sprintf("[%s] pause %s", c.Rule, target)
+	}
+
+	return fmt.Sprintf("[%s] %s bid %s -> %s %s", c.Rule, target, c.OldBid.Amount, c.NewBid.Amount, c.NewBid.Currency)
+}
+
+// Evaluate returns the change the rule makes to the entity of a report row, or nil when the row does not
+// match every condition of the rule. Deleted entities are never changed, and paused entities are not paused again.
+func (r *BidRule) Evaluate(campaignID int64, row *Row) *BidChange {
+	if row.Metadata == nil || row.Total == nil || r.Action == nil || row.Metadata.Deleted {
+		return nil
+	}
+
+	if r.Action.Pause && r.paused(row.Metadata) {
+		return nil
+	}
+
+	metrics := make(map[BidRuleMetric]float64, len(r.Conditions))
+
+	for _, condition := range r.Conditions {
+		value, ok := spendRowMetric(row.Total, condition.Metric)
+		if !ok || !compareMetric(value, condition.Operator, condition.Value) {
+			return nil
+		}
+
+		metrics[condition.Metric] = value
+	}
+
+	change := &BidChange{
+		Rule:             r.Name,
+		Level:            r.Level,
+		CampaignID:       campaignID,
+		AdGroupID:        row.Metadata.AdGroupID,
+		Metrics:          metrics,
+		Pause:            r.Action.Pause,
+		modificationTime: row.Metadata.ModificationTime,
+	}
+
+	if row.Metadata.CampaignID != 0 {
+		change.CampaignID = row.Metadata.CampaignID
+	}
+
+	if r.Level == BidRuleLevelKeyword {
+		change.KeywordID = row.Metadata.KeywordID
+		change.Name = row.Metadata.Keyword
+		change.OldBid = row.Metadata.BidAmount
+		change.matchType = keywordMatchTypeFromReport(row.Metadata.MatchType)
+	} else {
+		change.Name = row.Metadata.AdGroupName
+		change.OldBid = row.Metadata.DefaultCpcBid
+	}
+
+	if change.Pause {
+		return change
+	}
+
+	if change.OldBid == nil {
+		return nil
+	}
+
+	change.NewBid = r.Action.newBid(change.OldBid, row.Insights)
+	if change.NewBid.Amount == newMoney(moneyAmount(change.OldBid), change.OldBid.Currency).Amount {
+		return nil
+	}
+
+	return change
+}
+
+// paused reports whether the entity of the rule level is already paused.
+func (r *BidRule) paused(metadata *MetaDataObject) bool {
+	if r.Level == BidRuleLevelKeyword {
+		return metadata.KeywordStatus == KeywordStatusPaused
+	}
+
+	return metadata.AdGroupStatus == AdGroupStatusPaused
+}
+
+func (a *BidRuleAction) newBid(old *Money, insights *InsightsObject) *Money {
+	bid := moneyAmount(old) * (1 + a.AdjustPercent/percent)
+
+	if a.CeilingAtRecommendedMax && insights != nil && insights.BidRecommendation != nil && insights.BidRecommendation.BidMax != nil {
+		bid = math.Min(bid, moneyAmount(insights.BidRecommendation.BidMax))
+	}
+
+	if a.MaxBid > 0 {
+		bid = math.Min(bid, a.MaxBid)
+	}
+
+	if a.FloorAtRecommendedMin && insights != nil && insights.BidRecommendation != nil && insights.BidRecommendation.BidMin != nil {
+		bid = math.Max(bid, moneyAmount(insights.BidRecommendation.BidMin))
+	}
+
+	if a.MinBid > 0 {
+		bid = math.Max(bid, a.MinBid)
+	}
+
+	return newMoney(bid, old.Currency)
+}
+
+func spendRowMetric(row *SpendRow, metric BidRuleMetric) (float64, bool) {
+	switch metric {
+	case BidRuleMetricAvgCPA:
+		return moneyAmount(row.AvgCPA), true
+	case BidRuleMetricAvgCPT:
+		return moneyAmount(row.AvgCPT), true
+	case BidRuleMetricLocalSpend:
+		return moneyAmount(row.LocalSpend), true
+	case BidRuleMetricImpressions:
+		return float64(row.Impressions), true
+	case BidRuleMetricTaps:
+		return float64(row.Taps), true
+	case BidRuleMetricInstalls:
+		return float64(row.Installs), true
+	case BidRuleMetricConversionRate:
+		return row.ConversionRate, true
+	case BidRuleMetricTTR:
+		return row.Ttr, true
+	default:
+		return 0, false
+	}
+}
+
+func compareMetric(value float64, operator ConditionOperator, target float64) bool {
+	switch operator {
+	case ConditionOperatorGreaterThan:
+		return value > target
+	case ConditionOperatorLessThan:
+		return value < target
+	case ConditionOperatorEquals:
+		return value == target
+	case ConditionOperatorNotEqual:
+		return value != target
+	default:
+		return false
+	}
+}
+
+func keywordMatchTypeFromReport(matchType *ReportingKeywordMatchType) KeywordMatchType {
+	if matchType != nil && *matchType == ReportingKeywordMatchTypeBroad {
+		return KeywordMatchTypeBroad
+	}
+
+	return KeywordMatchTypeExact
+}
+
+// BidRuleRun is the outcome of a BidRuleEngine run.
+type BidRuleRun struct {
+	StartedAt time.Time `json:"startedAt"`
+	DryRun    bool      `json:"dryRun"`
+	// Changes are the changes within the change cap, applied unless the run is a dry run.
+	Changes []*BidChange `json:"changes,omitempty"`
+	// Skipped are the matching changes left out by the change cap.
+	Skipped []*BidChange `json:"skipped,omitempty"`
+}
+
+// String returns a human-readable report of the run.
+func (r *BidRuleRun) String() string {
+	b := strings.Builder{}
+
+	mode := "applied"
+	if r.DryRun {
+		mode = "dry run"
+	}
+
+	b.WriteString(fmt.Sprintf("Bid rule run at %s (%s): %d changes, %d skipped by the change cap\n", r.StartedAt.Format(time.RFC3339), mode, len(r.Changes), len(r.Skipped)))
+
+	for _, change := range r.Changes {
+		b.WriteString("  " + change.String())
+
+		if change.Error != "" {
+			b.WriteString(" FAILED: " + change.Error)
+		}
+
+		b.WriteString("\n")
+	}
+
+	for _, change := range r.Skipped {
+		b.WriteString("  skipped " + change.String() + "\n")
+	}
+
+	return b.String()
+}
+
+// BidRuleEngine evaluates bid rules against keyword and ad group reports and applies the resulting changes.
+type BidRuleEngine struct {
+	client *Client
+	config *BidRuleConfig
+
+	// DryRun evaluates the rules and reports the changes without applying them.
+	DryRun bool
+	// AuditLog receives one JSON line per proposed change when set.
+	AuditLog io.Writer
+	// Now returns the current time and defaults to time.Now. Report windows end the day before Now.
+	Now func() time.Time
+}
+
+// NewBidRuleEngine creates a BidRuleEngine evaluating config with client.
+func NewBidRuleEngine(client *Client, config *BidRuleConfig) *BidRuleEngine {
+	return &BidRuleEngine{
+		client: client,
+		config: config,
+		Now:    time.Now,
+	}
+}
+
+// Run evaluates every rule on every configured campaign, caps the number of changes and applies them unless the
+// engine is in dry-run mode. Failures to apply a change are recorded on the change and do not stop the run.
+func (e *BidRuleEngine) Run(ctx context.Context) (*BidRuleRun, error) {
+	if err := e.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	run := &BidRuleRun{StartedAt: e.Now(), DryRun: e.DryRun}
+
+	var changes []*BidChange
+
+	for _, campaignID := range e.config.CampaignIDs {
+		campaignChanges, err := e.evaluateCampaign(ctx, campaignID, run.StartedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, campaignChanges...)
+	}
+
+	run.Changes = changes
+	if e.config.MaxChanges > 0 && len(changes) > e.config.MaxChanges {
+		run.Changes, run.Skipped = changes[:e.config.MaxChanges], changes[e.config.MaxChanges:]
+	}
+
+	if !e.DryRun {
+		e.apply(ctx, run.Changes)
+	}
+
+	return run, e.audit(run)
+}
+
+func (e *BidRuleEngine) evaluateCampaign(ctx context.Context, campaignID int64, now time.Time) ([]*BidChange, error) {
+	type reportKey struct {
+		level        BidRuleLevel
+		lookbackDays int
+	}
+
+	reports := make(map[reportKey][]Row)
+	changed := make(map[string]bool)
+
+	var changes []*BidChange
+
+	for _, rule := range e.config.Rules {
+		key := reportKey{level: rule.Level, lookbackDays: rule.LookbackDays}
+
+		rows, ok := reports[key]
+		if !ok {
+			var err error
+
+			rows, err = e.fetchRows(ctx, campaignID, rule.Level, rule.LookbackDays, now)
+			if err != nil {
+				return nil, err
+			}
+
+			reports[key] = rows
+		}
+
+		for i := range rows {
+			change := rule.Evaluate(campaignID, &rows[i])
+			if change == nil {
+				continue
+			}
+
+			entity := fmt.Sprintf("%s/%d/%d", change.Level, change.AdGroupID, change.KeywordID)
+			if changed[entity] {
+				continue
+			}
+
+			changed[entity] = true
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func (e *BidRuleEngine) fetchRows(ctx context.Context, campaignID int64, level BidRuleLevel, lookbackDays int, now time.Time) ([]Row, error) {
+	end := now.AddDate(0, 0, -1)
+	params := &ReportingRequest{
+		StartTime:       Date{end.AddDate(0, 0, 1-lookbackDays)},
+		EndTime:         Date{end},
+		TimeZone:        ReportingRequestTimeZoneORTZ,
+		ReturnRowTotals: true,
+		Selector: &Selector{
+			OrderBy: []*Sorting{{Field: "localSpend", SortOrder: SortingOrderDescending}},
+		},
+	}
+
+	fetch := func(ctx context.Context, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
+		if level == BidRuleLevelKeyword {
+			return e.client.Reporting.GetKeywordLevelReports(ctx, campaignID, params)
+		}
+
+		return e.client.Reporting.GetAdGroupLevelReports(ctx, campaignID, params)
+	}
+
+	return fetchAllReportRows(ctx, params, fetch)
+}
+
+func (e *BidRuleEngine) apply(ctx context.Context, changes []*BidChange) {
+	type adGroupKey struct {
+		campaignID int64
+		adGroupID  int64
+	}
+
+	var order []adGroupKey
+
+	keywordChanges := make(map[adGroupKey][]*BidChange)
+
+	for _, change := range changes {
+		if change.Level == BidRuleLevelAdGroup {
+			_, _, err := e.client.AdGroups.UpdateAdGroup(ctx, change.CampaignID, change.AdGroupID, change.adGroupUpdate())
+			change.setResult(err)
+
+			continue
+		}
+
+		key := adGroupKey{campaignID: change.CampaignID, adGroupID: change.AdGroupID}
+		if _, ok := keywordChanges[key]; !ok {
+			order = append(order, key)
+		}
+
+		keywordChanges[key] = append(keywordChanges[key], change)
+	}
+
+	for _, key := range order {
+		batch := keywordChanges[key]
+		updates := make([]*KeywordUpdateRequest, len(batch))
+
+		for i, change := range batch {
+			updates[i] = change.keywordUpdate()
+		}
+
+		_, _, err := e.client.Keywords.UpdateTargetingKeywords(ctx, key.campaignID, key.adGroupID, updates)
+		for _, change := range batch {
+			change.setResult(err)
+		}
+	}
+}
+
+func (c *BidChange) setResult(err error) {
+	if err != nil {
+		c.Error = err.Error()
+
+		return
+	}
+
+	c.Applied = true
+}
+
+func (c *BidChange) keywordUpdate() *KeywordUpdateRequest {
+	update := &KeywordUpdateRequest{
+		ID:        c.KeywordID,
+		AdGroupID: c.AdGroupID,
+		MatchType: c.matchType,
+		BidAmount: c.NewBid,
+	}
+
+	if !c.modificationTime.IsZero() {
+		modificationTime := c.modificationTime
+		update.ModificationTime = &modificationTime
+	}
+
+	if c.Pause {
+		update.Status = KeywordStatusPaused
+	}
+
+	return update
+}
+
+func (c *BidChange) adGroupUpdate() *AdGroupUpdateRequest {
+	if c.Pause {
+		return &AdGroupUpdateRequest{Status: AdGroupStatusPaused}
+	}
+
+	return &AdGroupUpdateRequest{DefaultBidAmount: c.NewBid}
+}
+
+func (e *BidRuleEngine) audit(run *BidRuleRun) error {
+	if e.AuditLog == nil {
+		return nil
+	}
+
+	encoder := json.NewEncoder(e.AuditLog)
+
+	for _, change := range run.Changes {
+		entry := struct {
+			Time   time.Time `json:"time"`
+			DryRun bool      `json:"dryRun"`
+			*BidChange
+		}{run.StartedAt, run.DryRun, change}
+
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBidRuleEvaluate(t *testing.T) {
+	t.Parallel()
+
+	report := deserializeFileToReportingResponse(t, "../test/response_body_json_files/get_keyword_level_reports.json")
+	row := &report.ReportingCampaign.ReportingDataResponse.Rows[0]
+
+	lower := &BidRule{
+		Name:         "lower",
+		Level:        BidRuleLevelKeyword,
+		LookbackDays: 7,
+		Conditions:   []*BidRuleCondition{{Metric: BidRuleMetricInstalls, Operator: ConditionOperatorEquals, Value: 0}},
+		Action:       &BidRuleAction{AdjustPercent: -25, MinBid: 1.8},
+	}
+
+	change := lower.Evaluate(9, row)
+	assert.NotNil(t, change)
+	assert.Equal(t, int64(9), change.CampaignID)
+	assert.Equal(t, int64(1), change.AdGroupID)
+	assert.Equal(t, int64(1), change.KeywordID)
+	assert.Equal(t, "2", change.OldBid.Amount)
+	assert.Equal(t, &Money{Amount: "1.80", Currency: "USD"}, change.NewBid)
+	assert.Equal(t, KeywordMatchTypeExact, change.keywordUpdate().MatchType)
+
+	noMatch := &BidRule{
+		Name:         "raise",
+		Level:        BidRuleLevelKeyword,
+		LookbackDays: 7,
+		Conditions:   []*BidRuleCondition{{Metric: BidRuleMetricInstalls, Operator: ConditionOperatorGreaterThan, Value: 0}},
+		Action:       &BidRuleAction{AdjustPercent: 10},
+	}
+	assert.Nil(t, noMatch.Evaluate(9, row))
+
+	pause := &BidRule{
+		Name:         "pause",
+		Level:        BidRuleLevelKeyword,
+		LookbackDays: 7,
+		Conditions:   []*BidRuleCondition{{Metric: BidRuleMetricInstalls, Operator: ConditionOperatorEquals, Value: 0}},
+		Action:       &BidRuleAction{Pause: true},
+	}
+	change = pause.Evaluate(9, row)
+	assert.True(t, change.Pause)
+	assert.Equal(t, KeywordStatusPaused, change.keywordUpdate().Status)
+
+	paused := *row
+	paused.Metadata = &MetaDataObject{KeywordID: 2, KeywordStatus: KeywordStatusPaused, BidAmount: row.Metadata.BidAmount}
+	assert.Nil(t, pause.Evaluate(9, &paused), "paused keywords are not paused again")
+	assert.NotNil(t, lower.Evaluate(9, &paused), "the bid of paused keywords still changes")
+
+	deleted := *row
+	deleted.Metadata = &MetaDataObject{KeywordID: 3, Deleted: true, BidAmount: row.Metadata.BidAmount}
+	assert.Nil(t, lower.Evaluate(9, &deleted), "deleted keywords are never changed")
+	assert.Nil(t, pause.Evaluate(9, &deleted))
+
+	pauseAdGroup := &BidRule{
+		Name:         "pause ad group",
+		Level:        BidRuleLevelAdGroup,
+		LookbackDays: 7,
+		Conditions:   pause.Conditions,
+		Action:       &BidRuleAction{Pause: true},
+	}
+	adGroup := *row
+	adGroup.Metadata = &MetaDataObject{AdGroupID: 1, AdGroupStatus: AdGroupStatusEnabled}
+	assert.NotNil(t, pauseAdGroup.Evaluate(9, &adGroup))
+	adGroup.Metadata = &MetaDataObject{AdGroupID: 1, AdGroupStatus: AdGroupStatusPaused}
+	assert.Nil(t, pauseAdGroup.Evaluate(9, &adGroup))
+}
+
+func TestBidRuleActionRecommendationBounds(t *testing.T) {
+	t.Parallel()
+
+	insights := &InsightsObject{BidRecommendation: &KeywordBidRecommendation{
+		BidMin: &Money{Amount: "1", Currency: "USD"},
+		BidMax: &Money{Amount: "3", Currency: "USD"},
+	}}
+	old := &Money{Amount: "2", Currency: "USD"}
+
+	raise := &BidRuleAction{AdjustPercent: 100, CeilingAtRecommendedMax: true}
+	assert.Equal(t, "3.00", raise.newBid(old, insights).Amount)
+
+	lower := &BidRuleAction{AdjustPercent: -90, FloorAtRecommendedMin: true}
+	assert.Equal(t, "1.00", lower.newBid(old, insights).Amount)
+}
+
+func TestLoadBidRuleConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := LoadBidRuleConfig(strings.NewReader(`{
+		"campaignIds": [1],
+		"maxChanges": 5,
+		"rules": [{"name": "lower", "level": "KEYWORD", "lookbackDays": 7,
+			"conditions": [{"metric": "avgCPA", "operator": "GREATER_THAN", "value": 3}],
+			"action": {"adjustPercent": -10}}]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, config.MaxChanges)
+	assert.Equal(t, BidRuleMetricAvgCPA, config.Rules[0].Conditions[0].Metric)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"rules": [{"name": "x", "level": "KEYWORD", "lookbackDays": 7,
+		"conditions": [{"metric": "clicks", "operator": "GREATER_THAN", "value": 3}], "action": {"adjustPercent": -10}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"rules": [{"name": "x", "level": "CAMPAIGN", "lookbackDays": 7, "action": {"pause": true}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"rules": [{"name": "x", "level": "KEYWORD", "lookbackDays": 7, "action": {}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"rules": [{"name": "x", "level": "KEYWORD", "lookbackDays": 7, "action": {"pause": true}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"maxChanges": -1, "rules": []}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+
+	_, err = LoadBidRuleConfig(strings.NewReader(`{"rules": [{"name": "x", "level": "KEYWORD", "lookbackDays": 7, "conditions": [],
+		"action": {"pause": true}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidBidRule)
+}
+
+func TestBidRuleEngineRun(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"POST /reports/campaigns/1/keywords": `{"data":{"reportingDataResponse":{"row":[
+			{"metadata":{"keywordId":10,"keyword":"photo","matchType":"EXACT","adGroupId":5,"bidAmount":{"amount":"2","currency":"USD"}},
+			 "total":{"installs":0,"taps":40,"localSpend":{"amount":"20","currency":"USD"}}},
+			{"metadata":{"keywordId":11,"keyword":"editor","matchType":"BROAD","adGroupId":5,"bidAmount":{"amount":"1","currency":"USD"}},
+			 "total":{"installs":0,"taps":30,"localSpend":{"amount":"10","currency":"USD"}}}
+		]}}}`,
+		"POST /reports/campaigns/1/adgroups": `{"data":{"reportingDataResponse":{"row":[
+			{"metadata":{"adGroupId":5,"defaultCpcBid":{"amount":"1","currency":"USD"}},
+			 "total":{"installs":20,"taps":50,"avgCPA":{"amount":"0.5","currency":"USD"}}}
+		]}}}`,
+		"PUT /campaigns/1/adgroups/5/targetingkeywords/bulk": `{"data":[{"id":10}]}`,
+		"PUT /campaigns/1/adgroups/5":                        `{"data":{"id":5}}`,
+	})
+	defer server.Close()
+
+	config := &BidRuleConfig{
+		CampaignIDs: []int64{1},
+		MaxChanges:  2,
+		Rules: []*BidRule{
+			{
+				Name:         "cut spenders",
+				Level:        BidRuleLevelKeyword,
+				LookbackDays: 14,
+				Conditions: []*BidRuleCondition{
+					{Metric: BidRuleMetricInstalls, Operator: ConditionOperatorEquals, Value: 0},
+					{Metric: BidRuleMetricTaps, Operator: ConditionOperatorGreaterThan, Value: 20},
+				},
+				Action: &BidRuleAction{AdjustPercent: -50},
+			},
+			{
+				Name:         "raise winners",
+				Level:        BidRuleLevelAdGroup,
+				LookbackDays: 7,
+				Conditions:   []*BidRuleCondition{{Metric: BidRuleMetricAvgCPA, Operator: ConditionOperatorLessThan, Value: 1}},
+				Action:       &BidRuleAction{AdjustPercent: 20},
+			},
+		},
+	}
+
+	var audit bytes.Buffer
+
+	engine := NewBidRuleEngine(client, config)
+	engine.AuditLog = &audit
+	engine.Now = func() time.Time { return time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	run, err := engine.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, run.Changes, 2)
+	assert.Len(t, run.Skipped, 1)
+	assert.Equal(t, "raise winners", run.Skipped[0].Rule)
+
+	for _, change := range run.Changes {
+		assert.True(t, change.Applied, change.Error)
+	}
+
+	assert.Equal(t, "1.00", run.Changes[0].NewBid.Amount)
+	assert.Equal(t, 2, strings.Count(audit.String(), "\n"))
+	assert.Contains(t, run.String(), "skipped [raise winners] ad group 5")
+
+	engine.DryRun = true
+	run, err = engine.Run(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, run.Changes[0].Applied)
+}
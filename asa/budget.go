@@ -93,7 +93,7 @@ type BudgetOrder struct {
 func (s *BudgetService) GetBudgetOrder(ctx context.Context, boID int64) (*BudgetOrderInfoResponse, *Response, error) {
 	url := fmt.Sprintf("budgetorders/%d", boID)
 	res := new(BudgetOrderInfoResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"Budget", "GetBudgetOrder"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -119,7 +119,7 @@ type BudgetOrderInfoListResponse struct {
 func (s *BudgetService) GetAllBudgetOrders(ctx context.Context, params *GetAllBudgetOrdersQuery) (*BudgetOrderInfoListResponse, *Response, error) {
 	url := "budgetorders"
 	res := new(BudgetOrderInfoListResponse)
-	resp, err := s.client.get(ctx, url, params, res)
+	resp, err := s.client.get(ctx, operationName{"Budget", "GetAllBudgetOrders"}, url, params, res)
 
 	return res, resp, err
 }
@@ -0,0 +1,599 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	hoursPerDay                   = 24
+	defaultPacingTolerance        = 0.1
+	defaultPacingRunRateHours     = 3
+	defaultMaxBudgetAdjustPercent = 20
+)
+
+// PacingStatus is how the spend of a campaign or a budget order compares to its budget.
+type PacingStatus string
+
+const (
+	// PacingStatusOnPace is for a projected spend within the tolerance of the budget.
+	PacingStatusOnPace PacingStatus = "ON_PACE"
+	// PacingStatusUnderPacing is for a projected spend below the budget by more than the tolerance.
+	PacingStatusUnderPacing PacingStatus = "UNDER_PACING"
+	// PacingStatusOverPacing is for a projected spend above the budget by more than the tolerance.
+	PacingStatusOverPacing PacingStatus = "OVER_PACING"
+	// PacingStatusUnknown is for a campaign or a budget order without a budget to pace against.
+	PacingStatusUnknown PacingStatus = "UNKNOWN"
+)
+
+// BudgetPacingConfig is the configuration of a BudgetPacer.
+type BudgetPacingConfig struct {
+	// CampaignIDs are the campaigns to check. All campaigns that are not deleted are checked when empty.
+	CampaignIDs []int64
+	// Tolerance is the fraction of the budget the projected spend may deviate from before a campaign or a budget
+	// order is flagged. Defaults to 0.1.
+	Tolerance float64
+	// RunRateHours is the number of most recent complete hours used to project the rest of the day. Defaults to 3.
+	RunRateHours int
+	// Location is the time zone of the organization, which reports use for ORTZ. Defaults to UTC.
+	Location *time.Location
+
+	// AdjustDailyBudgets enables changing the daily budgets of the campaigns linked to a budget order that is not
+	// on pace, so that the remaining budget is spread over the remaining days of the order.
+	AdjustDailyBudgets bool
+	// MaxAdjustPercent caps the change of a daily budget in one run as a percentage of the current daily budget.
+	// Defaults to 20.
+	MaxAdjustPercent float64
+	// MinDailyBudget and MaxDailyBudget bound adjusted daily budgets. Zero disables the bound.
+	MinDailyBudget float64
+	MaxDailyBudget float64
+}
+
+// CampaignPacing is the pacing of the spend of a campaign for the current day against its daily budget.
+type CampaignPacing struct {
+	CampaignID     int64   `json:"campaignId"`
+	Name           string  `json:"name,omitempty"`
+	BudgetOrderIDs []int64 `json:"budgetOrderIds,omitempty"`
+	DailyBudget    *Money  `json:"dailyBudget,omitempty"`
+	SpendToday     *Money  `json:"spendToday"`
+	// HourlyRunRate is the average spend per hour over the most recent complete hours.
+	HourlyRunRate     *Money       `json:"hourlyRunRate"`
+	ProjectedDaySpend *Money       `json:"projectedDaySpend"`
+	Status            PacingStatus `json:"status"`
+}
+
+// BudgetOrderPacing is the pacing of the spend of the campaigns linked to a budget order against the budget of the
+// order between its start and end dates.
+type BudgetOrderPacing struct {
+	BudgetOrderID int64     `json:"budgetOrderId"`
+	Name          string    `json:"name,omitempty"`
+	Budget        *Money    `json:"budget,omitempty"`
+	StartDate     time.Time `json:"startDate"`
+	EndDate       time.Time `json:"endDate"`
+	CampaignIDs   []int64   `json:"campaignIds,omitempty"`
+	// Elapsed is the fraction of the order period that has passed.
+	Elapsed        float64 `json:"elapsed"`
+	Spend          *Money  `json:"spend"`
+	ExpectedSpend  *Money  `json:"expectedSpend,omitempty"`
+	ProjectedSpend *Money  `json:"projectedSpend,omitempty"`
+	// RecommendedDailySpend spreads the remaining budget evenly over the remaining days of the order.
+	RecommendedDailySpend *Money       `json:"recommendedDailySpend,omitempty"`
+	Status                PacingStatus `json:"status"`
+}
+
+// BudgetAdjustment is a change of the daily budget of a campaign made to bring a budget order back on pace.
+type BudgetAdjustment struct {
+	CampaignID     int64  `json:"campaignId"`
+	BudgetOrderID  int64  `json:"budgetOrderId"`
+	OldDailyBudget *Money `json:"oldDailyBudget"`
+	NewDailyBudget *Money `json:"newDailyBudget"`
+	Applied        bool   `json:"applied"`
+	Error          string `json:"error,omitempty"`
+}
+
+// BudgetPacingReport is the result of a BudgetPacer check.
+type BudgetPacingReport struct {
+	GeneratedAt  time.Time            `json:"generatedAt"`
+	Campaigns    []*CampaignPacing    `json:"campaigns,omitempty"`
+	BudgetOrders []*BudgetOrderPacing `json:"budgetOrders,omitempty"`
+	Adjustments  []*BudgetAdjustment  `json:"adjustments,omitempty"`
+}
+
+// String returns a human-readable summary of the report.
+func (r *BudgetPacingReport) String() string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf("Budget pacing at %s\n", r.GeneratedAt.Format(time.RFC3339)))
+
+	for _, c := range r.Campaigns {
+		b.WriteString(fmt.Sprintf("  campaign %d %q: spent %s, projected %s of daily budget %s (%s)\n",
+			c.CampaignID, c.Name, moneyString(c.SpendToday), moneyString(c.ProjectedDaySpend), moneyString(c.DailyBudget), c.Status))
+	}
+
+	for _, o := range r.BudgetOrders {
+		b.WriteString(fmt.Sprintf("  budget order %d %q: spent %s, expected %s, projected %s of %s (%s)\n",
+			o.BudgetOrderID, o.Name, moneyString(o.Spend), moneyString(o.ExpectedSpend), moneyString(o.ProjectedSpend), moneyString(o.Budget), o.Status))
+	}
+
+	for _, a := range r.Adjustments {
+		b.WriteString(fmt.Sprintf("  adjust campaign %d daily budget %s -> %s", a.CampaignID, moneyString(a.OldDailyBudget), moneyString(a.NewDailyBudget)))
+
+		switch {
+		case a.Error != "":
+			b.WriteString(" FAILED: " + a.Error)
+		case !a.Applied:
+			b.WriteString(" (not applied)")
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func moneyString(m *Money) string {
+	if m == nil {
+		return "-"
+	}
+
+	return m.Amount + " " + m.Currency
+}
+
+// BudgetPacer compares the spend reported for campaigns and budget orders with their budgets, and optionally
+// adjusts daily budgets within guardrails.
+type BudgetPacer struct {
+	client *Client
+	config *BudgetPacingConfig
+
+	// DryRun computes the adjustments without updating the campaigns.
+	DryRun bool
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewBudgetPacer creates a BudgetPacer for client. A nil config checks every campaign with the default settings.
+func NewBudgetPacer(client *Client, config *BudgetPacingConfig) *BudgetPacer {
+	if config == nil {
+		config = &BudgetPacingConfig{}
+	}
+
+	return &BudgetPacer{
+		client: client,
+		config: config,
+		Now:    time.Now,
+	}
+}
+
+// Check pulls today's hourly spend of the configured campaigns and the spend of their budget orders to date,
+// projects the end-of-day and end-of-order spend and flags anything under or over pacing. When AdjustDailyBudgets
+// is set, it updates the daily budgets of the campaigns linked to off-pace budget orders.
+func (p *BudgetPacer) Check(ctx context.Context) (*BudgetPacingReport, error) {
+	location := p.config.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	now := p.Now().In(location)
+	report := &BudgetPacingReport{GeneratedAt: now}
+
+	all, err := p.client.Campaigns.listAllCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := p.selectCampaigns(all)
+	if len(campaigns) == 0 {
+		return report, nil
+	}
+
+	report.Campaigns, err = p.campaignPacing(ctx, campaigns, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report.BudgetOrders, err = p.budgetOrderPacing(ctx, campaigns, all, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.AdjustDailyBudgets {
+		report.Adjustments = p.adjustments(report.BudgetOrders, campaigns, all)
+
+		if !p.DryRun {
+			p.applyAdjustments(ctx, report.Adjustments)
+		}
+	}
+
+	return report, nil
+}
+
+func (p *BudgetPacer) selectCampaigns(all []*Campaign) []*Campaign {
+	wanted := make(map[int64]bool, len(p.config.CampaignIDs))
+	for _, id := range p.config.CampaignIDs {
+		wanted[id] = true
+	}
+
+	var campaigns []*Campaign
+
+	for _, campaign := range all {
+		if campaign.Deleted || (len(wanted) > 0 && !wanted[campaign.ID]) {
+			continue
+		}
+
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns
+}
+
+func (p *BudgetPacer) tolerance() float64 {
+	if p.config.Tolerance > 0 {
+		return p.config.Tolerance
+	}
+
+	return defaultPacingTolerance
+}
+
+func (p *BudgetPacer) pacingStatus(projected float64, budget float64) PacingStatus {
+	switch {
+	case budget <= 0:
+		return PacingStatusUnknown
+	case projected > budget*(1+p.tolerance()):
+		return PacingStatusOverPacing
+	case projected < budget*(1-p.tolerance()):
+		return PacingStatusUnderPacing
+	default:
+		return PacingStatusOnPace
+	}
+}
+
+func (p *BudgetPacer) campaignPacing(ctx context.Context, campaigns []*Campaign, now time.Time) ([]*CampaignPacing, error) {
+	rows, err := p.campaignReport(ctx, campaigns, now, now, ReportingRequestGranularityTypeHourly)
+	if err != nil {
+		return nil, err
+	}
+
+	runRateHours := p.config.RunRateHours
+	if runRateHours <= 0 {
+		runRateHours = defaultPacingRunRateHours
+	}
+
+	elapsedHours := float64(now.Hour()) + float64(now.Minute())/float64(time.Hour/time.Minute)
+	pacing := make([]*CampaignPacing, 0, len(campaigns))
+
+	for _, campaign := range campaigns {
+		currency := campaignCurrency(campaign)
+		row := rows[campaign.ID]
+
+		spent := 0.0
+		if row != nil && row.Total != nil {
+			spent = moneyAmount(row.Total.LocalSpend)
+		}
+
+		rate := hourlyRunRate(row, now.Hour(), runRateHours)
+		if rate < 0 && elapsedHours > 0 {
+			rate = spent / elapsedHours
+		}
+
+		rate = math.Max(rate, 0)
+		projected := spent + rate*(hoursPerDay-elapsedHours)
+
+		pacing = append(pacing, &CampaignPacing{
+			CampaignID:        campaign.ID,
+			Name:              campaign.Name,
+			BudgetOrderIDs:    campaign.BudgetOrders,
+			DailyBudget:       campaign.DailyBudgetAmount,
+			SpendToday:        newMoney(spent, currency),
+			HourlyRunRate:     newMoney(rate, currency),
+			ProjectedDaySpend: newMoney(projected, currency),
+			Status:            p.pacingStatus(projected, moneyAmount(campaign.DailyBudgetAmount)),
+		})
+	}
+
+	return pacing, nil
+}
+
+// hourlyRunRate returns the average hourly spend over the last hours complete hours before currentHour, or -1 when
+// the row has no complete hours.
+func hourlyRunRate(row *Row, currentHour int, hours int) float64 {
+	if row == nil {
+		return -1
+	}
+
+	spend := make(map[int]float64, len(row.Granularity))
+	for _, hourly := range row.Granularity {
+		spend[hourly.Date.Hour()] += moneyAmount(hourly.LocalSpend)
+	}
+
+	from := currentHour - hours
+	if from < 0 {
+		from = 0
+	}
+
+	if currentHour == from {
+		return -1
+	}
+
+	total := 0.0
+	for hour := from; hour < currentHour; hour++ {
+		total += spend[hour]
+	}
+
+	return total / float64(currentHour-from)
+}
+
+func campaignCurrency(campaign *Campaign) string {
+	switch {
+	case campaign.DailyBudgetAmount != nil:
+		return campaign.DailyBudgetAmount.Currency
+	case campaign.BudgetAmount != nil:
+		return campaign.BudgetAmount.Currency
+	default:
+		return ""
+	}
+}
+
+func (p *BudgetPacer) budgetOrderPacing(ctx context.Context, campaigns []*Campaign, all []*Campaign, now time.Time) ([]*BudgetOrderPacing, error) {
+	var orderIDs []int64
+
+	seen := make(map[int64]bool)
+
+	for _, campaign := range campaigns {
+		for _, id := range campaign.BudgetOrders {
+			if !seen[id] {
+				seen[id] = true
+				orderIDs = append(orderIDs, id)
+			}
+		}
+	}
+
+	pacing := make([]*BudgetOrderPacing, 0, len(orderIDs))
+
+	for _, id := range orderIDs {
+		res, _, err := p.client.Budget.GetBudgetOrder(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.BudgetOrder == nil || res.BudgetOrder.Bo == nil {
+			continue
+		}
+
+		order, err := p.orderPacing(ctx, res.BudgetOrder.Bo, all, now)
+		if err != nil {
+			return nil, err
+		}
+
+		pacing = append(pacing, order)
+	}
+
+	return pacing, nil
+}
+
+func (p *BudgetPacer) orderPacing(ctx context.Context, order *BudgetOrder, all []*Campaign, now time.Time) (*BudgetOrderPacing, error) {
+	var linked []*Campaign
+
+	for _, campaign := range all {
+		for _, id := range campaign.BudgetOrders {
+			if id == order.ID {
+				linked = append(linked, campaign)
+
+				break
+			}
+		}
+	}
+
+	pacing := &BudgetOrderPacing{
+		BudgetOrderID: order.ID,
+		Name:          order.Name,
+		Budget:        order.Budget,
+		StartDate:     order.StartDate.Time,
+		EndDate:       order.EndDate.Time,
+		Status:        PacingStatusUnknown,
+	}
+
+	currency := ""
+	if order.Budget != nil {
+		currency = order.Budget.Currency
+	}
+
+	for _, campaign := range linked {
+		pacing.CampaignIDs = append(pacing.CampaignIDs, campaign.ID)
+	}
+
+	spent := 0.0
+
+	end := order.EndDate.Time
+	if end.IsZero() || now.Before(end) {
+		end = now
+	}
+
+	if !order.StartDate.IsZero() && !end.Before(order.StartDate.Time) && len(linked) > 0 {
+		rows, err := p.campaignReport(ctx, linked, order.StartDate.Time, end, "")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			if row.Total != nil {
+				spent += moneyAmount(row.Total.LocalSpend)
+			}
+		}
+	}
+
+	pacing.Spend = newMoney(spent, currency)
+
+	budget := moneyAmount(order.Budget)
+	if budget <= 0 || order.StartDate.IsZero() || order.EndDate.IsZero() || !order.EndDate.After(order.StartDate.Time) {
+		return pacing, nil
+	}
+
+	period := order.EndDate.Sub(order.StartDate.Time)
+	pacing.Elapsed = math.Min(math.Max(float64(now.Sub(order.StartDate.Time))/float64(period), 0), 1)
+	pacing.ExpectedSpend = newMoney(budget*pacing.Elapsed, currency)
+
+	if pacing.Elapsed == 0 {
+		return pacing, nil
+	}
+
+	projected := spent / pacing.Elapsed
+	pacing.ProjectedSpend = newMoney(projected, currency)
+	pacing.Status = p.pacingStatus(projected, budget)
+
+	remainingDays := math.Ceil(order.EndDate.Sub(now).Hours() / hoursPerDay)
+	if remainingDays > 0 {
+		pacing.RecommendedDailySpend = newMoney(math.Max(budget-spent, 0)/remainingDays, currency)
+	}
+
+	return pacing, nil
+}
+
+// campaignReport returns the campaign-level report rows of campaigns between start and end, keyed by campaign ID.
+func (p *BudgetPacer) campaignReport(ctx context.Context, campaigns []*Campaign, start time.Time, end time.Time, granularity ReportingRequestGranularity) (map[int64]*Row, error) {
+	ids := make([]string, len(campaigns))
+	for i, campaign := range campaigns {
+		ids[i] = fmt.Sprint(campaign.ID)
+	}
+
+	params := &ReportingRequest{
+		StartTime:       Date{start},
+		EndTime:         Date{end},
+		Granularity:     granularity,
+		TimeZone:        ReportingRequestTimeZoneORTZ,
+		ReturnRowTotals: true,
+		Selector: &Selector{
+			Conditions: []*Condition{{Field: "campaignId", Operator: ConditionOperatorIn, Values: ids}},
+			OrderBy:    []*Sorting{{Field: "localSpend", SortOrder: SortingOrderDescending}},
+		},
+	}
+
+	rows, err := fetchAllReportRows(ctx, params, p.client.Reporting.GetCampaignLevelReports)
+	if err != nil {
+		return nil, err
+	}
+
+	byCampaign := make(map[int64]*Row, len(rows))
+
+	for i := range rows {
+		if rows[i].Metadata != nil {
+			byCampaign[rows[i].Metadata.CampaignID] = &rows[i]
+		}
+	}
+
+	return byCampaign, nil
+}
+
+// adjustments splits the recommended daily spend of every off-pace budget order between its enabled campaigns in
+// proportion to their current daily budgets, and returns the resulting changes for the checked campaigns.
+func (p *BudgetPacer) adjustments(orders []*BudgetOrderPacing, campaigns []*Campaign, all []*Campaign) []*BudgetAdjustment {
+	maxPercent := p.config.MaxAdjustPercent
+	if maxPercent <= 0 {
+		maxPercent = defaultMaxBudgetAdjustPercent
+	}
+
+	byID := make(map[int64]*Campaign, len(all))
+	for _, campaign := range all {
+		byID[campaign.ID] = campaign
+	}
+
+	checked := make(map[int64]bool, len(campaigns))
+	for _, campaign := range campaigns {
+		checked[campaign.ID] = true
+	}
+
+	adjusted := make(map[int64]bool)
+
+	var adjustments []*BudgetAdjustment
+
+	for _, order := range orders {
+		if order.RecommendedDailySpend == nil || (order.Status != PacingStatusUnderPacing && order.Status != PacingStatusOverPacing) {
+			continue
+		}
+
+		var linked []*Campaign
+
+		current := 0.0
+
+		for _, id := range order.CampaignIDs {
+			campaign := byID[id]
+			if campaign == nil || campaign.Deleted || campaign.Status != CampaignStatusEnabled || moneyAmount(campaign.DailyBudgetAmount) <= 0 {
+				continue
+			}
+
+			linked = append(linked, campaign)
+			current += moneyAmount(campaign.DailyBudgetAmount)
+		}
+
+		for _, campaign := range linked {
+			if !checked[campaign.ID] || adjusted[campaign.ID] {
+				continue
+			}
+
+			old := moneyAmount(campaign.DailyBudgetAmount)
+			target := moneyAmount(order.RecommendedDailySpend) * old / current
+			target = p.clampDailyBudget(old, target, maxPercent)
+
+			next := newMoney(target, campaign.DailyBudgetAmount.Currency)
+			if next.Amount == newMoney(old, campaign.DailyBudgetAmount.Currency).Amount {
+				continue
+			}
+
+			adjusted[campaign.ID] = true
+			adjustments = append(adjustments, &BudgetAdjustment{
+				CampaignID:     campaign.ID,
+				BudgetOrderID:  order.BudgetOrderID,
+				OldDailyBudget: campaign.DailyBudgetAmount,
+				NewDailyBudget: next,
+			})
+		}
+	}
+
+	return adjustments
+}
+
+func (p *BudgetPacer) clampDailyBudget(old float64, target float64, maxPercent float64) float64 {
+	target = math.Min(math.Max(target, old*(1-maxPercent/percent)), old*(1+maxPercent/percent))
+
+	if p.config.MaxDailyBudget > 0 {
+		target = math.Min(target, p.config.MaxDailyBudget)
+	}
+
+	if p.config.MinDailyBudget > 0 {
+		target = math.Max(target, p.config.MinDailyBudget)
+	}
+
+	return target
+}
+
+func (p *BudgetPacer) applyAdjustments(ctx context.Context, adjustments []*BudgetAdjustment) {
+	for _, adjustment := range adjustments {
+		req := &UpdateCampaignRequest{Campaign: &CampaignUpdate{DailyBudgetAmount: adjustment.NewDailyBudget}}
+
+		if _, _, err := p.client.Campaigns.UpdateCampaign(ctx, adjustment.CampaignID, req); err != nil {
+			adjustment.Error = err.Error()
+
+			continue
+		}
+
+		adjustment.Applied = true
+	}
+}
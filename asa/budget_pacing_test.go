@@ -0,0 +1,124 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPacingServer() (*Client, func()) {
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns": `{"data":[
+			{"id":1,"name":"Brand","status":"ENABLED","dailyBudgetAmount":{"amount":"100","currency":"USD"},"budgetOrders":[7]},
+			{"id":2,"name":"Generic","status":"ENABLED","dailyBudgetAmount":{"amount":"50","currency":"USD"},"budgetOrders":[7]},
+			{"id":3,"name":"Old","deleted":true}
+		],"pagination":{"totalResults":3,"startIndex":0,"itemsPerPage":3}}`,
+		"POST /reports/campaigns": `{"data":{"reportingDataResponse":{"row":[
+			{"metadata":{"campaignId":1},"total":{"localSpend":{"amount":"60","currency":"USD"}},"granularity":[
+				{"date":"2021-06-15 09","localSpend":{"amount":"10","currency":"USD"}},
+				{"date":"2021-06-15 10","localSpend":{"amount":"10","currency":"USD"}},
+				{"date":"2021-06-15 11","localSpend":{"amount":"10","currency":"USD"}}
+			]},
+			{"metadata":{"campaignId":2},"total":{"localSpend":{"amount":"10","currency":"USD"}}}
+		]}}}`,
+		"GET /budgetorders/7": `{"data":{"bo":{"id":7,"name":"June","budget":{"amount":"1000","currency":"USD"},
+			"startDate":"2021-06-01T00:00:00.000","endDate":"2021-07-01T00:00:00.000","status":"ACTIVE"}}}`,
+		"PUT /campaigns/1": `{"data":{"id":1}}`,
+		"PUT /campaigns/2": `{"data":{"id":2}}`,
+	})
+
+	return client, server.Close
+}
+
+func TestBudgetPacerCheck(t *testing.T) {
+	t.Parallel()
+
+	client, closeServer := newPacingServer()
+	defer closeServer()
+
+	pacer := NewBudgetPacer(client, &BudgetPacingConfig{AdjustDailyBudgets: true})
+	pacer.Now = func() time.Time { return time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	report, err := pacer.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, report.Campaigns, 2)
+
+	brand := report.Campaigns[0]
+	assert.Equal(t, "10.00", brand.HourlyRunRate.Amount)
+	assert.Equal(t, "180.00", brand.ProjectedDaySpend.Amount)
+	assert.Equal(t, PacingStatusOverPacing, brand.Status)
+	assert.Equal(t, PacingStatusUnderPacing, report.Campaigns[1].Status)
+
+	assert.Len(t, report.BudgetOrders, 1)
+	order := report.BudgetOrders[0]
+	assert.Equal(t, []int64{1, 2}, order.CampaignIDs)
+	assert.Equal(t, "70.00", order.Spend.Amount)
+	assert.Equal(t, PacingStatusUnderPacing, order.Status)
+	assert.Equal(t, "58.12", order.RecommendedDailySpend.Amount)
+
+	assert.Len(t, report.Adjustments, 2)
+	assert.Equal(t, "80.00", report.Adjustments[0].NewDailyBudget.Amount)
+	assert.Equal(t, "40.00", report.Adjustments[1].NewDailyBudget.Amount)
+
+	for _, adjustment := range report.Adjustments {
+		assert.True(t, adjustment.Applied, adjustment.Error)
+	}
+
+	assert.Contains(t, report.String(), "adjust campaign 1 daily budget 100 USD -> 80.00 USD")
+}
+
+func TestBudgetPacerDryRunGuardrails(t *testing.T) {
+	t.Parallel()
+
+	client, closeServer := newPacingServer()
+	defer closeServer()
+
+	pacer := NewBudgetPacer(client, &BudgetPacingConfig{
+		CampaignIDs:        []int64{2},
+		AdjustDailyBudgets: true,
+		MaxAdjustPercent:   50,
+		MinDailyBudget:     30,
+	})
+	pacer.DryRun = true
+	pacer.Now = func() time.Time { return time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	report, err := pacer.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, report.Campaigns, 1)
+	assert.Len(t, report.Adjustments, 1)
+	assert.Equal(t, "30.00", report.Adjustments[0].NewDailyBudget.Amount)
+	assert.False(t, report.Adjustments[0].Applied)
+	assert.Contains(t, report.String(), "(not applied)")
+}
+
+func TestHourlyRunRate(t *testing.T) {
+	t.Parallel()
+
+	row := &Row{Granularity: []*ExtendedSpendRow{
+		{Date: Date{time.Date(2021, 6, 15, 1, 0, 0, 0, time.UTC)}, LocalSpend: &Money{Amount: "4", Currency: "USD"}},
+		{Date: Date{time.Date(2021, 6, 15, 2, 0, 0, 0, time.UTC)}, LocalSpend: &Money{Amount: "2", Currency: "USD"}},
+	}}
+
+	assert.Equal(t, 3.0, hourlyRunRate(row, 3, 2))
+	assert.Equal(t, 2.0, hourlyRunRate(row, 3, 3))
+	assert.Equal(t, -1.0, hourlyRunRate(row, 0, 3))
+	assert.Equal(t, -1.0, hourlyRunRate(nil, 5, 3))
+}
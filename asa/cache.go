@@ -0,0 +1,569 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheDirPerm       = 0o700
+	cacheFilePerm      = 0o600
+	cacheFileExtension = ".json"
+	metadataCacheTTL   = 24 * time.Hour
+	searchCacheTTL     = time.Hour
+	headerCache        = "X-Asa-Cache"
+	cacheStatusHit     = "HIT"
+	cacheStatusRevalid = "REVALIDATED"
+)
+
+// ErrCacheMiss happens when a CacheBackend has no entry for a key.
+var ErrCacheMiss = errors.New("cache miss")
+
+// CacheEntry is a response stored by a ResponseCache.
+type CacheEntry struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Path       string      `json:"path"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body"`
+	ETag       string      `json:"etag,omitempty"`
+	StoredAt   time.Time   `json:"storedAt"`
+	ExpiresAt  time.Time   `json:"expiresAt"`
+}
+
+// httpResponse rebuilds the stored response, marking it with the cache status.
+func (e *CacheEntry) httpResponse(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	header.Set(headerCache, status)
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// CacheBackend stores the entries of a ResponseCache. Implementations must be safe for concurrent use.
+type CacheBackend interface {
+	// Get returns the entry for key, or ErrCacheMiss.
+	Get(key string) (*CacheEntry, error)
+	Set(key string, entry *CacheEntry) error
+	Delete(key string) error
+	Keys() ([]string, error)
+}
+
+// LRUCacheBackend is an in-memory CacheBackend that evicts the least recently used entry beyond its capacity.
+type LRUCacheBackend struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCacheBackend creates an LRUCacheBackend holding up to capacity entries. A capacity of zero or less means
+// no limit.
+func NewLRUCacheBackend(capacity int) *LRUCacheBackend {
+	return &LRUCacheBackend{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry for key and marks it as recently used.
+func (b *LRUCacheBackend) Get(key string) (*CacheEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	element, ok := b.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	b.order.MoveToFront(element)
+
+	return element.Value.(*lruItem).entry, nil
+}
+
+// Set stores entry under key and evicts the least recently used entry when the backend is full.
+func (b *LRUCacheBackend) Set(key string, entry *CacheEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if element, ok := b.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		b.order.MoveToFront(element)
+
+		return nil
+	}
+
+	b.items[key] = b.order.PushFront(&lruItem{key: key, entry: entry})
+
+	if b.capacity > 0 && b.order.Len() > b.capacity {
+		oldest := b.order.Back()
+		b.order.Remove(oldest)
+		delete(b.items, oldest.Value.(*lruItem).key)
+	}
+
+	return nil
+}
+
+// Delete removes the entry for key.
+func (b *LRUCacheBackend) Delete(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if element, ok := b.items[key]; ok {
+		b.order.Remove(element)
+		delete(b.items, key)
+	}
+
+	return nil
+}
+
+// Keys returns the keys of every entry.
+func (b *LRUCacheBackend) Keys() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(b.items))
+	for key := range b.items {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// DiskCacheBackend is a CacheBackend that stores each entry as a JSON file in a directory.
+type DiskCacheBackend struct {
+	dir string
+}
+
+// NewDiskCacheBackend creates a DiskCacheBackend in dir, creating the directory if needed.
+func NewDiskCacheBackend(dir string) (*DiskCacheBackend, error) {
+	if err := os.MkdirAll(dir, cacheDirPerm); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheBackend{dir: dir}, nil
+}
+
+func (b *DiskCacheBackend) path(key string) string {
+	return filepath.Join(b.dir, key+cacheFileExtension)
+}
+
+// Get reads the entry for key.
+func (b *DiskCacheBackend) Get(key string) (*CacheEntry, error) {
+	raw, err := ioutil.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	entry := &CacheEntry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Set writes the entry for key through a temporary file so that readers never see a partial entry.
+func (b *DiskCacheBackend) Set(key string, entry *CacheEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(b.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), cacheFilePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), b.path(key))
+}
+
+// Delete removes the entry for key.
+func (b *DiskCacheBackend) Delete(key string) error {
+	if err := os.Remove(b.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Keys returns the keys of every entry in the directory.
+func (b *DiskCacheBackend) Keys() ([]string, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if name := file.Name(); strings.HasSuffix(name, cacheFileExtension) {
+			keys = append(keys, strings.TrimSuffix(name, cacheFileExtension))
+		}
+	}
+
+	return keys, nil
+}
+
+// CacheRule enables caching for the requests with Method whose path relative to the API base URL starts with
+// PathPrefix, for TTL unless the response sets its own max-age.
+type CacheRule struct {
+	Method     string
+	PathPrefix string
+	TTL        time.Duration
+}
+
+func (r CacheRule) matches(method string, path string) bool {
+	return r.Method == method && strings.HasPrefix(path, r.PathPrefix)
+}
+
+// DefaultCacheRules returns the rules for the slowly changing metadata endpoints: geo search and lookup, app
+// search, app preview device sizes and the user ACL.
+func DefaultCacheRules() []CacheRule {
+	return []CacheRule{
+		{Method: http.MethodGet, PathPrefix: "search/geo", TTL: metadataCacheTTL},
+		{Method: http.MethodPost, PathPrefix: "search/geo", TTL: metadataCacheTTL},
+		{Method: http.MethodGet, PathPrefix: "search/apps", TTL: searchCacheTTL},
+		{Method: http.MethodGet, PathPrefix: "creativeappassets/devices", TTL: metadataCacheTTL},
+		{Method: http.MethodGet, PathPrefix: "acls", TTL: searchCacheTTL},
+	}
+}
+
+// ResponseCache caches successful API responses by method, URL and request body, scoped to the organization and API
+// client ID of the AuthTransport of the client so that clients sharing a CacheBackend never see each other's
+// responses. Clients sending requests through another transport cannot be told apart and need a backend of their own
+// for each organization.
+//
+// Only requests matching one of its rules are cached. Responses marked Cache-Control no-store are never stored,
+// and a response max-age overrides the TTL of the rule. Expired entries with an ETag are revalidated with
+// If-None-Match. A successful mutation invalidates every entry sharing the first path segment of the mutated
+// resource, so that an update of campaigns/1 drops cached campaigns responses.
+type ResponseCache struct {
+	backend CacheBackend
+	rules   []CacheRule
+
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewResponseCache creates a ResponseCache storing entries in backend. The DefaultCacheRules apply when no rules
+// are given; a nil backend is replaced with an unbounded LRUCacheBackend.
+func NewResponseCache(backend CacheBackend, rules ...CacheRule) *ResponseCache {
+	if backend == nil {
+		backend = NewLRUCacheBackend(0)
+	}
+
+	if len(rules) == 0 {
+		rules = DefaultCacheRules()
+	}
+
+	return &ResponseCache{
+		backend: backend,
+		rules:   rules,
+		Now:     time.Now,
+	}
+}
+
+// SetCache enables the response cache of the client. A nil cache disables caching.
+func (c *Client) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
+// Cached reports whether the body of the response came from the response cache of the client, either directly or
+// after the server confirmed that the cached entry is still valid.
+func (r *Response) Cached() bool {
+	return r.Response != nil && r.Header.Get(headerCache) != ""
+}
+
+// Invalidate removes every entry whose path starts with prefix. An empty prefix clears the cache.
+func (rc *ResponseCache) Invalidate(prefix string) error {
+	keys, err := rc.backend.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		entry, err := rc.backend.Get(key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry.Path, prefix) {
+			if err := rc.backend.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cacheLookup is the state of a request going through the cache.
+type cacheLookup struct {
+	key      string
+	path     string
+	rule     *CacheRule
+	fresh    *CacheEntry
+	stale    *CacheEntry
+	mutation bool
+}
+
+func (rc *ResponseCache) lookup(req *http.Request, path string, scope string) *cacheLookup {
+	lookup := &cacheLookup{path: path, mutation: isMutatingRequest(req.Method, path)}
+
+	for i := range rc.rules {
+		if rc.rules[i].matches(req.Method, path) {
+			lookup.rule = &rc.rules[i]
+
+			break
+		}
+	}
+
+	if lookup.rule == nil {
+		return lookup
+	}
+
+	lookup.key = cacheKey(req, scope)
+
+	entry, err := rc.backend.Get(lookup.key)
+	if err != nil {
+		return lookup
+	}
+
+	if rc.Now().Before(entry.ExpiresAt) {
+		lookup.fresh = entry
+	} else {
+		lookup.stale = entry
+	}
+
+	return lookup
+}
+
+// prepare asks the server to revalidate a stale entry.
+func (l *cacheLookup) prepare(req *http.Request) {
+	if l.stale != nil && l.stale.ETag != "" {
+		req.Header.Set("If-None-Match", l.stale.ETag)
+	}
+}
+
+// store caches a successful response, answers a 304 Not Modified from the stale entry and invalidates entries after
+// successful mutations. It returns the response to decode.
+func (rc *ResponseCache) store(lookup *cacheLookup, req *http.Request, resp *http.Response) *http.Response {
+	successful := resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+
+	if lookup.mutation {
+		if successful {
+			_ = rc.Invalidate(topLevelPathSegment(lookup.path))
+		}
+
+		return resp
+	}
+
+	if lookup.rule == nil {
+		return resp
+	}
+
+	ttl, storable := cacheControlTTL(resp.Header, lookup.rule.TTL)
+
+	if resp.StatusCode == http.StatusNotModified && lookup.stale != nil {
+		lookup.stale.ExpiresAt = rc.Now().Add(ttl)
+		_ = rc.backend.Set(lookup.key, lookup.stale)
+
+		return lookup.stale.httpResponse(req, cacheStatusRevalid)
+	}
+
+	if !successful || !storable {
+		return resp
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+
+		return resp
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	etag := resp.Header.Get("ETag")
+	if ttl <= 0 && etag == "" {
+		return resp
+	}
+
+	now := rc.Now()
+	_ = rc.backend.Set(lookup.key, &CacheEntry{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Path:       lookup.path,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		ETag:       etag,
+		StoredAt:   now,
+		ExpiresAt:  now.Add(ttl),
+	})
+
+	return resp
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// cacheControlTTL applies the Cache-Control header of a response to the TTL of a rule. It reports false when the
+// response must not be stored.
+func cacheControlTTL(header http.Header, ttl time.Duration) (time.Duration, bool) {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case directive == "no-cache":
+			ttl = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	return ttl, true
+}
+
+// topLevelPathSegment returns the first segment of a relative path, e.g. "campaigns" for "campaigns/1/adgroups".
+func topLevelPathSegment(path string) string {
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i]
+	}
+
+	return path
+}
+
+// cacheKey identifies a request by the scope of its credentials, method, URL and body. The organization is part of
+// the scope, as the AuthTransport only sets the X-AP-Context header once the request is sent.
+func cacheKey(req *http.Request, scope string) string {
+	hash := sha256.New()
+	hash.Write([]byte(scope + "\n"))
+	hash.Write([]byte(req.Method + " " + req.URL.String() + "\n"))
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			_, _ = io.Copy(hash, body)
+			body.Close()
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type noCacheKey struct{}
+
+// withoutCache marks ctx so that requests made with it neither read nor fill the response cache, for callers that need
+// the current state of an entity.
+func withoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheScope identifies the credentials the requests of the client are sent with, empty when its transport is not an
+// AuthTransport.
+func (c *Client) cacheScope() string {
+	switch transport := c.client.Transport.(type) {
+	case *AuthTransport:
+		return transport.cacheScope()
+	case AuthTransport:
+		return transport.cacheScope()
+	default:
+		return ""
+	}
+}
+
+// isMutatingRequest reports whether a request changes data. Find, report and search requests are POSTs that only
+// read data.
+func isMutatingRequest(method string, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	case http.MethodPost:
+		return !(strings.HasSuffix(path, "/find") ||
+			strings.HasPrefix(path, "reports/") ||
+			strings.HasPrefix(path, "search/") ||
+			strings.HasPrefix(path, "creativeappassets/"))
+	default:
+		return true
+	}
+}
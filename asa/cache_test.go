@@ -0,0 +1,277 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheServer struct {
+	mu      sync.Mutex
+	hits    map[string]int
+	header  http.Header
+	matches string
+}
+
+func (s *cacheServer) count(route string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hits[route]
+}
+
+func newCacheServer(header http.Header) (*Client, *cacheServer, func()) {
+	cs := &cacheServer{hits: make(map[string]int), header: header}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.mu.Lock()
+		cs.hits[r.Method+" "+r.URL.Path]++
+		cs.matches = r.Header.Get("If-None-Match")
+		cs.mu.Unlock()
+
+		for key, values := range cs.header {
+			w.Header()[key] = values
+		}
+
+		if etag := cs.header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		fmt.Fprintln(w, `{"data":[{"adamId":1,"appName":"Photos","developerName":"Dev"}]}`)
+	}))
+
+	client, _ := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+
+	return client, cs, server.Close
+}
+
+func TestLRUCacheBackend(t *testing.T) {
+	t.Parallel()
+
+	backend := NewLRUCacheBackend(2)
+	assert.NoError(t, backend.Set("a", &CacheEntry{Path: "a"}))
+	assert.NoError(t, backend.Set("b", &CacheEntry{Path: "b"}))
+
+	_, err := backend.Get("a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, backend.Set("c", &CacheEntry{Path: "c"}))
+
+	_, err = backend.Get("b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	keys, err := backend.Keys()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c"}, keys)
+
+	assert.NoError(t, backend.Delete("a"))
+
+	_, err = backend.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestDiskCacheBackend(t *testing.T) {
+	t.Parallel()
+
+	backend, err := NewDiskCacheBackend(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = backend.Get("missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	entry := &CacheEntry{Method: http.MethodGet, Path: "search/apps", StatusCode: http.StatusOK, Body: []byte(`{}`), ETag: `"v1"`}
+	assert.NoError(t, backend.Set("key", entry))
+
+	got, err := backend.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, entry, got)
+
+	keys, err := backend.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+
+	assert.NoError(t, backend.Delete("key"))
+	assert.NoError(t, backend.Delete("key"))
+}
+
+func TestResponseCacheHit(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newCacheServer(nil)
+	defer closeServer()
+
+	client.SetCache(NewResponseCache(nil))
+
+	query := &SearchAppsQuery{Query: "photo"}
+
+	first, resp, err := client.App.SearchApps(context.Background(), query)
+	assert.NoError(t, err)
+	assert.False(t, resp.Cached())
+
+	second, resp, err := client.App.SearchApps(context.Background(), query)
+	assert.NoError(t, err)
+	assert.True(t, resp.Cached())
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, server.count("GET /search/apps"))
+
+	_, _, err = client.App.SearchApps(context.Background(), &SearchAppsQuery{Query: "video"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.count("GET /search/apps"))
+}
+
+func TestResponseCacheScopedByOrganization(t *testing.T) {
+	t.Parallel()
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintf(w, `{"data":[{"orgName":%q}]}`, r.Header.Get("X-AP-Context"))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(NewLRUCacheBackend(0))
+
+	newOrgClient := func(orgID string) *Client {
+		auth := &AuthTransport{
+			Transport:    server.Client().Transport,
+			jwtGenerator: &mockJWTGenerator{accessToken: &accessToken{AccessToken: "TEST.TEST.TEST"}},
+			orgID:        orgID,
+		}
+		client, _ := NewClientWithOptions(WithHTTPClient(auth.Client()), WithBaseURL(server.URL))
+		client.SetCache(cache)
+
+		return client
+	}
+
+	first, second := newOrgClient("1"), newOrgClient("2")
+
+	acls, _, err := first.AccessControlList.GetUserACL(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "orgId=1", acls.UserAcls[0].OrgName)
+
+	acls, resp, err := second.AccessControlList.GetUserACL(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, resp.Cached())
+	assert.Equal(t, "orgId=2", acls.UserAcls[0].OrgName)
+
+	acls, resp, err = first.AccessControlList.GetUserACL(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, resp.Cached())
+	assert.Equal(t, "orgId=1", acls.UserAcls[0].OrgName)
+	assert.Equal(t, 2, hits)
+
+	sameOrg := AuthTransport{orgID: "1", jwtGenerator: &standardJWTGenerator{clientID: "SEARCHADS.a"}}
+	otherUser := AuthTransport{orgID: "1", jwtGenerator: &standardJWTGenerator{clientID: "SEARCHADS.b"}}
+	assert.NotEqual(t, sameOrg.cacheScope(), otherUser.cacheScope())
+	assert.NotContains(t, sameOrg.cacheScope(), "SEARCHADS")
+}
+
+func TestResponseCacheRevalidate(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newCacheServer(http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-cache"}})
+	defer closeServer()
+
+	client.SetCache(NewResponseCache(nil))
+
+	_, _, err := client.App.SearchApps(context.Background(), nil)
+	assert.NoError(t, err)
+
+	apps, resp, err := client.App.SearchApps(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.True(t, resp.Cached())
+	assert.Equal(t, "Photos", apps.AppInfos[0].AppName)
+	assert.Equal(t, 2, server.count("GET /search/apps"))
+	assert.Equal(t, `"v1"`, server.matches)
+}
+
+func TestResponseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newCacheServer(http.Header{"Cache-Control": {"no-store"}})
+	defer closeServer()
+
+	client.SetCache(NewResponseCache(nil))
+
+	for i := 0; i < 2; i++ {
+		_, resp, err := client.App.SearchApps(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.False(t, resp.Cached())
+	}
+
+	assert.Equal(t, 2, server.count("GET /search/apps"))
+
+	ttl, storable := cacheControlTTL(http.Header{"Cache-Control": {"private, max-age=60"}}, time.Hour)
+	assert.True(t, storable)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestResponseCacheExpiryAndInvalidation(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns":   `{"data":[{"id":1}]}`,
+		"PUT /campaigns/1": `{"data":{"id":1}}`,
+	})
+	defer server.Close()
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewResponseCache(nil, CacheRule{Method: http.MethodGet, PathPrefix: "campaigns", TTL: time.Minute})
+	cache.Now = func() time.Time { return now }
+	client.SetCache(cache)
+
+	_, _, err := client.Campaigns.GetAllCampaigns(context.Background(), nil)
+	assert.NoError(t, err)
+
+	_, resp, err := client.Campaigns.GetAllCampaigns(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.True(t, resp.Cached())
+
+	now = now.Add(2 * time.Minute)
+
+	_, resp, err = client.Campaigns.GetAllCampaigns(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.False(t, resp.Cached())
+
+	_, _, err = client.Campaigns.UpdateCampaign(context.Background(), 1, &UpdateCampaignRequest{Campaign: &CampaignUpdate{}})
+	assert.NoError(t, err)
+
+	_, resp, err = client.Campaigns.GetAllCampaigns(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.False(t, resp.Cached())
+}
+
+func TestIsMutatingRequest(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, isMutatingRequest(http.MethodGet, "campaigns"))
+	assert.False(t, isMutatingRequest(http.MethodPost, "campaigns/find"))
+	assert.False(t, isMutatingRequest(http.MethodPost, "reports/campaigns"))
+	assert.False(t, isMutatingRequest(http.MethodPost, "search/geo"))
+	assert.True(t, isMutatingRequest(http.MethodPost, "campaigns"))
+	assert.True(t, isMutatingRequest(http.MethodPut, "campaigns/1"))
+	assert.True(t, isMutatingRequest(http.MethodDelete, "campaigns/1"))
+}
@@ -278,7 +278,7 @@ type ErrorResponseItem struct {
 // https://developer.apple.com/documentation/apple_search_ads/get_all_campaigns
 func (s *CampaignService) GetAllCampaigns(ctx context.Context, params *GetAllCampaignQuery) (*CampaignListResponse, *Response, error) {
 	res := new(CampaignListResponse)
-	resp, err := s.client.get(ctx, "campaigns", &params, res)
+	resp, err := s.client.get(ctx, operationName{"Campaigns", "GetAllCampaigns"}, "campaigns", &params, res)
 
 	return res, resp, err
 }
@@ -289,7 +289,7 @@ func (s *CampaignService) GetAllCampaigns(ctx context.Context, params *GetAllCam
 func (s *CampaignService) GetCampaign(ctx context.Context, campaignID int64) (*CampaignResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d", campaignID)
 	res := new(CampaignResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"Campaigns", "GetCampaign"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -300,7 +300,7 @@ func (s *CampaignService) GetCampaign(ctx context.Context, campaignID int64) (*C
 func (s *CampaignService) FindCampaigns(ctx context.Context, selector *Selector) (*CampaignListResponse, *Response, error) {
 	url := "campaigns/find"
 	res := new(CampaignListResponse)
-	resp, err := s.client.post(ctx, url, selector, res)
+	resp, err := s.client.post(ctx, operationName{"Campaigns", "FindCampaigns"}, url, selector, res)
 
 	return res, resp, err
 }
@@ -310,7 +310,7 @@ func (s *CampaignService) FindCampaigns(ctx context.Context, selector *Selector)
 // https://developer.apple.com/documentation/apple_search_ads/delete_a_campaign
 func (s *CampaignService) DeleteCampaign(ctx context.Context, campaignID int64) (*Response, error) {
 	url := fmt.Sprintf("campaigns/%d", campaignID)
-	resp, err := s.client.delete(ctx, url, nil)
+	resp, err := s.client.delete(ctx, operationName{"Campaigns", "DeleteCampaign"}, url, nil)
 
 	return resp, err
 }
@@ -321,22 +321,22 @@ func (s *CampaignService) DeleteCampaign(ctx context.Context, campaignID int64)
 func (s *CampaignService) CreateCampaign(ctx context.Context, campaign *Campaign) (*CampaignResponse, *Response, error) {
 	url := "campaigns"
 	res := new(CampaignResponse)
-	resp, err := s.client.post(ctx, url, campaign, res)
+	resp, err := s.client.post(ctx, operationName{"Campaigns", "CreateCampaign"}, url, campaign, res)
 
 	return res, resp, err
 }
 
-// CampaignUpdate is the list of campaign fields that are updatable
+// CampaignUpdate is the list of campaign fields that are updatable. Nil and empty fields are left unchanged.
 //
 // https://developer.apple.com/documentation/apple_search_ads/campaignupdate
 type CampaignUpdate struct {
-	BudgetAmount       *Money            `json:"budgetAmount,omitempty"`
-	BudgetOrders       int64             `json:"budgetOrders,omitempty"`
-	CountriesOrRegions []string          `json:"countriesOrRegions,omitempty"`
-	DailyBudgetAmount  *Money            `json:"dailyBudgetAmount,omitempty"`
-	LOCInvoiceDetails  LOCInvoiceDetails `json:"locInvoiceDetails,omitempty"`
-	Name               string            `json:"name,omitempty"`
-	Status             *CampaignStatus   `json:"status,omitempty"`
+	BudgetAmount       *Money             `json:"budgetAmount,omitempty"`
+	BudgetOrders       []int64            `json:"budgetOrders,omitempty"`
+	CountriesOrRegions []string           `json:"countriesOrRegions,omitempty"`
+	DailyBudgetAmount  *Money             `json:"dailyBudgetAmount,omitempty"`
+	LOCInvoiceDetails  *LOCInvoiceDetails `json:"locInvoiceDetails,omitempty"`
+	Name               string             `json:"name,omitempty"`
+	Status             *CampaignStatus    `json:"status,omitempty"`
 }
 
 // UpdateCampaignRequest is the payload properties to clear Geo Targeting from a campaign
@@ -353,7 +353,27 @@ type UpdateCampaignRequest struct {
 func (s *CampaignService) UpdateCampaign(ctx context.Context, campaignID int64, req *UpdateCampaignRequest) (*CampaignResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d", campaignID)
 	res := new(CampaignResponse)
-	resp, err := s.client.put(ctx, url, req, res)
+	resp, err := s.client.put(ctx, operationName{"Campaigns", "UpdateCampaign"}, url, req, res)
 
 	return res, resp, err
 }
+
+// listAllCampaigns pages through GetAllCampaigns and returns every campaign of the organization.
+func (s *CampaignService) listAllCampaigns(ctx context.Context) ([]*Campaign, error) {
+	var campaigns []*Campaign
+
+	for offset := 0; ; {
+		res, _, err := s.GetAllCampaigns(ctx, &GetAllCampaignQuery{Limit: findPageLimit, Offset: int32(offset)})
+		if err != nil {
+			return nil, err
+		}
+
+		campaigns = append(campaigns, res.Campaigns...)
+
+		if !hasNextPage(res.Pagination, offset, len(res.Campaigns)) {
+			return campaigns, nil
+		}
+
+		offset += len(res.Campaigns)
+	}
+}
@@ -0,0 +1,395 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a CassetteTransport records or replays interactions.
+type CassetteMode string
+
+const (
+	// CassetteModeRecord sends requests to the API and records the interactions.
+	CassetteModeRecord CassetteMode = "record"
+	// CassetteModeReplay serves recorded interactions without network access.
+	CassetteModeReplay CassetteMode = "replay"
+)
+
+const (
+	cassetteRedacted = "REDACTED"
+	scrubbedOrgID    = 0
+)
+
+// ErrCassetteNoMatch happens when a replaying CassetteTransport has no recorded interaction for a request.
+var ErrCassetteNoMatch = errors.New("no recorded interaction matches the request")
+
+// ErrUnknownCassetteMode happens when a CassetteTransport is created with an unknown mode.
+var ErrUnknownCassetteMode = errors.New("unknown cassette mode")
+
+// CassetteRequest is a recorded request.
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response.
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// CassetteInteraction is a recorded request with its response.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []*CassetteInteraction `json:"interactions"`
+}
+
+// CassetteTransport is an http.RoundTripper that records API interactions into a cassette file, or replays them
+// offline for deterministic tests.
+//
+// Recorded interactions are scrubbed before they are kept: the Authorization header, client secrets, access tokens
+// and organization identifiers never reach the cassette file. Replayed requests are matched on method, path, query
+// and JSON body, ignoring the key order and whitespace of the body. Each recorded interaction is served once, in
+// the order it was recorded, so that repeated requests can return different responses.
+type CassetteTransport struct {
+	// Transport sends the requests in record mode and defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mode     CassetteMode
+	path     string
+	mu       sync.Mutex
+	cassette *Cassette
+	played   []bool
+}
+
+// NewCassetteTransport creates a CassetteTransport for the cassette file at path. Replay mode loads the file, record
+// mode starts an empty cassette that is written by Save.
+func NewCassetteTransport(path string, mode CassetteMode) (*CassetteTransport, error) {
+	t := &CassetteTransport{
+		mode:     mode,
+		path:     path,
+		cassette: &Cassette{},
+	}
+
+	switch mode {
+	case CassetteModeRecord:
+	case CassetteModeReplay:
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, t.cassette); err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", path, err)
+		}
+
+		t.played = make([]bool, len(t.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCassetteMode, mode)
+	}
+
+	return t, nil
+}
+
+// Mode returns the mode of the transport.
+func (t *CassetteTransport) Mode() CassetteMode {
+	return t.mode
+}
+
+// Client returns a new http.Client instance using the transport.
+func (t *CassetteTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := CassetteRequest{
+		Method: req.Method,
+		URL:    scrubURL(req.URL),
+		Header: scrubHeader(req.Header),
+		Body:   scrubBody(body, req.Header.Get("Content-Type")),
+	}
+
+	if t.mode == CassetteModeReplay {
+		return t.replay(req, recorded)
+	}
+
+	return t.record(req, recorded)
+}
+
+func (t *CassetteTransport) replay(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.cassette.Interactions {
+		if t.played[i] || !interaction.Request.matches(recorded) {
+			continue
+		}
+
+		t.played[i] = true
+
+		return interaction.Response.httpResponse(req), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrCassetteNoMatch, recorded.Method, recorded.URL)
+}
+
+func (t *CassetteTransport) record(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, &CassetteInteraction{
+		Request: recorded,
+		Response: CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       scrubBody(body, resp.Header.Get("Content-Type")),
+		},
+	})
+	t.mu.Unlock()
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (t *CassetteTransport) Save() error {
+	if t.mode != CassetteModeRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	raw, err := json.MarshalIndent(t.cassette, "", "  ")
+	t.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	raw = append(raw, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(t.path), cacheDirPerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(t.path, raw, cacheFilePerm)
+}
+
+// Unplayed returns the number of recorded interactions that were not replayed yet.
+func (t *CassetteTransport) Unplayed() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+
+	for _, played := range t.played {
+		if !played {
+			count++
+		}
+	}
+
+	return count
+}
+
+// matches compares the method, path, query and normalized JSON body of two requests.
+func (r CassetteRequest) matches(other CassetteRequest) bool {
+	if r.Method != other.Method || scrubBody([]byte(r.Body), "") != scrubBody([]byte(other.Body), "") {
+		return false
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return false
+	}
+
+	o, err := url.Parse(other.URL)
+	if err != nil {
+		return false
+	}
+
+	return u.Path == o.Path && u.Query().Encode() == o.Query().Encode()
+}
+
+func (r CassetteResponse) httpResponse(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody reads the body of req and restores it for the next reader.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// scrubbedKeys returns the secret query, form and JSON keys together with whether they hold an organization ID.
+func scrubbedKeys() map[string]bool {
+	return map[string]bool{
+		"client_secret": false,
+		"access_token":  false,
+		"orgId":         true,
+		"parentOrgId":   true,
+	}
+}
+
+func scrubHeader(header http.Header) http.Header {
+	scrubbed := header.Clone()
+
+	if scrubbed.Get("Authorization") != "" {
+		scrubbed.Set("Authorization", cassetteRedacted)
+	}
+
+	if scrubbed.Get("X-AP-Context") != "" {
+		scrubbed.Set("X-AP-Context", "orgId="+cassetteRedacted)
+	}
+
+	return scrubbed
+}
+
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	scrubbed.RawQuery = scrubValues(u.Query()).Encode()
+
+	return scrubbed.String()
+}
+
+func scrubValues(values url.Values) url.Values {
+	for key := range scrubbedKeys() {
+		if _, ok := values[key]; ok {
+			values.Set(key, cassetteRedacted)
+		}
+	}
+
+	return values
+}
+
+// scrubBody removes secrets from a JSON or form body. JSON bodies are normalized so that they can be compared.
+func scrubBody(body []byte, contentType string) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil {
+		if normalized, err := json.Marshal(scrubJSON(value)); err == nil {
+			return string(normalized)
+		}
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return scrubValues(values).Encode()
+		}
+	}
+
+	return string(body)
+}
+
+func scrubJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := scrubbedKeys()
+
+		for key, item := range v {
+			if isOrgID, ok := keys[key]; ok {
+				if isOrgID {
+					v[key] = scrubbedOrgID
+				} else {
+					v[key] = cassetteRedacted
+				}
+
+				continue
+			}
+
+			v[key] = scrubJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubJSON(item)
+		}
+	}
+
+	return value
+}
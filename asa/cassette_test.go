@@ -0,0 +1,168 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), `"limit":1`) {
+			fmt.Fprintln(w, `{"data":[{"id":1,"orgId":123456,"name":"Brand"}]}`)
+
+			return
+		}
+
+		fmt.Fprintln(w, `{"data":[{"orgId":123456,"parentOrgId":654321,"orgName":"Org"}]}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "record.json")
+
+	recorder, err := NewCassetteTransport(path, CassetteModeRecord)
+	assert.NoError(t, err)
+
+	recordClient, err := NewClientWithOptions(WithHTTPClient(&http.Client{Transport: headerTransport{recorder}}), WithBaseURL(server.URL))
+	assert.NoError(t, err)
+
+	_, _, err = recordClient.Campaigns.FindCampaigns(context.Background(), &Selector{Pagination: &Pagination{Limit: 1}})
+	assert.NoError(t, err)
+
+	_, _, err = recordClient.AccessControlList.GetUserACL(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Save())
+
+	raw, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret-token")
+	assert.NotContains(t, string(raw), "123456")
+	assert.NotContains(t, string(raw), "654321")
+	assert.Contains(t, string(raw), cassetteRedacted)
+	assert.True(t, strings.HasSuffix(string(raw), "}\n"), "cassettes end with a newline")
+
+	server.Close()
+
+	player, err := NewCassetteTransport(path, CassetteModeReplay)
+	assert.NoError(t, err)
+
+	replayClient, err := NewClientWithOptions(WithHTTPClient(player.Client()), WithBaseURL(server.URL))
+	assert.NoError(t, err)
+
+	acls, _, err := replayClient.AccessControlList.GetUserACL(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Org", acls.UserAcls[0].OrgName)
+	assert.Equal(t, int64(scrubbedOrgID), acls.UserAcls[0].OrgID)
+
+	campaigns, _, err := replayClient.Campaigns.FindCampaigns(context.Background(), &Selector{Pagination: &Pagination{Limit: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Brand", campaigns.Campaigns[0].Name)
+	assert.Equal(t, 0, player.Unplayed())
+
+	_, _, err = replayClient.AccessControlList.GetUserACL(context.Background())
+	assert.ErrorIs(t, err, ErrCassetteNoMatch)
+}
+
+func TestCassetteRequestMatches(t *testing.T) {
+	t.Parallel()
+
+	recorded := CassetteRequest{Method: http.MethodPost, URL: "https://api.example.com/api/v4/campaigns/find?b=2&a=1", Body: `{"b":2,"a":1}`}
+
+	assert.True(t, recorded.matches(CassetteRequest{Method: http.MethodPost, URL: "http://127.0.0.1/api/v4/campaigns/find?a=1&b=2", Body: `{ "a": 1, "b": 2 }`}))
+	assert.False(t, recorded.matches(CassetteRequest{Method: http.MethodPost, URL: "http://127.0.0.1/api/v4/campaigns/find?a=1", Body: `{"a":1,"b":2}`}))
+	assert.False(t, recorded.matches(CassetteRequest{Method: http.MethodPost, URL: "http://127.0.0.1/api/v4/campaigns/find?a=1&b=2", Body: `{"a":1}`}))
+	assert.False(t, recorded.matches(CassetteRequest{Method: http.MethodPut, URL: "http://127.0.0.1/api/v4/campaigns/find?a=1&b=2", Body: `{"a":1,"b":2}`}))
+}
+
+func TestCassetteScrub(t *testing.T) {
+	t.Parallel()
+
+	u, _ := url.Parse("https://appleid.apple.com/auth/oauth2/token?client_id=id&client_secret=jwt")
+	assert.Equal(t, "https://appleid.apple.com/auth/oauth2/token?client_id=id&client_secret=REDACTED", scrubURL(u))
+
+	assert.Equal(t, "client_id=id&client_secret=REDACTED", scrubBody([]byte("client_secret=jwt&client_id=id"), "application/x-www-form-urlencoded"))
+	assert.Equal(t, `{"access_token":"REDACTED","expires_in":3600}`, scrubBody([]byte(`{"expires_in":3600,"access_token":"abc"}`), "application/json"))
+	assert.Equal(t, "plain", scrubBody([]byte("plain"), "text/plain"))
+
+	header := scrubHeader(http.Header{"Authorization": {"Bearer abc"}, "X-Ap-Context": {"orgId=1"}})
+	assert.Equal(t, cassetteRedacted, header.Get("Authorization"))
+	assert.Equal(t, "orgId="+cassetteRedacted, header.Get("X-AP-Context"))
+
+	_, err := NewCassetteTransport("unused.json", CassetteMode("live"))
+	assert.ErrorIs(t, err, ErrUnknownCassetteMode)
+}
+
+// TestIntegrationCassettes checks that the committed cassettes are scrubbed the way CassetteTransport records them
+// and replays the one of TestListCampaigns with the calls of that integration test.
+func TestIntegrationCassettes(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob("../test/integration/testdata/cassettes/*.json")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		player, err := NewCassetteTransport(path, CassetteModeReplay)
+		assert.NoError(t, err, path)
+
+		for _, interaction := range player.cassette.Interactions {
+			u, err := url.Parse(interaction.Request.URL)
+			assert.NoError(t, err, path)
+			assert.Equal(t, scrubURL(u), interaction.Request.URL, path)
+			assert.Equal(t, scrubHeader(interaction.Request.Header), interaction.Request.Header, path)
+			assert.Equal(t, scrubBody([]byte(interaction.Request.Body), ""), interaction.Request.Body, path)
+			assert.Equal(t, scrubBody([]byte(interaction.Response.Body), ""), interaction.Response.Body, path)
+		}
+	}
+
+	player, err := NewCassetteTransport("../test/integration/testdata/cassettes/TestListCampaigns.json", CassetteModeReplay)
+	assert.NoError(t, err)
+
+	client := NewClient(player.Client())
+
+	campaigns, _, err := client.Campaigns.GetAllCampaigns(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, campaigns.Campaigns)
+
+	_, _, err = client.Campaigns.GetCampaign(context.Background(), campaigns.Campaigns[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, player.Unplayed())
+}
+
+type headerTransport struct {
+	next http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-AP-Context", "orgId=123456")
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,226 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EnvironmentProduction is the name of the public Apple Search Ads API.
+const EnvironmentProduction = "production"
+
+// ErrInvalidBaseURL happens when a base URL is not an absolute http or https URL.
+var ErrInvalidBaseURL = errors.New("invalid base URL")
+
+// ErrInvalidClientOption happens when a ClientOption is given an unusable value.
+var ErrInvalidClientOption = errors.New("invalid client option")
+
+// Environment is a named deployment of the Search Ads API, such as production, a corporate egress proxy or a local
+// fake used in tests.
+type Environment struct {
+	Name    string
+	BaseURL string
+}
+
+// ProductionEnvironment returns the environment of the public Apple Search Ads API.
+func ProductionEnvironment() Environment {
+	return Environment{Name: EnvironmentProduction, BaseURL: defaultBaseURL}
+}
+
+// ClientOption configures a Client created with NewClientWithOptions.
+type ClientOption func(c *Client) error
+
+// NewClientWithOptions creates a new Client configured by options. Without options it is equivalent to
+// NewClient(nil).
+func NewClientWithOptions(options ...ClientOption) (*Client, error) {
+	c := NewClient(nil)
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.timeout > 0 {
+		// copy the client so that a client given to WithHTTPClient keeps its own timeout
+		client := *c.client
+		client.Timeout = c.timeout
+		c.client = &client
+	}
+
+	return c, nil
+}
+
+// WithHTTPClient sends the requests of the client through httpClient, typically the client of an AuthTransport.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) error {
+		if httpClient != nil {
+			c.client = httpClient
+		}
+
+		return nil
+	}
+}
+
+// WithBaseURL sends the requests of the client to baseURL instead of the production API. The URL must be an absolute
+// http or https URL, e.g. "https://egress.example.com/searchads/api/v4/".
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		u, err := parseBaseURL(baseURL)
+		if err != nil {
+			return err
+		}
+
+		c.baseURL = u
+		c.environment = ""
+
+		return nil
+	}
+}
+
+// WithEnvironment sends the requests of the client to the base URL of env, and reports its name from
+// Client.Environment.
+func WithEnvironment(env Environment) ClientOption {
+	return func(c *Client) error {
+		if env.Name == "" {
+			return fmt.Errorf("%w: environment without a name", ErrInvalidClientOption)
+		}
+
+		u, err := parseBaseURL(env.BaseURL)
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", env.Name, err)
+		}
+
+		c.baseURL = u
+		c.environment = env.Name
+
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) error {
+		c.UserAgent = userAgent
+
+		return nil
+	}
+}
+
+// WithRetryPolicy retries requests failing with a network error, 429 Too Many Requests or a 5xx status. Clients do
+// not retry by default.
+//
+// Only idempotent requests are retried after network errors and 5xx statuses: GET, PUT and DELETE requests, and the
+// POSTs that only read data, such as find, report and search requests. Other POSTs, such as creates, may have been
+// applied before failing, so they are only retried after 429 Too Many Requests, unless RetryNonIdempotent is set.
+// The IdempotentCreator retries creates safely.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) error {
+		if policy.MaxRetries < 0 {
+			return fmt.Errorf("%w: negative MaxRetries %d", ErrInvalidClientOption, policy.MaxRetries)
+		}
+
+		c.retry = policy
+
+		return nil
+	}
+}
+
+// WithTimeout limits the time of every request, including reading the response body.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return fmt.Errorf("%w: negative timeout %s", ErrInvalidClientOption, timeout)
+		}
+
+		c.timeout = timeout
+
+		return nil
+	}
+}
+
+// WithCache enables the response cache of the client, see SetCache.
+func WithCache(cache *ResponseCache) ClientOption {
+	return func(c *Client) error {
+		c.SetCache(cache)
+
+		return nil
+	}
+}
+
+// WithValidation validates the payload of every mutating request before sending it, see SetValidation.
+func WithValidation(flag bool) ClientOption {
+	return func(c *Client) error {
+		c.SetValidation(flag)
+
+		return nil
+	}
+}
+
+// WithHTTPDebug dumps every request and response, see SetHTTPDebug.
+func WithHTTPDebug(flag bool) ClientOption {
+	return func(c *Client) error {
+		c.SetHTTPDebug(flag)
+
+		return nil
+	}
+}
+
+// BaseURL returns a copy of the URL the client resolves request paths against.
+func (c *Client) BaseURL() *url.URL {
+	u := *c.baseURL
+
+	return &u
+}
+
+// Environment returns the name of the environment the client talks to, or an empty string when it was given a bare
+// base URL.
+func (c *Client) Environment() string {
+	return c.environment
+}
+
+// parseBaseURL validates a base URL and makes sure its path ends with a slash, so that request paths are resolved
+// below it rather than replacing its last segment.
+func parseBaseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBaseURL, err.Error())
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q must use http or https", ErrInvalidBaseURL, raw)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: %q has no host", ErrInvalidBaseURL, raw)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("%w: %q must not have a query or fragment", ErrInvalidBaseURL, raw)
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return u, nil
+}
@@ -0,0 +1,215 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientWithOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClientWithOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultBaseURL, client.BaseURL().String())
+	assert.Equal(t, EnvironmentProduction, client.Environment())
+	assert.Equal(t, userAgent, client.UserAgent)
+	assert.NotNil(t, client.Campaigns)
+}
+
+func TestWithBaseURL(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClientWithOptions(WithBaseURL("https://egress.example.com/searchads/api/v4"))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://egress.example.com/searchads/api/v4/", client.BaseURL().String())
+	assert.Equal(t, "", client.Environment())
+
+	req, err := client.newRequest(context.Background(), http.MethodGet, "campaigns", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://egress.example.com/searchads/api/v4/campaigns", req.URL.String())
+
+	for _, raw := range []string{"", "api.searchads.apple.com/api/v4/", "ftp://example.com/", "https:///v4/", "https://example.com/v4/?debug=1"} {
+		_, err := NewClientWithOptions(WithBaseURL(raw))
+		assert.ErrorIs(t, err, ErrInvalidBaseURL, raw)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClientWithOptions(WithEnvironment(Environment{Name: "fake", BaseURL: "http://localhost:8080/api/v4/"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "fake", client.Environment())
+	assert.Equal(t, "http://localhost:8080/api/v4/", client.BaseURL().String())
+
+	_, err = NewClientWithOptions(WithEnvironment(Environment{BaseURL: defaultBaseURL}))
+	assert.ErrorIs(t, err, ErrInvalidClientOption)
+
+	_, err = NewClientWithOptions(WithEnvironment(Environment{Name: "broken", BaseURL: "localhost"}))
+	assert.ErrorIs(t, err, ErrInvalidBaseURL)
+}
+
+func TestWithTimeoutKeepsGivenClient(t *testing.T) {
+	t.Parallel()
+
+	httpClient := &http.Client{}
+
+	client, err := NewClientWithOptions(WithTimeout(time.Second), WithHTTPClient(httpClient), WithUserAgent("agent"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, client.client.Timeout)
+	assert.Equal(t, time.Duration(0), httpClient.Timeout)
+	assert.Equal(t, "agent", client.UserAgent)
+
+	_, err = NewClientWithOptions(WithTimeout(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidClientOption)
+
+	_, err = NewClientWithOptions(WithRetryPolicy(RetryPolicy{MaxRetries: -1}))
+	assert.ErrorIs(t, err, ErrInvalidClientOption)
+}
+
+func newFlakyServer(failures int, status int) (*httptest.Server, func() []string) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+
+		if attempt <= failures {
+			w.WriteHeader(status)
+			fmt.Fprintln(w, `{"error":{"errors":[{"messageCode":"SERVER_ERROR","message":"try again"}]}}`)
+
+			return
+		}
+
+		fmt.Fprintln(w, `{"data":{"id":1,"name":"Campaign"}}`)
+	}))
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), bodies...)
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	policy := RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithRetryPolicy(policy))
+	assert.NoError(t, err)
+
+	campaign, _, err := client.Campaigns.UpdateCampaign(context.Background(), 1, &UpdateCampaignRequest{Campaign: &CampaignUpdate{Name: "Campaign"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), campaign.Campaign.ID)
+
+	sent := bodies()
+	assert.Len(t, sent, 3)
+	assert.Equal(t, sent[0], sent[2], "the body must be sent again on every retry")
+	assert.Contains(t, sent[2], `"name":"Campaign"`)
+}
+
+func TestRetryPolicyNonIdempotent(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+
+	for name, test := range map[string]struct {
+		status             int
+		retryNonIdempotent bool
+		attempts           int
+	}{
+		"server error":          {status: http.StatusServiceUnavailable, attempts: 1},
+		"too many requests":     {status: http.StatusTooManyRequests, attempts: 3},
+		"opted in server error": {status: http.StatusServiceUnavailable, retryNonIdempotent: true, attempts: 3},
+	} {
+		server, bodies := newFlakyServer(2, test.status)
+
+		policy := policy
+		policy.RetryNonIdempotent = test.retryNonIdempotent
+
+		client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithRetryPolicy(policy))
+		assert.NoError(t, err)
+
+		_, _, err = client.Campaigns.CreateCampaign(context.Background(), &Campaign{Name: "Campaign"})
+		assert.Equal(t, test.attempts == 1, err != nil, name)
+		assert.Len(t, bodies(), test.attempts, name)
+
+		server.Close()
+	}
+
+	server, bodies := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithRetryPolicy(policy))
+	assert.NoError(t, err)
+
+	_, resp, _ := client.Campaigns.FindCampaigns(context.Background(), &Selector{})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, bodies(), 3, "find requests only read data and are retried")
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newFlakyServer(3, http.StatusTooManyRequests)
+	defer server.Close()
+
+	policy := RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithRetryPolicy(policy))
+	assert.NoError(t, err)
+
+	_, resp, err := client.Campaigns.GetCampaign(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Len(t, bodies(), 2)
+}
+
+func TestNoRetriesByDefault(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := newFlakyServer(1, http.StatusInternalServerError)
+	defer server.Close()
+
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+	assert.NoError(t, err)
+
+	_, resp, err := client.Campaigns.GetCampaign(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Len(t, bodies(), 1)
+}
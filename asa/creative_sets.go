@@ -109,7 +109,7 @@ type MediaAppPreviewOrScreenshots struct {
 func (s *CreativeSetsService) GetCreativeAppAssets(ctx context.Context, adamID int64, params *MediaCreativeSetRequest) (*MediaCreativeSetDetailResponse, *Response, error) {
 	url := fmt.Sprintf("creativeappassets/%d", adamID)
 	res := new(MediaCreativeSetDetailResponse)
-	resp, err := s.client.post(ctx, url, *params, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "GetCreativeAppAssets"}, url, *params, res)
 
 	return res, resp, err
 }
@@ -127,7 +127,7 @@ type AppPreviewDevicesMappingResponse struct {
 func (s *CreativeSetsService) GetAppPreviewDeviceSizes(ctx context.Context) (*AppPreviewDevicesMappingResponse, *Response, error) {
 	url := "creativeappassets/devices"
 	res := new(AppPreviewDevicesMappingResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"CreativeSets", "GetAppPreviewDeviceSizes"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -193,7 +193,7 @@ const (
 func (s *CreativeSetsService) CreateAdGroupCreativeSets(ctx context.Context, campaignID int64, adgroupID int64, body *CreateAdGroupCreativeSetRequest) (*AdGroupCreativeSetResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/adgroupcreativesets/creativesets", campaignID, adgroupID)
 	res := new(AdGroupCreativeSetResponse)
-	resp, err := s.client.post(ctx, url, body, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "CreateAdGroupCreativeSets"}, url, body, res)
 
 	return res, resp, err
 }
@@ -220,7 +220,7 @@ type AdGroupCreativeSetListResponse struct {
 func (s *CreativeSetsService) FindAdGroupCreativeSets(ctx context.Context, campaignID int64, body *FindAdGroupCreativeSetRequest) (*AdGroupCreativeSetListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroupcreativesets/find", campaignID)
 	res := new(AdGroupCreativeSetListResponse)
-	resp, err := s.client.post(ctx, url, body, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "FindAdGroupCreativeSets"}, url, body, res)
 
 	return res, resp, err
 }
@@ -238,7 +238,7 @@ type AdGroupCreativeSetUpdate struct {
 func (s *CreativeSetsService) UpdateAdGroupCreativeSets(ctx context.Context, campaignID int64, adgroupID int64, adGroupCreativeSetID int64, body *AdGroupCreativeSetUpdate) (*AdGroupCreativeSetResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/adgroupcreativesets/%d", campaignID, adgroupID, adGroupCreativeSetID)
 	res := new(AdGroupCreativeSetResponse)
-	resp, err := s.client.put(ctx, url, body, res)
+	resp, err := s.client.put(ctx, operationName{"CreativeSets", "UpdateAdGroupCreativeSets"}, url, body, res)
 
 	return res, resp, err
 }
@@ -249,7 +249,7 @@ func (s *CreativeSetsService) UpdateAdGroupCreativeSets(ctx context.Context, cam
 func (s *CreativeSetsService) DeleteAdGroupCreativeSets(ctx context.Context, campaignID int64, adgroupID int64, adGroupCreativeSetIDs []int64) (*IntegerResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/adgroupcreativesets/delete/bulk", campaignID, adgroupID)
 	res := new(IntegerResponse)
-	resp, err := s.client.post(ctx, url, adGroupCreativeSetIDs, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "DeleteAdGroupCreativeSets"}, url, adGroupCreativeSetIDs, res)
 
 	return res, resp, err
 }
@@ -324,7 +324,7 @@ type GetCreativeSetVariationQuery struct {
 func (s *CreativeSetsService) GetCreativeSetVariation(ctx context.Context, creativeSetID int64, params *GetCreativeSetVariationQuery) (*CreativeSetResponse, *Response, error) {
 	url := fmt.Sprintf("creativesets/%d", creativeSetID)
 	res := new(CreativeSetResponse)
-	resp, err := s.client.get(ctx, url, params, res)
+	resp, err := s.client.get(ctx, operationName{"CreativeSets", "GetCreativeSetVariation"}, url, params, res)
 
 	return res, resp, err
 }
@@ -352,7 +352,7 @@ type CreativeSetListResponse struct {
 func (s *CreativeSetsService) FindCreativeSets(ctx context.Context, params *FindCreativeSetRequest) (*CreativeSetListResponse, *Response, error) {
 	url := "creativesets/find"
 	res := new(CreativeSetListResponse)
-	resp, err := s.client.post(ctx, url, params, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "FindCreativeSets"}, url, params, res)
 
 	return res, resp, err
 }
@@ -370,7 +370,7 @@ type AssignAdGroupCreativeSetRequest struct {
 func (s *CreativeSetsService) AssignCreativeSetsToAdGroup(ctx context.Context, campaignID int64, adgroupID int64, request *AssignAdGroupCreativeSetRequest) (*AdGroupCreativeSetResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/adgroupcreativesets", campaignID, adgroupID)
 	res := new(AdGroupCreativeSetResponse)
-	resp, err := s.client.post(ctx, url, request, res)
+	resp, err := s.client.post(ctx, operationName{"CreativeSets", "AssignCreativeSetsToAdGroup"}, url, request, res)
 
 	return res, resp, err
 }
@@ -388,7 +388,27 @@ type CreativeSetUpdate struct {
 func (s *CreativeSetsService) UpdateCreativeSets(ctx context.Context, creativeSetID int64, request *CreativeSetUpdate) (*CreativeSetResponse, *Response, error) {
 	url := fmt.Sprintf("creativesets/%d", creativeSetID)
 	res := new(CreativeSetResponse)
-	resp, err := s.client.put(ctx, url, request, res)
+	resp, err := s.client.put(ctx, operationName{"CreativeSets", "UpdateCreativeSets"}, url, request, res)
 
 	return res, resp, err
 }
+
+// findAllAdGroupCreativeSets pages through FindAdGroupCreativeSets and returns every ad group Creative Set of a campaign.
+func (s *CreativeSetsService) findAllAdGroupCreativeSets(ctx context.Context, campaignID int64, selector *Selector) ([]*AdGroupCreativeSet, error) {
+	var creativeSets []*AdGroupCreativeSet
+
+	for offset := 0; ; {
+		res, _, err := s.FindAdGroupCreativeSets(ctx, campaignID, &FindAdGroupCreativeSetRequest{Selector: pagedSelector(selector, offset)})
+		if err != nil {
+			return nil, err
+		}
+
+		creativeSets = append(creativeSets, res.AdGroupCreativeSets...)
+
+		if !hasNextPage(res.Pagination, offset, len(res.AdGroupCreativeSets)) {
+			return creativeSets, nil
+		}
+
+		offset += len(res.AdGroupCreativeSets)
+	}
+}
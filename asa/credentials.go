@@ -0,0 +1,479 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultTokenExpiry  = 20 * time.Minute
+	defaultEnvPrefix    = "ASA_"
+	defaultProfile      = "default"
+	envProfile          = "ASA_PROFILE"
+	defaultProfileDir   = ".asa"
+	defaultProfileFile  = "credentials.yaml"
+	jsonProfileFileType = ".json"
+)
+
+// ErrMissingCredentials happens when the credentials lack a value required to authenticate.
+var ErrMissingCredentials = errors.New("missing credentials")
+
+// ErrProfileNotFound happens when a profile file has no profile with the requested name.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Credentials identify an API user of an organization.
+//
+// The client secret is either signed from KeyID and TeamID with Signer, PrivateKey or the key file at
+// PrivateKeyPath, in that order, or given precomputed as ClientSecret.
+type Credentials struct {
+	OrgID          string `json:"org_id,omitempty" yaml:"org_id,omitempty"`
+	KeyID          string `json:"key_id,omitempty" yaml:"key_id,omitempty"`
+	TeamID         string `json:"team_id,omitempty" yaml:"team_id,omitempty"`
+	ClientID       string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	PrivateKey     string `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	PrivateKeyPath string `json:"private_key_path,omitempty" yaml:"private_key_path,omitempty"`
+	ClientSecret   string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+
+	// PrivateKeyPassphrase decrypts an encrypted PrivateKey or key file.
+	PrivateKeyPassphrase string `json:"private_key_passphrase,omitempty" yaml:"private_key_passphrase,omitempty"`
+
+	// Signer signs the client secret without exposing the private key, e.g. a key held in a KMS or an HSM. It must
+	// hold a P-256 ECDSA key.
+	Signer crypto.Signer `json:"-" yaml:"-"`
+}
+
+// merge overrides the values of c with the non-empty values of other.
+func (c *Credentials) merge(other *Credentials) {
+	override := func(value *string, with string) {
+		if with != "" {
+			*value = with
+		}
+	}
+
+	override(&c.OrgID, other.OrgID)
+	override(&c.KeyID, other.KeyID)
+	override(&c.TeamID, other.TeamID)
+	override(&c.ClientID, other.ClientID)
+	override(&c.PrivateKey, other.PrivateKey)
+	override(&c.PrivateKeyPath, other.PrivateKeyPath)
+	override(&c.ClientSecret, other.ClientSecret)
+	override(&c.PrivateKeyPassphrase, other.PrivateKeyPassphrase)
+
+	if other.Signer != nil {
+		c.Signer = other.Signer
+	}
+}
+
+// signer returns the signer of the client secret.
+func (c *Credentials) signer() (crypto.Signer, error) {
+	var blob []byte
+
+	switch {
+	case c.Signer != nil:
+		if err := checkCurve(c.Signer.Public()); err != nil {
+			return nil, err
+		}
+
+		return c.Signer, nil
+	case c.PrivateKey != "":
+		blob = []byte(c.PrivateKey)
+	case c.PrivateKeyPath != "":
+		var err error
+		if blob, err = ioutil.ReadFile(c.PrivateKeyPath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: private key", ErrMissingCredentials)
+	}
+
+	key, err := parsePrivateKey(blob, []byte(c.PrivateKeyPassphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// jwtGenerator returns the generator of the client secret and access token.
+func (c *Credentials) jwtGenerator(expireDuration time.Duration) (*standardJWTGenerator, error) {
+	if c.ClientID == "" {
+		return nil, fmt.Errorf("%w: client ID", ErrMissingCredentials)
+	}
+
+	gen := &standardJWTGenerator{
+		keyID:          c.KeyID,
+		issuerID:       c.TeamID,
+		clientID:       c.ClientID,
+		expireDuration: expireDuration,
+		clientSecret:   c.ClientSecret,
+	}
+
+	if c.ClientSecret != "" {
+		return gen, nil
+	}
+
+	if c.KeyID == "" {
+		return nil, fmt.Errorf("%w: key ID", ErrMissingCredentials)
+	}
+
+	if c.TeamID == "" {
+		return nil, fmt.Errorf("%w: team ID", ErrMissingCredentials)
+	}
+
+	signer, err := c.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	gen.signer = signer
+
+	return gen, nil
+}
+
+// CredentialsProvider loads the Credentials used by an AuthTransport.
+type CredentialsProvider interface {
+	Credentials() (*Credentials, error)
+}
+
+type staticCredentialsProvider struct {
+	credentials Credentials
+}
+
+// StaticCredentials returns a CredentialsProvider that always provides credentials.
+func StaticCredentials(credentials Credentials) CredentialsProvider {
+	return &staticCredentialsProvider{credentials: credentials}
+}
+
+func (p *staticCredentialsProvider) Credentials() (*Credentials, error) {
+	credentials := p.credentials
+
+	return &credentials, nil
+}
+
+// EnvCredentialsProvider loads credentials from the environment variables ORG_ID, KEY_ID, TEAM_ID, CLIENT_ID,
+// PRIVATE_KEY, PRIVATE_KEY_PATH, PRIVATE_KEY_PASSPHRASE and CLIENT_SECRET, each starting with Prefix.
+type EnvCredentialsProvider struct {
+	Prefix string
+}
+
+// NewEnvCredentialsProvider creates an EnvCredentialsProvider reading the variables prefixed with ASA_, e.g.
+// ASA_CLIENT_ID.
+func NewEnvCredentialsProvider() *EnvCredentialsProvider {
+	return &EnvCredentialsProvider{Prefix: defaultEnvPrefix}
+}
+
+// Credentials reads the credentials from the environment.
+func (p *EnvCredentialsProvider) Credentials() (*Credentials, error) {
+	env := func(name string) string {
+		return os.Getenv(p.Prefix + name)
+	}
+
+	return &Credentials{
+		OrgID:          env("ORG_ID"),
+		KeyID:          env("KEY_ID"),
+		TeamID:         env("TEAM_ID"),
+		ClientID:       env("CLIENT_ID"),
+		PrivateKey:     env("PRIVATE_KEY"),
+		PrivateKeyPath: env("PRIVATE_KEY_PATH"),
+		ClientSecret:   env("CLIENT_SECRET"),
+
+		PrivateKeyPassphrase: env("PRIVATE_KEY_PASSPHRASE"),
+	}, nil
+}
+
+// ProfileCredentialsProvider loads named credentials from a YAML or JSON profile file, where each top-level key is
+// the name of a profile:
+//
+//	default:
+//	  org_id: "1234567"
+//	  key_id: ...
+//	  team_id: SEARCHADS.xxxx
+//	  client_id: SEARCHADS.xxxx
+//	  private_key_path: keys/private-key.pem
+//	staging:
+//	  ...
+//
+// Files ending with .json are read as JSON, all others as YAML. A relative private_key_path is resolved from the
+// directory of the profile file.
+type ProfileCredentialsProvider struct {
+	Path    string
+	Profile string
+}
+
+// NewProfileCredentialsProvider creates a ProfileCredentialsProvider. An empty path means DefaultProfilePath, and an
+// empty profile means the ASA_PROFILE environment variable or else the "default" profile.
+func NewProfileCredentialsProvider(path string, profile string) *ProfileCredentialsProvider {
+	if profile == "" {
+		profile = os.Getenv(envProfile)
+	}
+
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	return &ProfileCredentialsProvider{Path: path, Profile: profile}
+}
+
+// DefaultProfilePath returns the path of the profile file in the home directory of the user, ~/.asa/credentials.yaml.
+func DefaultProfilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, defaultProfileDir, defaultProfileFile), nil
+}
+
+// Credentials reads the profile from the profile file.
+func (p *ProfileCredentialsProvider) Credentials() (*Credentials, error) {
+	path := p.Path
+	if path == "" {
+		var err error
+		if path, err = DefaultProfilePath(); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*Credentials)
+
+	if strings.EqualFold(filepath.Ext(path), jsonProfileFileType) {
+		err = json.Unmarshal(raw, &profiles)
+	} else {
+		err = yaml.Unmarshal(raw, &profiles)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("profile file %s: %w", path, err)
+	}
+
+	credentials, ok := profiles[p.Profile]
+	if !ok || credentials == nil {
+		return nil, fmt.Errorf("%w: %q in %s", ErrProfileNotFound, p.Profile, path)
+	}
+
+	if credentials.PrivateKeyPath != "" && !filepath.IsAbs(credentials.PrivateKeyPath) {
+		credentials.PrivateKeyPath = filepath.Join(filepath.Dir(path), credentials.PrivateKeyPath)
+	}
+
+	return credentials, nil
+}
+
+// AuthOption configures an AuthTransport created by NewAuthTransport.
+type AuthOption func(*authOptions)
+
+type authOptions struct {
+	provider       CredentialsProvider
+	credentials    Credentials
+	expireDuration time.Duration
+	transport      http.RoundTripper
+	tokenClient    *http.Client
+	tokenURL       string
+	tokenRetry     RetryPolicy
+	tokenStore     TokenStore
+}
+
+// WithCredentialsProvider loads the credentials from provider. Values given with the other options override the
+// loaded ones.
+func WithCredentialsProvider(provider CredentialsProvider) AuthOption {
+	return func(o *authOptions) {
+		o.provider = provider
+	}
+}
+
+// WithOrgID sets the organization the requests are sent for.
+func WithOrgID(orgID string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.OrgID = orgID
+	}
+}
+
+// WithKeyID sets the identifier of the public key uploaded to Apple Search Ads.
+func WithKeyID(keyID string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.KeyID = keyID
+	}
+}
+
+// WithTeamID sets the team identifier of the API user.
+func WithTeamID(teamID string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.TeamID = teamID
+	}
+}
+
+// WithClientID sets the client identifier of the API user.
+func WithClientID(clientID string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.ClientID = clientID
+	}
+}
+
+// WithPrivateKey sets the PEM encoded private key signing the client secret.
+func WithPrivateKey(privateKey []byte) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.PrivateKey = string(privateKey)
+	}
+}
+
+// WithPrivateKeyFile sets the path of the PEM encoded private key signing the client secret.
+func WithPrivateKeyFile(path string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.PrivateKeyPath = path
+	}
+}
+
+// WithPrivateKeyPassphrase sets the passphrase decrypting an encrypted private key.
+func WithPrivateKeyPassphrase(passphrase []byte) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.PrivateKeyPassphrase = string(passphrase)
+	}
+}
+
+// WithSigner signs the client secret with signer, e.g. a key held in a KMS or an HSM.
+func WithSigner(signer crypto.Signer) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.Signer = signer
+	}
+}
+
+// WithClientSecret uses a precomputed client secret JWT instead of signing one.
+func WithClientSecret(clientSecret string) AuthOption {
+	return func(o *authOptions) {
+		o.credentials.ClientSecret = clientSecret
+	}
+}
+
+// WithTokenExpiry sets the lifetime of the signed client secret. It defaults to 20 minutes.
+func WithTokenExpiry(expireDuration time.Duration) AuthOption {
+	return func(o *authOptions) {
+		o.expireDuration = expireDuration
+	}
+}
+
+// WithRoundTripper sets the http.RoundTripper sending the authenticated requests.
+func WithRoundTripper(transport http.RoundTripper) AuthOption {
+	return func(o *authOptions) {
+		o.transport = transport
+	}
+}
+
+// WithTokenHTTPClient sets the http.Client exchanging the client secret for access tokens. It defaults to a client
+// sharing the http.RoundTripper of the transport, so that proxy and TLS settings apply to both.
+func WithTokenHTTPClient(client *http.Client) AuthOption {
+	return func(o *authOptions) {
+		o.tokenClient = client
+	}
+}
+
+// WithTokenURL sets the URL of the OAuth token endpoint.
+func WithTokenURL(tokenURL string) AuthOption {
+	return func(o *authOptions) {
+		o.tokenURL = tokenURL
+	}
+}
+
+// WithTokenRetryPolicy sets how token requests failing with network errors, throttling or server errors are
+// retried. It defaults to DefaultRetryPolicy.
+func WithTokenRetryPolicy(policy RetryPolicy) AuthOption {
+	return func(o *authOptions) {
+		o.tokenRetry = policy
+	}
+}
+
+// WithTokenStore shares the access token through store, e.g. a FileTokenStore or a KeyValueTokenStore, so that
+// processes using the same client ID reuse it until it expires instead of each requesting their own.
+func WithTokenStore(store TokenStore) AuthOption {
+	return func(o *authOptions) {
+		o.tokenStore = store
+	}
+}
+
+// NewAuthTransport returns a new AuthTransport configured with options.
+//
+//	auth, err := asa.NewAuthTransport(
+//		asa.WithCredentialsProvider(asa.NewProfileCredentialsProvider("", "production")),
+//	)
+func NewAuthTransport(options ...AuthOption) (*AuthTransport, error) {
+	o := &authOptions{
+		expireDuration: defaultTokenExpiry,
+		tokenURL:       defaultAuthURL,
+		tokenRetry:     DefaultRetryPolicy(),
+	}
+
+	for _, option := range options {
+		option(o)
+	}
+
+	credentials := &Credentials{}
+
+	if o.provider != nil {
+		loaded, err := o.provider.Credentials()
+		if err != nil {
+			return nil, err
+		}
+
+		credentials = loaded
+	}
+
+	credentials.merge(&o.credentials)
+
+	gen, err := credentials.jwtGenerator(o.expireDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := o.transport
+	if transport == nil {
+		transport = newTransport()
+	}
+
+	tokenClient := o.tokenClient
+	if tokenClient == nil {
+		tokenClient = &http.Client{Transport: transport, Timeout: defaultTimeout}
+	}
+
+	gen.client = &authClient{
+		client:  tokenClient,
+		baseURL: o.tokenURL,
+		retry:   o.tokenRetry,
+	}
+	gen.store = o.tokenStore
+	gen.storeKey = tokenStoreKey(credentials.ClientID)
+
+	return &AuthTransport{
+		Transport:    transport,
+		jwtGenerator: gen,
+		orgID:        credentials.OrgID,
+	}, nil
+}
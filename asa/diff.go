@@ -0,0 +1,198 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DiffCampaign returns the minimal CampaignUpdate turning current into desired, or nil when the updatable fields of
+// both campaigns match. Fields left empty in desired are treated as unchanged.
+func DiffCampaign(current, desired *Campaign) *CampaignUpdate {
+	update := &CampaignUpdate{}
+	changed := false
+
+	if moneyChanged(current.BudgetAmount, desired.BudgetAmount) {
+		update.BudgetAmount, changed = copyMoney(desired.BudgetAmount), true
+	}
+
+	if moneyChanged(current.DailyBudgetAmount, desired.DailyBudgetAmount) {
+		update.DailyBudgetAmount, changed = copyMoney(desired.DailyBudgetAmount), true
+	}
+
+	if len(desired.BudgetOrders) > 0 && !sameInt64s(current.BudgetOrders, desired.BudgetOrders) {
+		update.BudgetOrders, changed = append([]int64(nil), desired.BudgetOrders...), true
+	}
+
+	if len(desired.CountriesOrRegions) > 0 && !sameStrings(current.CountriesOrRegions, desired.CountriesOrRegions) {
+		update.CountriesOrRegions, changed = append([]string(nil), desired.CountriesOrRegions...), true
+	}
+
+	if desired.LocInvoiceDetails != nil && (current.LocInvoiceDetails == nil || *current.LocInvoiceDetails != *desired.LocInvoiceDetails) {
+		details := *desired.LocInvoiceDetails
+		update.LOCInvoiceDetails, changed = &details, true
+	}
+
+	if desired.Name != "" && desired.Name != current.Name {
+		update.Name, changed = desired.Name, true
+	}
+
+	if desired.Status != "" && desired.Status != current.Status {
+		status := desired.Status
+		update.Status, changed = &status, true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return update
+}
+
+// DiffAdGroup returns the minimal AdGroupUpdateRequest turning current into desired, or nil when the updatable fields
+// of both ad groups match. Fields left empty in desired are treated as unchanged, except AutomatedKeywordsOptIn which
+// is always compared.
+func DiffAdGroup(current, desired *AdGroup) *AdGroupUpdateRequest {
+	update := &AdGroupUpdateRequest{}
+	changed := false
+
+	if desired.AutomatedKeywordsOptIn != current.AutomatedKeywordsOptIn {
+		update.AutomatedKeywordsOptIn, changed = Bool(desired.AutomatedKeywordsOptIn), true
+	}
+
+	if moneyChanged(current.CpaGoal, desired.CpaGoal) {
+		update.CpaGoal, changed = copyMoney(desired.CpaGoal), true
+	}
+
+	if moneyChanged(current.DefaultBidAmount, desired.DefaultBidAmount) {
+		update.DefaultBidAmount, changed = copyMoney(desired.DefaultBidAmount), true
+	}
+
+	if !desired.StartTime.IsZero() && !desired.StartTime.Equal(current.StartTime.Time) {
+		update.StartTime, changed = &DateTime{desired.StartTime.Time}, true
+	}
+
+	if !desired.EndTime.IsZero() && !desired.EndTime.Equal(current.EndTime.Time) {
+		update.EndTime, changed = &DateTime{desired.EndTime.Time}, true
+	}
+
+	if desired.Name != "" && desired.Name != current.Name {
+		update.Name, changed = desired.Name, true
+	}
+
+	if desired.Status != "" && desired.Status != current.Status {
+		update.Status, changed = desired.Status, true
+	}
+
+	if desired.TargetDimensions != nil && !reflect.DeepEqual(current.TargetDimensions, desired.TargetDimensions) {
+		update.TargetingDimensions, changed = desired.TargetDimensions, true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return update
+}
+
+// DiffKeyword returns the minimal KeywordUpdateRequest turning current into desired, or nil when the updatable fields
+// of both keywords match. The update identifies the keyword by the ID and ad group of current.
+func DiffKeyword(current, desired *Keyword) *KeywordUpdateRequest {
+	update := &KeywordUpdateRequest{ID: current.ID, AdGroupID: current.AdGroupID}
+	changed := false
+
+	if desired.BidAmount != (Money{}) && moneyChanged(&current.BidAmount, &desired.BidAmount) {
+		update.BidAmount, changed = copyMoney(&desired.BidAmount), true
+	}
+
+	if desired.Deleted != current.Deleted {
+		update.Deleted, changed = Bool(desired.Deleted), true
+	}
+
+	if desired.MatchType != "" && desired.MatchType != current.MatchType {
+		update.MatchType, changed = desired.MatchType, true
+	}
+
+	if desired.Status != "" && desired.Status != current.Status {
+		update.Status, changed = desired.Status, true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return update
+}
+
+// moneyChanged reports whether desired is set and differs from current. Amounts are compared as numbers, so "1.5"
+// and "1.50" are the same.
+func moneyChanged(current, desired *Money) bool {
+	if desired == nil {
+		return false
+	}
+
+	if current == nil || current.Currency != desired.Currency {
+		return true
+	}
+
+	currentAmount, currentErr := strconv.ParseFloat(current.Amount, 64)
+	desiredAmount, desiredErr := strconv.ParseFloat(desired.Amount, 64)
+
+	if currentErr != nil || desiredErr != nil {
+		return current.Amount != desired.Amount
+	}
+
+	return currentAmount != desiredAmount
+}
+
+func copyMoney(m *Money) *Money {
+	money := *m
+
+	return &money
+}
+
+// sameStrings reports whether a and b hold the same values in any order.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	return reflect.DeepEqual(sortedA, sortedB)
+}
+
+// sameInt64s reports whether a and b hold the same values in any order.
+func sameInt64s(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]int64(nil), a...)
+	sortedB := append([]int64(nil), b...)
+
+	sort.Slice(sortedA, func(i, j int) bool { return sortedA[i] < sortedA[j] })
+	sort.Slice(sortedB, func(i, j int) bool { return sortedB[i] < sortedB[j] })
+
+	return reflect.DeepEqual(sortedA, sortedB)
+}
@@ -0,0 +1,107 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCampaign(t *testing.T) {
+	t.Parallel()
+
+	current := &Campaign{
+		ID:                 1,
+		Name:               "Campaign",
+		CountriesOrRegions: []string{"US", "GB"},
+		DailyBudgetAmount:  &Money{Amount: "10", Currency: "USD"},
+		Status:             CampaignStatusEnabled,
+	}
+
+	desired := *current
+	desired.CountriesOrRegions = []string{"GB", "US"}
+	desired.DailyBudgetAmount = &Money{Amount: "10.00", Currency: "USD"}
+	assert.Nil(t, DiffCampaign(current, &desired))
+
+	desired.DailyBudgetAmount = &Money{Amount: "12.5", Currency: "USD"}
+	desired.Status = CampaignStatusPaused
+	desired.LocInvoiceDetails = &LOCInvoiceDetails{OrderNumber: "PO-1"}
+
+	update := DiffCampaign(current, &desired)
+	status := CampaignStatusPaused
+	assert.Equal(t, &CampaignUpdate{
+		DailyBudgetAmount: &Money{Amount: "12.5", Currency: "USD"},
+		LOCInvoiceDetails: &LOCInvoiceDetails{OrderNumber: "PO-1"},
+		Status:            &status,
+	}, update)
+
+	raw, err := json.Marshal(update)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"dailyBudgetAmount":{"amount":"12.5","currency":"USD"},"locInvoiceDetails":{"orderNumber":"PO-1"},"status":"PAUSED"}`, string(raw))
+}
+
+func TestDiffAdGroup(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	targeting := &TargetDimensions{DeviceClass: &DeviceClassCriteria{Included: []AdGroupDeviceClass{AdGroupDeviceClassIphone}}}
+
+	current := &AdGroup{
+		ID:                     2,
+		Name:                   "Ad Group",
+		AutomatedKeywordsOptIn: true,
+		DefaultBidAmount:       &Money{Amount: "1", Currency: "USD"},
+		StartTime:              DateTime{start},
+		TargetDimensions:       targeting,
+	}
+
+	desired := *current
+	desired.TargetDimensions = &TargetDimensions{DeviceClass: &DeviceClassCriteria{Included: []AdGroupDeviceClass{AdGroupDeviceClassIphone}}}
+	assert.Nil(t, DiffAdGroup(current, &desired))
+
+	desired.AutomatedKeywordsOptIn = false
+	desired.EndTime = DateTime{start.Add(24 * time.Hour)}
+
+	update := DiffAdGroup(current, &desired)
+	assert.Equal(t, &AdGroupUpdateRequest{AutomatedKeywordsOptIn: Bool(false), EndTime: &DateTime{start.Add(24 * time.Hour)}}, update)
+
+	raw, err := json.Marshal(update)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"automatedKeywordsOptIn":false,"endTime":"2021-06-02T00:00:00"}`, string(raw))
+}
+
+func TestDiffKeyword(t *testing.T) {
+	t.Parallel()
+
+	current := &Keyword{ID: 3, AdGroupID: 2, Text: "photo", MatchType: KeywordMatchTypeExact, Status: KeywordStatusActive, BidAmount: Money{Amount: "1", Currency: "USD"}}
+
+	desired := *current
+	assert.Nil(t, DiffKeyword(current, &desired))
+
+	desired.BidAmount = Money{Amount: "1.25", Currency: "USD"}
+	desired.Deleted = true
+
+	update := DiffKeyword(current, &desired)
+	assert.Equal(t, &KeywordUpdateRequest{ID: 3, AdGroupID: 2, BidAmount: &Money{Amount: "1.25", Currency: "USD"}, Deleted: Bool(true)}, update)
+
+	raw, err := json.Marshal(update)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":3,"adGroupId":2,"bidAmount":{"amount":"1.25","currency":"USD"},"deleted":true}`, string(raw))
+}
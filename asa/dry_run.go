@@ -0,0 +1,256 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const headerDryRun = "X-Asa-Dry-Run"
+
+// PlannedOperation is a mutating call recorded by a DryRun instead of being sent.
+type PlannedOperation struct {
+	Service    string          `json:"service,omitempty"`
+	Method     string          `json:"method"`
+	HTTPMethod string          `json:"httpMethod"`
+	Path       string          `json:"path"`
+	Query      string          `json:"query,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	PlannedAt  time.Time       `json:"plannedAt"`
+}
+
+// Name returns the qualified name of the service method, e.g. "Campaigns.CreateCampaign".
+func (p *PlannedOperation) Name() string {
+	if p.Service == "" {
+		return p.Method
+	}
+
+	return p.Service + "." + p.Method
+}
+
+// DryRun is a middleware recording every mutating call, such as creating, updating or deleting campaigns, ad groups,
+// keywords and creative sets, instead of sending it. Reads, including the find and report endpoints sent as POST
+// requests, still go through. Skipped calls succeed with their request payload echoed as the response data, so that
+// code under a dry run sees non-nil results; identifiers and server-side fields of those results are zero.
+type DryRun struct {
+	// Now returns the time recorded with the planned operations, time.Now when nil.
+	Now func() time.Time
+
+	mu         sync.Mutex
+	operations []PlannedOperation
+}
+
+// NewDryRun creates an empty DryRun.
+func NewDryRun() *DryRun {
+	return &DryRun{Now: time.Now}
+}
+
+// WithDryRun records mutating calls in dryRun instead of sending them. Middleware added after it never sees the
+// skipped calls, while middleware added before it sees them answered by the dry run.
+func WithDryRun(dryRun *DryRun) ClientOption {
+	return func(c *Client) error {
+		c.Use(dryRun.Middleware())
+
+		return nil
+	}
+}
+
+// DryRun reports whether the response answers a call skipped by a DryRun.
+func (r *Response) DryRun() bool {
+	return r.Response != nil && r.Header.Get(headerDryRun) != ""
+}
+
+// Middleware returns the middleware recording mutating calls.
+func (d *DryRun) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (*Response, error) {
+			if !op.Mutating() {
+				return next(ctx, op)
+			}
+
+			query, err := encodeOperationQuery(op.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			body, err := marshalOperationBody(op.Body)
+			if err != nil {
+				return nil, err
+			}
+
+			d.record(op, query, body)
+			echoDryRunResult(op.Result, body)
+
+			return newDryRunResponse(), nil
+		}
+	}
+}
+
+// Operations returns the recorded operations in the order they were planned.
+func (d *DryRun) Operations() []PlannedOperation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]PlannedOperation(nil), d.operations...)
+}
+
+// Reset forgets the recorded operations.
+func (d *DryRun) Reset() {
+	d.mu.Lock()
+	d.operations = nil
+	d.mu.Unlock()
+}
+
+// WritePlan writes the recorded operations to w as a human-readable plan.
+func (d *DryRun) WritePlan(w io.Writer) error {
+	operations := d.Operations()
+
+	if len(operations) == 0 {
+		_, err := fmt.Fprintln(w, "No changes planned.")
+
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%d change(s) planned:\n", len(operations)); err != nil {
+		return err
+	}
+
+	for i, op := range operations {
+		path := op.Path
+		if op.Query != "" {
+			path += "?" + op.Query
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%d. %s\n   %s %s\n", i+1, op.Name(), op.HTTPMethod, path); err != nil {
+			return err
+		}
+
+		if len(op.Body) == 0 {
+			continue
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, op.Body, "   ", "  "); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "   %s\n", indented.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Plan returns the recorded operations as a human-readable plan.
+func (d *DryRun) Plan() string {
+	var plan strings.Builder
+
+	_ = d.WritePlan(&plan)
+
+	return plan.String()
+}
+
+func (d *DryRun) record(op *Operation, query string, body json.RawMessage) {
+	now := time.Now
+	if d.Now != nil {
+		now = d.Now
+	}
+
+	d.mu.Lock()
+	d.operations = append(d.operations, PlannedOperation{
+		Service:    op.Service,
+		Method:     op.Method,
+		HTTPMethod: op.HTTPMethod,
+		Path:       op.Path,
+		Query:      query,
+		Body:       body,
+		PlannedAt:  now(),
+	})
+	d.mu.Unlock()
+}
+
+// encodeOperationQuery encodes the query parameters of a call the way they would be sent, or returns "" for calls
+// without any.
+func encodeOperationQuery(query interface{}) (string, error) {
+	if query == nil {
+		return "", nil
+	}
+
+	path, err := appendingQueryOptions("", query)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(path, "?"), nil
+}
+
+// marshalOperationBody encodes a request body the way it would be sent, or returns nil for calls without a body.
+func marshalOperationBody(body interface{}) (json.RawMessage, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(raw) == "null" {
+		raw = nil
+	}
+
+	return raw, nil
+}
+
+// echoDryRunResult decodes the request payload as the data of the response, when the shapes allow it.
+func echoDryRunResult(result interface{}, body json.RawMessage) {
+	if result == nil || len(body) == 0 {
+		return
+	}
+
+	if _, raw := result.(rawResult); raw {
+		return
+	}
+
+	envelope, err := json.Marshal(map[string]json.RawMessage{"data": body})
+	if err != nil {
+		return
+	}
+
+	// a payload that does not fit the result, such as an update request, leaves the result partially empty
+	_ = json.Unmarshal(envelope, result)
+}
+
+func newDryRunResponse() *Response {
+	header := make(http.Header)
+	header.Set(headerDryRun, "true")
+
+	return &Response{Response: &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       http.NoBody,
+	}}
+}
@@ -0,0 +1,128 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	// only reads are routed, so any mutating call reaching the server fails with 404 Not Found
+	client, server := newMuxServer(map[string]string{
+		"POST /campaigns/find":    `{"data":[{"id":1,"name":"Existing"}]}`,
+		"POST /reports/campaigns": `{"data":{"reportingDataResponse":{"row":[]}}}`,
+	})
+	defer server.Close()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	dryRun := NewDryRun()
+	dryRun.Now = func() time.Time { return now }
+	client.Use(dryRun.Middleware())
+
+	ctx := context.Background()
+
+	found, resp, err := client.Campaigns.FindCampaigns(ctx, &Selector{})
+	assert.NoError(t, err)
+	assert.False(t, resp.DryRun())
+	assert.Equal(t, "Existing", found.Campaigns[0].Name)
+
+	_, _, err = client.Reporting.GetCampaignLevelReports(ctx, &ReportingRequest{})
+	assert.NoError(t, err)
+
+	created, resp, err := client.Campaigns.CreateCampaign(ctx, &Campaign{Name: "Planned", AdamID: 9, CountriesOrRegions: []string{"US"}})
+	assert.NoError(t, err)
+	assert.True(t, resp.DryRun())
+	assert.Equal(t, "Planned", created.Campaign.Name)
+
+	keywords, _, err := client.Keywords.CreateTargetingKeywords(ctx, 1, 2, []*Keyword{{Text: "photo", MatchType: KeywordMatchTypeExact}})
+	assert.NoError(t, err)
+	assert.Equal(t, "photo", keywords.Keywords[0].Text)
+
+	_, err = client.Campaigns.DeleteCampaign(ctx, 1)
+	assert.NoError(t, err)
+
+	operations := dryRun.Operations()
+	assert.Len(t, operations, 3)
+	assert.Equal(t, "Campaigns.CreateCampaign", operations[0].Name())
+	assert.Equal(t, "POST", operations[0].HTTPMethod)
+	assert.Equal(t, "campaigns", operations[0].Path)
+	assert.JSONEq(t, `{"adamId":9,"countriesOrRegions":["US"],"name":"Planned","modificationTime":"0001-01-01T00:00:00","startTime":"0001-01-01T00:00:00"}`, string(operations[0].Body))
+	assert.Equal(t, now, operations[0].PlannedAt)
+	assert.Equal(t, "campaigns/1/adgroups/2/targetingkeywords/bulk", operations[1].Path)
+	assert.Equal(t, "Campaigns.DeleteCampaign", operations[2].Name())
+	assert.Equal(t, "DELETE", operations[2].HTTPMethod)
+	assert.Nil(t, operations[2].Body)
+
+	changelog, err := json.Marshal(operations[2])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"service":"Campaigns","method":"DeleteCampaign","httpMethod":"DELETE","path":"campaigns/1","plannedAt":"2021-06-01T12:00:00Z"}`, string(changelog))
+
+	plan := dryRun.Plan()
+	assert.Contains(t, plan, "3 change(s) planned:")
+	assert.Contains(t, plan, "1. Campaigns.CreateCampaign\n   POST campaigns\n   {\n     \"adamId\": 9,")
+	assert.Contains(t, plan, "3. Campaigns.DeleteCampaign\n   DELETE campaigns/1\n")
+
+	dryRun.Reset()
+	assert.Equal(t, "No changes planned.\n", dryRun.Plan())
+}
+
+func TestDryRunQuery(t *testing.T) {
+	t.Parallel()
+
+	dryRun := NewDryRun()
+	handler := dryRun.Middleware()(func(ctx context.Context, op *Operation) (*Response, error) {
+		return nil, errors.New("sent")
+	})
+
+	_, err := handler(context.Background(), &Operation{Method: "Update", HTTPMethod: http.MethodPut, Path: "campaigns/1", Query: url.Values{"deleteSupplySources": {"true"}}})
+	assert.NoError(t, err)
+
+	_, err = handler(context.Background(), &Operation{Method: "Delete", HTTPMethod: http.MethodDelete, Path: "campaigns/2", Query: (*GetAllCampaignQuery)(nil)})
+	assert.NoError(t, err)
+
+	operations := dryRun.Operations()
+	assert.Equal(t, "deleteSupplySources=true", operations[0].Query)
+	assert.Empty(t, operations[1].Query)
+	assert.Contains(t, dryRun.Plan(), "PUT campaigns/1?deleteSupplySources=true\n")
+
+	changelog, err := json.Marshal(operations[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(changelog), `"query":"deleteSupplySources=true"`)
+}
+
+func TestDryRunValidates(t *testing.T) {
+	t.Parallel()
+
+	dryRun := NewDryRun()
+
+	client, err := NewClientWithOptions(WithDryRun(dryRun), WithValidation(true))
+	assert.NoError(t, err)
+
+	_, _, err = client.Keywords.CreateTargetingKeywords(context.Background(), 1, 2, []*Keyword{{Text: "photo"}})
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Empty(t, dryRun.Operations())
+}
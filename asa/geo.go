@@ -70,7 +70,7 @@ type SearchEntity struct {
 func (s *GeoService) SearchGeos(ctx context.Context, params *SearchGeoQuery) (*SearchEntityListResponse, *Response, error) {
 	url := "search/geo"
 	res := new(SearchEntityListResponse)
-	resp, err := s.client.get(ctx, url, &params, res)
+	resp, err := s.client.get(ctx, operationName{"Geo", "SearchGeos"}, url, &params, res)
 
 	return res, resp, err
 }
@@ -95,7 +95,7 @@ type GeoRequest struct {
 func (s *GeoService) GetGeos(ctx context.Context, query *ListGeoQuery, params []*GeoRequest) (*SearchEntityListResponse, *Response, error) {
 	url := "search/geo"
 	res := new(SearchEntityListResponse)
-	resp, err := s.client.postWithQuery(ctx, url, &query, &params, res)
+	resp, err := s.client.postWithQuery(ctx, operationName{"Geo", "GetGeos"}, url, &query, &params, res)
 
 	return res, resp, err
 }
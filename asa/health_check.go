@@ -0,0 +1,399 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// HealthSeverity is how urgently a health finding needs attention.
+type HealthSeverity string
+
+const (
+	// HealthSeverityCritical is for findings that stop delivery until someone fixes the account, billing or app.
+	HealthSeverityCritical HealthSeverity = "CRITICAL"
+	// HealthSeverityWarning is for findings that limit delivery and usually need a configuration change.
+	HealthSeverityWarning HealthSeverity = "WARNING"
+	// HealthSeverityInfo is for findings that are expected, such as entities paused by a user.
+	HealthSeverityInfo HealthSeverity = "INFO"
+)
+
+// HealthSeverities are the severities in decreasing order of urgency.
+func HealthSeverities() []HealthSeverity {
+	return []HealthSeverity{HealthSeverityCritical, HealthSeverityWarning, HealthSeverityInfo}
+}
+
+// HealthEntityType is the kind of entity a health finding is about.
+type HealthEntityType string
+
+const (
+	// HealthEntityTypeCampaign is for findings about a campaign.
+	HealthEntityTypeCampaign HealthEntityType = "CAMPAIGN"
+	// HealthEntityTypeCampaignCountryOrRegion is for findings about a country or region of a campaign.
+	HealthEntityTypeCampaignCountryOrRegion HealthEntityType = "CAMPAIGN_COUNTRY_OR_REGION"
+	// HealthEntityTypeAdGroup is for findings about an ad group.
+	HealthEntityTypeAdGroup HealthEntityType = "AD_GROUP"
+	// HealthEntityTypeAdGroupCreativeSet is for findings about a Creative Set assigned to an ad group.
+	HealthEntityTypeAdGroupCreativeSet HealthEntityType = "AD_GROUP_CREATIVE_SET"
+)
+
+// ServingStateReasonInfo explains a serving state reason and how to resolve it.
+type ServingStateReasonInfo struct {
+	Severity    HealthSeverity `json:"severity"`
+	Explanation string         `json:"explanation"`
+	Remediation string         `json:"remediation"`
+}
+
+// ExplainServingStateReason returns the explanation of a serving state reason of a campaign, a campaign country or
+// region, an ad group or an ad group Creative Set. Unknown reasons are reported as warnings.
+func ExplainServingStateReason(reason string) ServingStateReasonInfo {
+	if info, ok := servingStateReasonCatalog()[reason]; ok {
+		return info
+	}
+
+	return ServingStateReasonInfo{
+		Severity:    HealthSeverityWarning,
+		Explanation: "Search Ads reported an undocumented reason.",
+		Remediation: "Check the Search Ads documentation or contact support.",
+	}
+}
+
+func servingStateReasonCatalog() map[string]ServingStateReasonInfo {
+	return map[string]ServingStateReasonInfo{
+		unknownServingReason:             {HealthSeverityWarning, "Search Ads reports the entity as not running without giving a reason.", "Check the entity in the Search Ads UI, the reason may appear after a delay."},
+		"NO_PAYMENT_METHOD_ON_FILE":      {HealthSeverityCritical, "The organization has no payment method.", "Add a payment method in the billing settings of the Search Ads account."},
+		"MISSING_BO_OR_INVOICING_FIELDS": {HealthSeverityCritical, "The campaign has no budget order or is missing invoicing details.", "Link the campaign to a budget order or fill in its LOC invoice details."},
+		"CREDIT_CARD_DECLINED":           {HealthSeverityCritical, "The credit card on file was declined.", "Update the payment method in the billing settings."},
+		"ORG_PAYMENT_TYPE_CHANGED":       {HealthSeverityCritical, "The payment type of the organization changed.", "Review the billing settings and relink the campaign to a budget order if required."},
+		"ORG_SUSPENDED_POLICY_VIOLATION": {HealthSeverityCritical, "The organization is suspended for a policy violation.", "Contact Search Ads support to resolve the suspension."},
+		"ORG_SUSPENDED_FRAUD":            {HealthSeverityCritical, "The organization is suspended for suspected fraud.", "Contact Search Ads support to resolve the suspension."},
+		"ORG_CHARGE_BACK_DISPUTED":       {HealthSeverityCritical, "A charge of the organization is disputed.", "Resolve the chargeback with your bank or Search Ads support."},
+		"LOC_EXHAUSTED":                  {HealthSeverityCritical, "The line of credit of the organization is exhausted.", "Request a higher credit limit or pay outstanding invoices."},
+		"TAX_VERIFICATION_PENDING":       {HealthSeverityCritical, "The tax information of the organization is being verified.", "Wait for the verification or complete the tax details in the account settings."},
+		"TOTAL_BUDGET_EXHAUSTED":         {HealthSeverityCritical, "The campaign spent its total budget.", "Raise the campaign budget amount or create a new campaign."},
+		"BO_EXHAUSTED":                   {HealthSeverityCritical, "The budget order of the campaign is spent.", "Raise the budget of the order or link the campaign to another budget order."},
+		"BO_END_DATE_REACHED":            {HealthSeverityCritical, "The budget order of the campaign ended.", "Extend the budget order or link the campaign to an active budget order."},
+		"PAUSED_BY_SYSTEM":               {HealthSeverityCritical, "Search Ads paused the entity.", "Check the account for policy or billing notices and contact support if the cause is unclear."},
+		"APP_NOT_ELIGIBLE":               {HealthSeverityCritical, "The app is not eligible for Search Ads.", "Check that the app is available on the App Store in the targeted countries or regions."},
+		"APP_NOT_ELIGIBLE_SEARCHADS":     {HealthSeverityCritical, "The app is not eligible to be promoted with Search Ads.", "Review the Search Ads policies for the app category and content."},
+		"NO_ELIGIBLE_COUNTRIES":          {HealthSeverityCritical, "None of the countries or regions of the campaign can serve the app.", "Target countries or regions where the app is available."},
+		"AD_GROUP_MISSING":               {HealthSeverityCritical, "The campaign has no ad groups.", "Create an ad group in the campaign."},
+		"APP_NOT_SUPPORT":                {HealthSeverityCritical, "The app does not support the devices targeted by the ad group.", "Change the device class targeting of the ad group."},
+		"CREATIVE_SET_INVALID":           {HealthSeverityCritical, "The Creative Set is no longer valid, usually because its assets changed in App Store Connect.", "Update the Creative Set with current assets or replace it."},
+		"DAILY_CAP_EXHAUSTED":            {HealthSeverityWarning, "The campaign spent its daily budget.", "Raise the daily budget if the campaign should keep serving today."},
+		"CAMPAIGN_END_DATE_REACHED":      {HealthSeverityWarning, "The campaign reached its end date.", "Extend or remove the end date of the campaign."},
+		"ADGROUP_END_DATE_REACHED":       {HealthSeverityWarning, "The ad group reached its end date.", "Extend or remove the end time of the ad group."},
+		"AUDIENCE_BELOW_THRESHOLD":       {HealthSeverityWarning, "The audience targeted by the ad group is too small to serve.", "Broaden the targeting dimensions of the ad group."},
+		"PENDING_AUDIENCE_VERIFICATION":  {HealthSeverityWarning, "The audience of the ad group is being verified.", "Wait for the verification to finish, usually within a few hours."},
+		"APP_NOT_PUBLISHED_YET":          {HealthSeverityWarning, "The app is not yet published in the country or region.", "Publish the app in the storefront or stop targeting it."},
+		"SAPIN_LAW_AGENT_UNKNOWN":        {HealthSeverityWarning, "Serving in France requires Sapin law details about the advertiser and its agent.", "Complete the Sapin law information in the account settings."},
+		"SAPIN_LAW_FRENCH_BIZ_UNKNOWN":   {HealthSeverityWarning, "Serving in France requires Sapin law details about the advertiser and its agent.", "Complete the Sapin law information in the account settings."},
+		"SAPIN_LAW_FRENCH_BIZ":           {HealthSeverityWarning, "Serving in France requires Sapin law details about the advertiser and its agent.", "Complete the Sapin law information in the account settings."},
+		"PAUSED_BY_USER":                 {HealthSeverityInfo, "A user paused the entity.", "Enable it when it should serve again."},
+		"AD_GROUP_PAUSED_BY_USER":        {HealthSeverityInfo, "A user paused the entity.", "Enable it when it should serve again."},
+		"DELETED_BY_USER":                {HealthSeverityInfo, "A user deleted the entity.", "Nothing to do unless the deletion was a mistake."},
+		"CAMPAIGN_START_DATE_IN_FUTURE":  {HealthSeverityInfo, "The start date is in the future.", "Nothing to do unless it should already be serving."},
+		"START_DATE_IN_THE_FUTURE":       {HealthSeverityInfo, "The start date is in the future.", "Nothing to do unless it should already be serving."},
+		"BO_START_DATE_IN_FUTURE":        {HealthSeverityInfo, "The budget order of the campaign has not started yet.", "Nothing to do unless it should already be serving."},
+		"CAMPAIGN_NOT_RUNNING":           {HealthSeverityInfo, "The campaign of the ad group is not running.", "Resolve the findings of the campaign."},
+	}
+}
+
+// HealthFinding is an entity that is not running and one of the reasons Search Ads gives for it.
+type HealthFinding struct {
+	Severity        HealthSeverity   `json:"severity"`
+	EntityType      HealthEntityType `json:"entityType"`
+	CampaignID      int64            `json:"campaignId"`
+	AdGroupID       int64            `json:"adGroupId,omitempty"`
+	CreativeSetID   int64            `json:"creativeSetId,omitempty"`
+	CountryOrRegion string           `json:"countryOrRegion,omitempty"`
+	Name            string           `json:"name,omitempty"`
+	Reason          string           `json:"reason"`
+	Explanation     string           `json:"explanation"`
+	Remediation     string           `json:"remediation"`
+}
+
+// HealthReport is the result of a HealthChecker run. Findings are ordered by severity.
+type HealthReport struct {
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Campaigns   int              `json:"campaigns"`
+	AdGroups    int              `json:"adGroups"`
+	Findings    []*HealthFinding `json:"findings,omitempty"`
+}
+
+// Healthy reports whether the report has no critical or warning findings.
+func (r *HealthReport) Healthy() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity != HealthSeverityInfo {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BySeverity groups the findings by severity.
+func (r *HealthReport) BySeverity() map[HealthSeverity][]*HealthFinding {
+	groups := make(map[HealthSeverity][]*HealthFinding)
+	for _, finding := range r.Findings {
+		groups[finding.Severity] = append(groups[finding.Severity], finding)
+	}
+
+	return groups
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *HealthReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report as human-readable text grouped by severity.
+func (r *HealthReport) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Health check at %s: %d campaigns, %d ad groups, %d findings\n",
+		r.GeneratedAt.Format(time.RFC3339), r.Campaigns, r.AdGroups, len(r.Findings)); err != nil {
+		return err
+	}
+
+	groups := r.BySeverity()
+
+	for _, severity := range HealthSeverities() {
+		findings := groups[severity]
+		if len(findings) == 0 {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%s (%d)\n", severity, len(findings)); err != nil {
+			return err
+		}
+
+		for _, finding := range findings {
+			if _, err := fmt.Fprintf(w, "  %s: %s\n    %s\n    Fix: %s\n",
+				finding.entity(), finding.Reason, finding.Explanation, finding.Remediation); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *HealthFinding) entity() string {
+	switch f.EntityType {
+	case HealthEntityTypeCampaignCountryOrRegion:
+		return fmt.Sprintf("campaign %d %q in %s", f.CampaignID, f.Name, f.CountryOrRegion)
+	case HealthEntityTypeAdGroup:
+		return fmt.Sprintf("ad group %d %q of campaign %d", f.AdGroupID, f.Name, f.CampaignID)
+	case HealthEntityTypeAdGroupCreativeSet:
+		return fmt.Sprintf("Creative Set %d of ad group %d in campaign %d", f.CreativeSetID, f.AdGroupID, f.CampaignID)
+	default:
+		return fmt.Sprintf("campaign %d %q", f.CampaignID, f.Name)
+	}
+}
+
+// HealthChecker walks the campaigns, ad groups and ad group Creative Sets of an organization and reports everything
+// that is not running.
+type HealthChecker struct {
+	client *Client
+
+	// CampaignIDs limits the check to these campaigns. Every campaign is checked when empty.
+	CampaignIDs []int64
+	// SkipCreativeSets disables the lookup of ad group Creative Sets.
+	SkipCreativeSets bool
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewHealthChecker creates a HealthChecker for client.
+func NewHealthChecker(client *Client) *HealthChecker {
+	return &HealthChecker{
+		client: client,
+		Now:    time.Now,
+	}
+}
+
+// Check walks the organization of the client and returns the findings for every campaign, country or region, ad
+// group and ad group Creative Set that is not running. Deleted entities are skipped.
+func (h *HealthChecker) Check(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{GeneratedAt: h.Now()}
+
+	campaigns, err := h.client.Campaigns.listAllCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int64]bool, len(h.CampaignIDs))
+	for _, id := range h.CampaignIDs {
+		wanted[id] = true
+	}
+
+	for _, campaign := range campaigns {
+		if campaign.Deleted || (len(wanted) > 0 && !wanted[campaign.ID]) {
+			continue
+		}
+
+		report.Campaigns++
+		report.Findings = append(report.Findings, campaignFindings(campaign)...)
+
+		adGroups, err := h.client.AdGroups.listAllAdGroups(ctx, campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make(map[int64]string, len(adGroups))
+
+		for _, adGroup := range adGroups {
+			if adGroup.Deleted {
+				continue
+			}
+
+			report.AdGroups++
+			names[adGroup.ID] = adGroup.Name
+			report.Findings = append(report.Findings, adGroupFindings(campaign.ID, adGroup)...)
+		}
+
+		if h.SkipCreativeSets {
+			continue
+		}
+
+		creativeSets, err := h.client.CreativeSets.findAllAdGroupCreativeSets(ctx, campaign.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, creativeSet := range creativeSets {
+			if creativeSet.Deleted {
+				continue
+			}
+
+			report.Findings = append(report.Findings, creativeSetFindings(campaign.ID, names[creativeSet.AdGroupID], creativeSet)...)
+		}
+	}
+
+	severityRank := make(map[HealthSeverity]int)
+	for i, severity := range HealthSeverities() {
+		severityRank[severity] = i
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityRank[report.Findings[i].Severity] < severityRank[report.Findings[j].Severity]
+	})
+
+	return report, nil
+}
+
+func newHealthFinding(entityType HealthEntityType, campaignID int64, name string, reason string) *HealthFinding {
+	explanation := ExplainServingStateReason(reason)
+
+	return &HealthFinding{
+		Severity:    explanation.Severity,
+		EntityType:  entityType,
+		CampaignID:  campaignID,
+		Name:        name,
+		Reason:      reason,
+		Explanation: explanation.Explanation,
+		Remediation: explanation.Remediation,
+	}
+}
+
+// unknownServingReason is reported for entities that are not running without a reason.
+const unknownServingReason = "NOT_RUNNING"
+
+func campaignFindings(campaign *Campaign) []*HealthFinding {
+	var findings []*HealthFinding
+
+	if campaign.ServingStatus == CampaignServingStatusNotRunning {
+		reasons := make([]string, 0, len(campaign.ServingStateReasons))
+		for _, reason := range campaign.ServingStateReasons {
+			reasons = append(reasons, string(reason))
+		}
+
+		findings = servingFindings(HealthEntityTypeCampaign, campaign.ID, campaign.Name, reasons)
+	}
+
+	countries := make([]string, 0, len(campaign.CountryOrRegionServingStateReasons))
+	for country := range campaign.CountryOrRegionServingStateReasons {
+		countries = append(countries, country)
+	}
+
+	sort.Strings(countries)
+
+	for _, country := range countries {
+		finding := newHealthFinding(HealthEntityTypeCampaignCountryOrRegion, campaign.ID, campaign.Name, string(campaign.CountryOrRegionServingStateReasons[country]))
+		finding.CountryOrRegion = country
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+func adGroupFindings(campaignID int64, adGroup *AdGroup) []*HealthFinding {
+	if adGroup.ServingStatus != AdGroupServingStatusNotRunning {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(adGroup.ServingStateReasons))
+	for _, reason := range adGroup.ServingStateReasons {
+		reasons = append(reasons, string(reason))
+	}
+
+	findings := servingFindings(HealthEntityTypeAdGroup, campaignID, adGroup.Name, reasons)
+	for _, finding := range findings {
+		finding.AdGroupID = adGroup.ID
+	}
+
+	return findings
+}
+
+func creativeSetFindings(campaignID int64, adGroupName string, creativeSet *AdGroupCreativeSet) []*HealthFinding {
+	if creativeSet.ServingStatus != AdGroupServingStatusNotRunning {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(creativeSet.ServingStatusReasons))
+	for _, reason := range creativeSet.ServingStatusReasons {
+		reasons = append(reasons, string(reason))
+	}
+
+	findings := servingFindings(HealthEntityTypeAdGroupCreativeSet, campaignID, adGroupName, reasons)
+	for _, finding := range findings {
+		finding.AdGroupID = creativeSet.AdGroupID
+		finding.CreativeSetID = creativeSet.CreativeSetID
+	}
+
+	return findings
+}
+
+func servingFindings(entityType HealthEntityType, campaignID int64, name string, reasons []string) []*HealthFinding {
+	if len(reasons) == 0 {
+		reasons = []string{unknownServingReason}
+	}
+
+	findings := make([]*HealthFinding, len(reasons))
+	for i, reason := range reasons {
+		findings[i] = newHealthFinding(entityType, campaignID, name, reason)
+	}
+
+	return findings
+}
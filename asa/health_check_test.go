@@ -0,0 +1,97 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainServingStateReason(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, HealthSeverityCritical, ExplainServingStateReason(string(CampaignServingStateReasonLocExhausted)).Severity)
+	assert.Equal(t, HealthSeverityWarning, ExplainServingStateReason(string(ServingStateReasonAudienceBelowThreshold)).Severity)
+	assert.Equal(t, HealthSeverityCritical, ExplainServingStateReason(string(CreativeSetsServingStateReasonCreativeSetInvalid)).Severity)
+	assert.Equal(t, HealthSeverityInfo, ExplainServingStateReason(string(ServingStateReasonAdGroupPausedByUser)).Severity)
+
+	unknown := ExplainServingStateReason("SOMETHING_NEW")
+	assert.Equal(t, HealthSeverityWarning, unknown.Severity)
+	assert.NotEmpty(t, unknown.Remediation)
+}
+
+func TestHealthCheckerCheck(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns": `{"data":[
+			{"id":1,"name":"Brand","servingStatus":"NOT_RUNNING","servingStateReasons":["LOC_EXHAUSTED"],
+			 "countryOrRegionServingStateReasons":{"FR":"SAPIN_LAW_AGENT_UNKNOWN"}},
+			{"id":2,"name":"Gone","deleted":true,"servingStatus":"NOT_RUNNING","servingStateReasons":["DELETED_BY_USER"]}
+		],"pagination":{"totalResults":2,"startIndex":0,"itemsPerPage":2}}`,
+		"GET /campaigns/1/adgroups": `{"data":[
+			{"id":10,"name":"Exact","servingStatus":"NOT_RUNNING","servingStateReasons":["AUDIENCE_BELOW_THRESHOLD","AD_GROUP_PAUSED_BY_USER"]},
+			{"id":11,"name":"Broad","servingStatus":"RUNNING"}
+		]}`,
+		"POST /campaigns/1/adgroupcreativesets/find": `{"data":[
+			{"id":100,"adGroupId":11,"creativeSetId":5,"servingStatus":"NOT_RUNNING","servingStatusReasons":["CREATIVE_SET_INVALID"]},
+			{"id":101,"adGroupId":11,"creativeSetId":6,"servingStatus":"RUNNING"}
+		]}`,
+	})
+	defer server.Close()
+
+	checker := NewHealthChecker(client)
+	checker.Now = func() time.Time { return time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	report, err := checker.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Campaigns)
+	assert.Equal(t, 2, report.AdGroups)
+	assert.Len(t, report.Findings, 5)
+	assert.False(t, report.Healthy())
+
+	groups := report.BySeverity()
+	assert.Len(t, groups[HealthSeverityCritical], 2)
+	assert.Len(t, groups[HealthSeverityWarning], 2)
+	assert.Len(t, groups[HealthSeverityInfo], 1)
+	assert.Equal(t, HealthSeverityCritical, report.Findings[0].Severity)
+
+	creativeSet := groups[HealthSeverityCritical][1]
+	assert.Equal(t, HealthEntityTypeAdGroupCreativeSet, creativeSet.EntityType)
+	assert.Equal(t, int64(5), creativeSet.CreativeSetID)
+	assert.Equal(t, "Broad", creativeSet.Name)
+
+	country := groups[HealthSeverityWarning][0]
+	assert.Equal(t, "FR", country.CountryOrRegion)
+
+	var text bytes.Buffer
+	assert.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "CRITICAL (2)")
+	assert.Contains(t, text.String(), "Creative Set 5 of ad group 11 in campaign 1: CREATIVE_SET_INVALID")
+
+	var raw bytes.Buffer
+	assert.NoError(t, report.WriteJSON(&raw))
+
+	decoded := &HealthReport{}
+	assert.NoError(t, json.Unmarshal(raw.Bytes(), decoded))
+	assert.Equal(t, report.Findings, decoded.Findings)
+}
@@ -0,0 +1,395 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultHierarchyConcurrency = 4
+
+// ErrPartialHierarchy happens when some branches of a Hierarchy could not be loaded.
+var ErrPartialHierarchy = errors.New("hierarchy partially loaded")
+
+// RateLimiter paces requests. Wait blocks until a request may be sent or ctx is done. A *rate.Limiter of
+// golang.org/x/time/rate satisfies it.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// HierarchyBranch is the kind of children a branch of a Hierarchy holds.
+type HierarchyBranch string
+
+const (
+	// HierarchyBranchAdGroups is for the ad groups of a campaign.
+	HierarchyBranchAdGroups HierarchyBranch = "AD_GROUPS"
+	// HierarchyBranchCampaignNegativeKeywords is for the negative keywords of a campaign.
+	HierarchyBranchCampaignNegativeKeywords HierarchyBranch = "CAMPAIGN_NEGATIVE_KEYWORDS"
+	// HierarchyBranchKeywords is for the targeting keywords of an ad group.
+	HierarchyBranchKeywords HierarchyBranch = "KEYWORDS"
+	// HierarchyBranchNegativeKeywords is for the negative keywords of an ad group.
+	HierarchyBranchNegativeKeywords HierarchyBranch = "NEGATIVE_KEYWORDS"
+	// HierarchyBranchCreativeSets is for the Creative Sets assigned to an ad group.
+	HierarchyBranchCreativeSets HierarchyBranch = "CREATIVE_SETS"
+)
+
+// HierarchyError is the failure to load a branch of a Hierarchy. The rest of the hierarchy is loaded regardless, and
+// the branch keeps the records of the pages loaded before the failure.
+type HierarchyError struct {
+	CampaignID int64
+	// AdGroupID is zero for the branches of a campaign.
+	AdGroupID int64
+	Branch    HierarchyBranch
+	Err       error
+}
+
+// Error returns the branch and the reason it failed.
+func (e *HierarchyError) Error() string {
+	if e.AdGroupID == 0 {
+		return fmt.Sprintf("campaign %d %s: %s", e.CampaignID, e.Branch, e.Err)
+	}
+
+	return fmt.Sprintf("campaign %d ad group %d %s: %s", e.CampaignID, e.AdGroupID, e.Branch, e.Err)
+}
+
+// Unwrap returns the reason the branch failed.
+func (e *HierarchyError) Unwrap() error {
+	return e.Err
+}
+
+// AdGroupNode is an ad group with its keywords and Creative Sets.
+type AdGroupNode struct {
+	AdGroup          *AdGroup              `json:"adGroup"`
+	Keywords         []*Keyword            `json:"keywords,omitempty"`
+	NegativeKeywords []*NegativeKeyword    `json:"negativeKeywords,omitempty"`
+	CreativeSets     []*AdGroupCreativeSet `json:"creativeSets,omitempty"`
+}
+
+// CampaignNode is a campaign with its negative keywords and ad groups.
+type CampaignNode struct {
+	Campaign         *Campaign          `json:"campaign"`
+	NegativeKeywords []*NegativeKeyword `json:"negativeKeywords,omitempty"`
+	AdGroups         []*AdGroupNode     `json:"adGroups,omitempty"`
+}
+
+// Hierarchy is the tree of campaigns, ad groups and their keywords and Creative Sets of an organization, in the order
+// the API lists them.
+type Hierarchy struct {
+	LoadedAt  time.Time         `json:"loadedAt"`
+	Campaigns []*CampaignNode   `json:"campaigns"`
+	Errors    []*HierarchyError `json:"-"`
+
+	mu sync.Mutex
+}
+
+// Err returns nil when every branch was loaded, and otherwise an ErrPartialHierarchy error naming the first failed
+// branch.
+func (h *Hierarchy) Err() error {
+	if len(h.Errors) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d branches failed, first %s", ErrPartialHierarchy, len(h.Errors), h.Errors[0].Error())
+}
+
+func (h *Hierarchy) addError(campaignID int64, adGroupID int64, branch HierarchyBranch, err error) {
+	h.mu.Lock()
+	h.Errors = append(h.Errors, &HierarchyError{CampaignID: campaignID, AdGroupID: adGroupID, Branch: branch, Err: err})
+	h.mu.Unlock()
+}
+
+// HierarchyLoader loads the campaigns of an organization with their ad groups, keywords, negative keywords and
+// Creative Sets. Branches are loaded concurrently with a bounded number of requests in flight, and a branch failing
+// to load is recorded in the Hierarchy without stopping the others. Deleted campaigns and ad groups are skipped.
+type HierarchyLoader struct {
+	client *Client
+
+	// Concurrency is the maximum number of requests in flight, 4 by default.
+	Concurrency int
+	// Limiter paces the requests when set.
+	Limiter RateLimiter
+	// CampaignIDs restricts the hierarchy to these campaigns when set.
+	CampaignIDs []int64
+	// SkipKeywords, SkipNegativeKeywords and SkipCreativeSets leave out branches that are not needed, saving requests.
+	SkipKeywords         bool
+	SkipNegativeKeywords bool
+	SkipCreativeSets     bool
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewHierarchyLoader creates a HierarchyLoader for client.
+func NewHierarchyLoader(client *Client) *HierarchyLoader {
+	return &HierarchyLoader{
+		client:      client,
+		Concurrency: defaultHierarchyConcurrency,
+		Now:         time.Now,
+	}
+}
+
+// hierarchyLoad is the state of a Load call.
+type hierarchyLoad struct {
+	*HierarchyLoader
+
+	hierarchy *Hierarchy
+	slots     chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Load walks the campaigns of the organization and their branches. It only returns an error when the campaigns
+// cannot be listed or ctx ends; failed branches are reported by Hierarchy.Errors and Hierarchy.Err.
+func (l *HierarchyLoader) Load(ctx context.Context) (*Hierarchy, error) {
+	load := l.newLoad()
+
+	campaigns, err := load.campaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, campaign := range campaigns {
+		node := &CampaignNode{Campaign: campaign}
+		load.hierarchy.Campaigns = append(load.hierarchy.Campaigns, node)
+
+		load.spawn(func() { load.loadCampaign(ctx, node) })
+	}
+
+	load.wait()
+
+	return load.hierarchy, ctx.Err()
+}
+
+func (l *HierarchyLoader) newLoad() *hierarchyLoad {
+	concurrency := l.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultHierarchyConcurrency
+	}
+
+	return &hierarchyLoad{
+		HierarchyLoader: l,
+		hierarchy:       &Hierarchy{LoadedAt: l.Now()},
+		slots:           make(chan struct{}, concurrency),
+	}
+}
+
+// wait waits for the spawned tasks and sorts the errors by campaign and ad group.
+func (l *hierarchyLoad) wait() {
+	l.wg.Wait()
+
+	errs := l.hierarchy.Errors
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].CampaignID != errs[j].CampaignID {
+			return errs[i].CampaignID < errs[j].CampaignID
+		}
+
+		return errs[i].AdGroupID < errs[j].AdGroupID
+	})
+}
+
+func (l *hierarchyLoad) spawn(task func()) {
+	l.wg.Add(1)
+
+	go func() {
+		defer l.wg.Done()
+
+		task()
+	}()
+}
+
+// request sends a request once a slot is free and the limiter allows it.
+func (l *hierarchyLoad) request(ctx context.Context, send func() error) error {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer func() { <-l.slots }()
+
+	if l.Limiter != nil {
+		if err := l.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	return send()
+}
+
+// pages requests every page of a list, fetch returning the number of records and the pagination of the page at
+// offset.
+func (l *hierarchyLoad) pages(ctx context.Context, fetch func(offset int) (int, *PageDetail, error)) error {
+	for offset := 0; ; {
+		var (
+			count      int
+			pagination *PageDetail
+		)
+
+		err := l.request(ctx, func() error {
+			var err error
+
+			count, pagination, err = fetch(offset)
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if !hasNextPage(pagination, offset, count) {
+			return nil
+		}
+
+		offset += count
+	}
+}
+
+func (l *hierarchyLoad) campaigns(ctx context.Context) ([]*Campaign, error) {
+	wanted := make(map[int64]bool, len(l.CampaignIDs))
+	for _, id := range l.CampaignIDs {
+		wanted[id] = true
+	}
+
+	var campaigns []*Campaign
+
+	err := l.pages(ctx, func(offset int) (int, *PageDetail, error) {
+		res, _, err := l.client.Campaigns.GetAllCampaigns(ctx, &GetAllCampaignQuery{Limit: findPageLimit, Offset: int32(offset)})
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, campaign := range res.Campaigns {
+			if !campaign.Deleted && (len(wanted) == 0 || wanted[campaign.ID]) {
+				campaigns = append(campaigns, campaign)
+			}
+		}
+
+		return len(res.Campaigns), res.Pagination, nil
+	})
+
+	return campaigns, err
+}
+
+func (l *hierarchyLoad) loadCampaign(ctx context.Context, node *CampaignNode) {
+	campaignID := node.Campaign.ID
+
+	if !l.SkipNegativeKeywords {
+		l.spawn(func() {
+			err := l.pages(ctx, func(offset int) (int, *PageDetail, error) {
+				res, _, err := l.client.Keywords.GetAllNegativeKeywords(ctx, campaignID, &GetAllNegativeKeywordsQuery{Limit: findPageLimit, Offset: int32(offset)})
+				if err != nil {
+					return 0, nil, err
+				}
+
+				node.NegativeKeywords = append(node.NegativeKeywords, res.Keywords...)
+
+				return len(res.Keywords), res.Pagination, nil
+			})
+			if err != nil {
+				l.hierarchy.addError(campaignID, 0, HierarchyBranchCampaignNegativeKeywords, err)
+			}
+		})
+	}
+
+	var adGroups []*AdGroupNode
+
+	err := l.pages(ctx, func(offset int) (int, *PageDetail, error) {
+		res, _, err := l.client.AdGroups.GetAllAdGroups(ctx, campaignID, &GetAllAdGroupsQuery{Limit: findPageLimit, Offset: int32(offset)})
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, adGroup := range res.AdGroups {
+			if !adGroup.Deleted {
+				adGroups = append(adGroups, &AdGroupNode{AdGroup: adGroup})
+			}
+		}
+
+		return len(res.AdGroups), res.Pagination, nil
+	})
+	if err != nil {
+		l.hierarchy.addError(campaignID, 0, HierarchyBranchAdGroups, err)
+
+		return
+	}
+
+	node.AdGroups = adGroups
+
+	for _, adGroup := range adGroups {
+		l.loadAdGroup(ctx, campaignID, adGroup)
+	}
+}
+
+// loadAdGroup spawns the loading of the branches of an ad group.
+func (l *hierarchyLoad) loadAdGroup(ctx context.Context, campaignID int64, node *AdGroupNode) {
+	adGroupID := node.AdGroup.ID
+
+	branch := func(branch HierarchyBranch, fetch func(offset int) (int, *PageDetail, error)) {
+		l.spawn(func() {
+			if err := l.pages(ctx, fetch); err != nil {
+				l.hierarchy.addError(campaignID, adGroupID, branch, err)
+			}
+		})
+	}
+
+	if !l.SkipKeywords {
+		branch(HierarchyBranchKeywords, func(offset int) (int, *PageDetail, error) {
+			res, _, err := l.client.Keywords.GetAllTargetingKeywords(ctx, campaignID, adGroupID, &GetAllTargetingKeywordsQuery{Limit: findPageLimit, Offset: int32(offset)})
+			if err != nil {
+				return 0, nil, err
+			}
+
+			node.Keywords = append(node.Keywords, res.Keywords...)
+
+			return len(res.Keywords), res.Pagination, nil
+		})
+	}
+
+	if !l.SkipNegativeKeywords {
+		branch(HierarchyBranchNegativeKeywords, func(offset int) (int, *PageDetail, error) {
+			res, _, err := l.client.Keywords.GetAllAdGroupNegativeKeywords(ctx, campaignID, adGroupID, &GetAllNegativeKeywordsQuery{Limit: findPageLimit, Offset: int32(offset)})
+			if err != nil {
+				return 0, nil, err
+			}
+
+			node.NegativeKeywords = append(node.NegativeKeywords, res.Keywords...)
+
+			return len(res.Keywords), res.Pagination, nil
+		})
+	}
+
+	if !l.SkipCreativeSets {
+		selector := &Selector{Conditions: []*Condition{
+			{Field: "adGroupId", Operator: ConditionOperatorEquals, Values: []string{strconv.FormatInt(adGroupID, 10)}},
+		}}
+
+		branch(HierarchyBranchCreativeSets, func(offset int) (int, *PageDetail, error) {
+			res, _, err := l.client.CreativeSets.FindAdGroupCreativeSets(ctx, campaignID, &FindAdGroupCreativeSetRequest{Selector: pagedSelector(selector, offset)})
+			if err != nil {
+				return 0, nil, err
+			}
+
+			node.CreativeSets = append(node.CreativeSets, res.AdGroupCreativeSets...)
+
+			return len(res.AdGroupCreativeSets), res.Pagination, nil
+		})
+	}
+}
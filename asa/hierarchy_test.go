@@ -0,0 +1,159 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingLimiter struct {
+	waits int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+
+	return ctx.Err()
+}
+
+func hierarchyTestRoutes() map[string]string {
+	return map[string]string{
+		"GET /campaigns":                                  `{"data":[{"id":1,"name":"Brand"},{"id":2,"name":"Old","deleted":true},{"id":3,"name":"Broken"}],"pagination":{"totalResults":3,"startIndex":0,"itemsPerPage":3}}`,
+		"GET /campaigns/1/negativekeywords/":              `{"data":[{"id":100,"text":"free"}]}`,
+		"GET /campaigns/1/adgroups":                       `{"data":[{"id":10,"name":"Exact"},{"id":11,"name":"Broad"},{"id":12,"name":"Gone","deleted":true}]}`,
+		"GET /campaigns/1/adgroups/10/targetingkeywords/": `{"data":[{"id":1000,"text":"photo"},{"id":1001,"text":"camera"}]}`,
+		"GET /campaigns/1/adgroups/10/negativekeywords/":  `{"data":[{"id":1100,"text":"cheap"}]}`,
+		"GET /campaigns/1/adgroups/11/negativekeywords/":  `{"data":[]}`,
+		"POST /campaigns/1/adgroupcreativesets/find":      `{"data":[{"id":500,"adGroupId":10,"creativeSetId":5}]}`,
+		"GET /campaigns/3/negativekeywords/":              `{"data":[]}`,
+	}
+}
+
+// newConcurrencyServer is like newMuxServer, and also reports the maximum number of requests it served at once.
+func newConcurrencyServer(routes map[string]string) (*Client, *httptest.Server, func() int32) {
+	var inFlight, maxInFlight int32
+
+	var mu sync.Mutex
+
+	mux := muxHandler(routes)
+
+	client, server := newHandlerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		mu.Lock()
+		if current > maxInFlight {
+			maxInFlight = current
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mux(w, r)
+	}))
+
+	return client, server, func() int32 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return maxInFlight
+	}
+}
+
+func TestHierarchyLoader(t *testing.T) {
+	t.Parallel()
+
+	client, server, maxInFlight := newConcurrencyServer(hierarchyTestRoutes())
+	defer server.Close()
+
+	limiter := &countingLimiter{}
+	loader := NewHierarchyLoader(client)
+	loader.Concurrency = 2
+	loader.Limiter = limiter
+
+	hierarchy, err := loader.Load(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, hierarchy.Campaigns, 2)
+	brand := hierarchy.Campaigns[0]
+	assert.Equal(t, "Brand", brand.Campaign.Name)
+	assert.Len(t, brand.NegativeKeywords, 1)
+	require.Len(t, brand.AdGroups, 2)
+	assert.Equal(t, "Exact", brand.AdGroups[0].AdGroup.Name)
+	assert.Len(t, brand.AdGroups[0].Keywords, 2)
+	assert.Equal(t, "cheap", brand.AdGroups[0].NegativeKeywords[0].Text)
+	assert.Equal(t, int64(5), brand.AdGroups[0].CreativeSets[0].CreativeSetID)
+	assert.Equal(t, "Broad", brand.AdGroups[1].AdGroup.Name)
+	assert.Empty(t, brand.AdGroups[1].Keywords)
+
+	broken := hierarchy.Campaigns[1]
+	assert.Equal(t, "Broken", broken.Campaign.Name)
+	assert.Empty(t, broken.AdGroups)
+
+	require.Len(t, hierarchy.Errors, 2)
+	assert.Equal(t, int64(1), hierarchy.Errors[0].CampaignID)
+	assert.Equal(t, int64(11), hierarchy.Errors[0].AdGroupID)
+	assert.Equal(t, HierarchyBranchKeywords, hierarchy.Errors[0].Branch)
+	assert.Equal(t, int64(3), hierarchy.Errors[1].CampaignID)
+	assert.Equal(t, HierarchyBranchAdGroups, hierarchy.Errors[1].Branch)
+	assert.ErrorIs(t, hierarchy.Err(), ErrPartialHierarchy)
+	assert.Contains(t, hierarchy.Err().Error(), "2 branches failed, first campaign 1 ad group 11 KEYWORDS")
+
+	// campaigns, 2 campaign negatives, 2 ad group lists and 3 branches for each of the 2 ad groups
+	assert.Equal(t, int32(11), atomic.LoadInt32(&limiter.waits))
+	assert.LessOrEqual(t, maxInFlight(), int32(2))
+}
+
+func TestHierarchyLoaderFiltersAndSkips(t *testing.T) {
+	t.Parallel()
+
+	client, server, _ := newConcurrencyServer(hierarchyTestRoutes())
+	defer server.Close()
+
+	loader := NewHierarchyLoader(client)
+	loader.CampaignIDs = []int64{1}
+	loader.SkipKeywords = true
+	loader.SkipNegativeKeywords = true
+	loader.SkipCreativeSets = true
+
+	hierarchy, err := loader.Load(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, hierarchy.Err())
+	require.Len(t, hierarchy.Campaigns, 1)
+	assert.Len(t, hierarchy.Campaigns[0].AdGroups, 2)
+	assert.Nil(t, hierarchy.Campaigns[0].NegativeKeywords)
+	assert.Nil(t, hierarchy.Campaigns[0].AdGroups[0].Keywords)
+}
+
+func TestHierarchyLoaderCampaignsFailure(t *testing.T) {
+	t.Parallel()
+
+	client, server, _ := newConcurrencyServer(map[string]string{})
+	defer server.Close()
+
+	_, err := NewHierarchyLoader(client).Load(context.Background())
+	assert.Error(t, err)
+}
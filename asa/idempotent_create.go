@@ -0,0 +1,247 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// ErrCreateUnconfirmed happens when an IdempotentCreator gives up after ambiguous failures. The entity may or may not
+// exist; a later lookup by its natural key tells.
+var ErrCreateUnconfirmed = errors.New("create could not be confirmed")
+
+// IdempotentCreator creates campaigns, ad groups and ad group Creative Sets so that the calls are safe to retry. When
+// a create fails ambiguously, because the connection broke, the request timed out or the server answered with a
+// transient status, the entity may have been created anyway. Before each retry, the creator looks the entity up by its
+// natural key, its name and parent identifiers, and returns the existing entity instead of creating a duplicate.
+type IdempotentCreator struct {
+	client *Client
+
+	// Retry controls the retries after ambiguous failures and defaults to DefaultRetryPolicy. The retry policy of the
+	// client is not applied to the creates, even with RetryNonIdempotent set, as it would retry them blindly.
+	Retry RetryPolicy
+}
+
+// NewIdempotentCreator creates an IdempotentCreator sending requests with client.
+func NewIdempotentCreator(client *Client) *IdempotentCreator {
+	return &IdempotentCreator{
+		client: client,
+		Retry:  DefaultRetryPolicy(),
+	}
+}
+
+// CreateCampaign creates campaign unless a campaign with the same name and app exists after an ambiguous failure.
+// Campaign names are unique within an organization.
+func (c *IdempotentCreator) CreateCampaign(ctx context.Context, campaign *Campaign) (*CampaignResponse, *Response, error) {
+	res := new(CampaignResponse)
+
+	resp, err := c.create(ctx,
+		func(ctx context.Context) (*Response, error) {
+			created, resp, err := c.client.Campaigns.CreateCampaign(ctx, campaign)
+			res = created
+
+			return resp, err
+		},
+		func(ctx context.Context) (bool, *Response, error) {
+			found, resp, err := c.client.Campaigns.FindCampaigns(ctx, &Selector{Conditions: []*Condition{
+				{Field: "name", Operator: ConditionOperatorEquals, Values: []string{campaign.Name}},
+				{Field: "adamId", Operator: ConditionOperatorEquals, Values: []string{strconv.FormatInt(campaign.AdamID, 10)}},
+			}})
+			if err != nil {
+				return false, resp, err
+			}
+
+			for _, existing := range found.Campaigns {
+				if existing.Name == campaign.Name && existing.AdamID == campaign.AdamID && !existing.Deleted {
+					res = &CampaignResponse{Campaign: existing}
+
+					return true, resp, nil
+				}
+			}
+
+			return false, resp, nil
+		},
+	)
+
+	return res, resp, err
+}
+
+// CreateAdGroup creates adGroup in a campaign unless an ad group with the same name exists in the campaign after an
+// ambiguous failure.
+func (c *IdempotentCreator) CreateAdGroup(ctx context.Context, campaignID int64, adGroup *AdGroup) (*AdGroupResponse, *Response, error) {
+	res := new(AdGroupResponse)
+
+	resp, err := c.create(ctx,
+		func(ctx context.Context) (*Response, error) {
+			created, resp, err := c.client.AdGroups.CreateAdGroup(ctx, campaignID, adGroup)
+			res = created
+
+			return resp, err
+		},
+		func(ctx context.Context) (bool, *Response, error) {
+			found, resp, err := c.client.AdGroups.FindAdGroups(ctx, campaignID, &Selector{Conditions: []*Condition{
+				{Field: "name", Operator: ConditionOperatorEquals, Values: []string{adGroup.Name}},
+			}})
+			if err != nil {
+				return false, resp, err
+			}
+
+			for _, existing := range found.AdGroups {
+				if existing.Name == adGroup.Name && !existing.Deleted {
+					res = &AdGroupResponse{AdGroup: existing}
+
+					return true, resp, nil
+				}
+			}
+
+			return false, resp, nil
+		},
+	)
+
+	return res, resp, err
+}
+
+// CreateAdGroupCreativeSets creates a Creative Set and assigns it to an ad group unless, after an ambiguous failure,
+// a Creative Set with the same name and app is already assigned to the ad group.
+func (c *IdempotentCreator) CreateAdGroupCreativeSets(ctx context.Context, campaignID int64, adGroupID int64, body *CreateAdGroupCreativeSetRequest) (*AdGroupCreativeSetResponse, *Response, error) {
+	res := new(AdGroupCreativeSetResponse)
+
+	resp, err := c.create(ctx,
+		func(ctx context.Context) (*Response, error) {
+			created, resp, err := c.client.CreativeSets.CreateAdGroupCreativeSets(ctx, campaignID, adGroupID, body)
+			res = created
+
+			return resp, err
+		},
+		func(ctx context.Context) (bool, *Response, error) {
+			existing, resp, err := c.findAdGroupCreativeSet(ctx, campaignID, adGroupID, body.CreativeSet)
+			if existing != nil {
+				res = &AdGroupCreativeSetResponse{AdGroupCreativeSet: existing}
+			}
+
+			return existing != nil, resp, err
+		},
+	)
+
+	return res, resp, err
+}
+
+// findAdGroupCreativeSet returns the assignment to an ad group of a Creative Set named like creativeSet, or nil.
+func (c *IdempotentCreator) findAdGroupCreativeSet(ctx context.Context, campaignID int64, adGroupID int64, creativeSet *CreativeSetCreate) (*AdGroupCreativeSet, *Response, error) {
+	if creativeSet == nil {
+		return nil, nil, nil
+	}
+
+	sets, resp, err := c.client.CreativeSets.FindCreativeSets(ctx, &FindCreativeSetRequest{Selector: &Selector{Conditions: []*Condition{
+		{Field: "name", Operator: ConditionOperatorEquals, Values: []string{creativeSet.Name}},
+		{Field: "adamId", Operator: ConditionOperatorEquals, Values: []string{strconv.FormatInt(creativeSet.AdamID, 10)}},
+	}}})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var ids []string
+
+	for _, set := range sets.CreativeSets {
+		if set.Name == creativeSet.Name && set.AdamID == creativeSet.AdamID {
+			ids = append(ids, strconv.FormatInt(set.ID, 10))
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, resp, nil
+	}
+
+	assignments, resp, err := c.client.CreativeSets.FindAdGroupCreativeSets(ctx, campaignID, &FindAdGroupCreativeSetRequest{Selector: &Selector{Conditions: []*Condition{
+		{Field: "adGroupId", Operator: ConditionOperatorEquals, Values: []string{strconv.FormatInt(adGroupID, 10)}},
+		{Field: "creativeSetId", Operator: ConditionOperatorIn, Values: ids},
+	}}})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	for _, assignment := range assignments.AdGroupCreativeSets {
+		if assignment.AdGroupID == adGroupID && !assignment.Deleted {
+			return assignment, resp, nil
+		}
+	}
+
+	return nil, resp, nil
+}
+
+// create sends a create once, and after each ambiguous failure looks the entity up with find before retrying. A
+// failed lookup is retried before creating again. The response is the one of the create or of the successful lookup.
+func (c *IdempotentCreator) create(ctx context.Context, create func(ctx context.Context) (*Response, error), find func(ctx context.Context) (bool, *Response, error)) (*Response, error) {
+	var resp *Response
+
+	// settled is set once the outcome of the create is known: created, found or rejected by the API
+	settled := false
+	attempts := 0
+
+	op := func() error {
+		if attempts > 0 {
+			found, findResp, err := find(ctx)
+			if err != nil {
+				return err
+			}
+
+			if found {
+				resp, settled = findResp, true
+
+				return nil
+			}
+		}
+
+		attempts++
+
+		r, err := create(withoutRetries(ctx))
+		resp = r
+
+		if err == nil || !ambiguousCreateFailure(r, err) {
+			settled = true
+
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}
+
+	err := backoff.Retry(op, c.Retry.backOff(ctx))
+	if err == nil || settled || ctx.Err() != nil {
+		return resp, err
+	}
+
+	return resp, fmt.Errorf("%w: %s", ErrCreateUnconfirmed, err.Error())
+}
+
+// ambiguousCreateFailure reports whether a create may have succeeded despite failing: the connection failed or the
+// server answered with a transient status. Requests the API rejected, or that failed before being sent, are not.
+func ambiguousCreateFailure(resp *Response, err error) bool {
+	if resp != nil && resp.Response != nil {
+		return isTransientStatus(resp.StatusCode)
+	}
+
+	var urlErr *url.Error
+
+	return errors.As(err, &urlErr)
+}
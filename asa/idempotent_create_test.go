@@ -0,0 +1,175 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scriptedReply struct {
+	status int
+	body   string
+}
+
+// newScriptedServer answers each "METHOD path" route with its replies in turn, repeating the last one, and counts
+// the requests of each route. Unknown routes are answered with 404 Not Found.
+func newScriptedServer(t *testing.T, routes map[string][]scriptedReply) (*IdempotentCreator, func(route string) int) {
+	t.Helper()
+
+	var mu sync.Mutex
+
+	hits := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		mu.Lock()
+		hit := hits[route]
+		hits[route]++
+		mu.Unlock()
+
+		replies, ok := routes[route]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if hit >= len(replies) {
+			hit = len(replies) - 1
+		}
+
+		w.WriteHeader(replies[hit].status)
+		fmt.Fprintln(w, replies[hit].body)
+	}))
+	t.Cleanup(server.Close)
+
+	retry := RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+
+	client, err := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithRetryPolicy(retry))
+	require.NoError(t, err)
+
+	creator := NewIdempotentCreator(client)
+	creator.Retry = retry
+
+	return creator, func(route string) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return hits[route]
+	}
+}
+
+func TestIdempotentCreateReturnsExistingAfterAmbiguousFailure(t *testing.T) {
+	t.Parallel()
+
+	creator, hits := newScriptedServer(t, map[string][]scriptedReply{
+		"POST /campaigns":      {{http.StatusServiceUnavailable, `{}`}},
+		"POST /campaigns/find": {{http.StatusOK, `{"data":[{"id":8,"name":"Other","adamId":9},{"id":7,"name":"Launch","adamId":9}]}`}},
+	})
+
+	res, resp, err := creator.CreateCampaign(context.Background(), &Campaign{Name: "Launch", AdamID: 9})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(7), res.Campaign.ID)
+	assert.Equal(t, 1, hits("POST /campaigns"), "the create must not be retried by the client")
+	assert.Equal(t, 1, hits("POST /campaigns/find"))
+}
+
+func TestIdempotentCreateRetriesWhenNotFound(t *testing.T) {
+	t.Parallel()
+
+	creator, hits := newScriptedServer(t, map[string][]scriptedReply{
+		"POST /campaigns/1/adgroups":      {{http.StatusBadGateway, `{}`}, {http.StatusOK, `{"data":{"id":3,"name":"Brand"}}`}},
+		"POST /campaigns/1/adgroups/find": {{http.StatusInternalServerError, `{}`}, {http.StatusOK, `{"data":[{"id":2,"name":"Brand","deleted":true}]}`}},
+	})
+
+	res, _, err := creator.CreateAdGroup(context.Background(), 1, &AdGroup{Name: "Brand"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), res.AdGroup.ID)
+	assert.Equal(t, 2, hits("POST /campaigns/1/adgroups"))
+	assert.Equal(t, 2, hits("POST /campaigns/1/adgroups/find"), "a failed lookup is retried before creating again")
+}
+
+func TestIdempotentCreateRejected(t *testing.T) {
+	t.Parallel()
+
+	creator, hits := newScriptedServer(t, map[string][]scriptedReply{
+		"POST /campaigns": {{http.StatusBadRequest, `{"error":{"errors":[{"messageCode":"INVALID_INPUT"}]}}`}},
+	})
+
+	_, resp, err := creator.CreateCampaign(context.Background(), &Campaign{Name: "Launch"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCreateUnconfirmed)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, 0, hits("POST /campaigns/find"))
+}
+
+func TestIdempotentCreateUnconfirmed(t *testing.T) {
+	t.Parallel()
+
+	creator, hits := newScriptedServer(t, map[string][]scriptedReply{
+		"POST /campaigns":      {{http.StatusServiceUnavailable, `{}`}},
+		"POST /campaigns/find": {{http.StatusOK, `{"data":[]}`}},
+	})
+
+	_, _, err := creator.CreateCampaign(context.Background(), &Campaign{Name: "Launch"})
+	assert.ErrorIs(t, err, ErrCreateUnconfirmed)
+	assert.Equal(t, 3, hits("POST /campaigns"))
+	assert.Equal(t, 2, hits("POST /campaigns/find"))
+}
+
+func TestIdempotentCreateAdGroupCreativeSets(t *testing.T) {
+	t.Parallel()
+
+	creator, hits := newScriptedServer(t, map[string][]scriptedReply{
+		"POST /campaigns/1/adgroups/2/adgroupcreativesets/creativesets": {{http.StatusGatewayTimeout, `{}`}},
+		"POST /creativesets/find":                    {{http.StatusOK, `{"data":[{"id":5,"name":"Dark mode","adamID":9}]}`}},
+		"POST /campaigns/1/adgroupcreativesets/find": {{http.StatusOK, `{"data":[{"id":11,"adGroupId":2,"creativeSetId":5}]}`}},
+	})
+
+	body := &CreateAdGroupCreativeSetRequest{CreativeSet: &CreativeSetCreate{Name: "Dark mode", AdamID: 9}}
+
+	res, _, err := creator.CreateAdGroupCreativeSets(context.Background(), 1, 2, body)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), res.AdGroupCreativeSet.ID)
+	assert.Equal(t, 1, hits("POST /campaigns/1/adgroups/2/adgroupcreativesets/creativesets"))
+}
+
+func TestAmbiguousCreateFailure(t *testing.T) {
+	t.Parallel()
+
+	timeout := &url.Error{Op: "Post", URL: "https://api.searchads.apple.com/api/v4/campaigns", Err: context.DeadlineExceeded}
+
+	assert.True(t, ambiguousCreateFailure(nil, timeout))
+	assert.True(t, ambiguousCreateFailure(&Response{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, errors.New("500")))
+	assert.False(t, ambiguousCreateFailure(&Response{Response: &http.Response{StatusCode: http.StatusBadRequest}}, errors.New("400")))
+	assert.False(t, ambiguousCreateFailure(nil, &ValidationError{}))
+}
@@ -75,7 +75,7 @@ type KeywordListResponse struct {
 func (s *KeywordService) CreateTargetingKeywords(ctx context.Context, campaignID int64, adGroupID int64, keyword []*Keyword) (*KeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/targetingkeywords/bulk", campaignID, adGroupID)
 	res := new(KeywordListResponse)
-	resp, err := s.client.post(ctx, url, keyword, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "CreateTargetingKeywords"}, url, keyword, res)
 
 	return res, resp, err
 }
@@ -86,7 +86,7 @@ func (s *KeywordService) CreateTargetingKeywords(ctx context.Context, campaignID
 func (s *KeywordService) FindTargetingKeywords(ctx context.Context, campaignID int64, selector *Selector) (*KeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/targetingkeywords/find", campaignID)
 	res := new(KeywordListResponse)
-	resp, err := s.client.post(ctx, url, selector, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "FindTargetingKeywords"}, url, selector, res)
 
 	return res, resp, err
 }
@@ -106,7 +106,7 @@ type KeywordResponse struct {
 func (s *KeywordService) GetTargetingKeyword(ctx context.Context, campaignID int64, adGroupID int64, keywordID int64) (*KeywordResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/targetingkeywords/%d", campaignID, adGroupID, keywordID)
 	res := new(KeywordResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetTargetingKeyword"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -123,21 +123,23 @@ type GetAllTargetingKeywordsQuery struct {
 func (s *KeywordService) GetAllTargetingKeywords(ctx context.Context, campaignID int64, adGroupID int64, params *GetAllTargetingKeywordsQuery) (*KeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/targetingkeywords/", campaignID, adGroupID)
 	res := new(KeywordListResponse)
-	resp, err := s.client.get(ctx, url, params, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetAllTargetingKeywords"}, url, params, res)
 
 	return res, resp, err
 }
 
-// KeywordUpdateRequest Targeting keyword parameters to use in requests and responses
+// KeywordUpdateRequest Targeting keyword parameters to use in requests and responses. Nil and empty fields are left
+// unchanged.
 //
 // https://developer.apple.com/documentation/apple_search_ads/keywordupdaterequest
 type KeywordUpdateRequest struct {
 	AdGroupID        int64            `json:"adGroupId,omitempty"`
 	BidAmount        *Money           `json:"bidAmount,omitempty"`
-	Deleted          bool             `json:"deleted,omitempty"`
+	Deleted          *bool            `json:"deleted,omitempty"`
 	ID               int64            `json:"id,omitempty"`
-	MatchType        KeywordMatchType `json:"matchType"`
-	ModificationTime DateTime         `json:"modificationTime"`
+	MatchType        KeywordMatchType `json:"matchType,omitempty"`
+	ModificationTime *DateTime        `json:"modificationTime,omitempty"`
+	Status           KeywordStatus    `json:"status,omitempty"`
 }
 
 // UpdateTargetingKeywords Updates targeting keywords in ad groups
@@ -146,7 +148,7 @@ type KeywordUpdateRequest struct {
 func (s *KeywordService) UpdateTargetingKeywords(ctx context.Context, campaignID int64, adGroupID int64, updateRequests []*KeywordUpdateRequest) (*KeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/targetingkeywords/bulk", campaignID, adGroupID)
 	res := new(KeywordListResponse)
-	resp, err := s.client.put(ctx, url, updateRequests, res)
+	resp, err := s.client.put(ctx, operationName{"Keywords", "UpdateTargetingKeywords"}, url, updateRequests, res)
 
 	return res, resp, err
 }
@@ -180,7 +182,7 @@ type NegativeKeywordListResponse struct {
 func (s *KeywordService) CreateNegativeKeywords(ctx context.Context, campaignID int64, keyword []*NegativeKeyword) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/bulk", campaignID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.post(ctx, url, keyword, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "CreateNegativeKeywords"}, url, keyword, res)
 
 	return res, resp, err
 }
@@ -191,7 +193,7 @@ func (s *KeywordService) CreateNegativeKeywords(ctx context.Context, campaignID
 func (s *KeywordService) CreateAdGroupNegativeKeywords(ctx context.Context, campaignID int64, adGroupID int64, keyword []*NegativeKeyword) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/negativekeywords/bulk", campaignID, adGroupID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.post(ctx, url, keyword, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "CreateAdGroupNegativeKeywords"}, url, keyword, res)
 
 	return res, resp, err
 }
@@ -202,7 +204,7 @@ func (s *KeywordService) CreateAdGroupNegativeKeywords(ctx context.Context, camp
 func (s *KeywordService) FindNegativeKeywords(ctx context.Context, campaignID int64, selector *Selector) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/find", campaignID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.post(ctx, url, selector, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "FindNegativeKeywords"}, url, selector, res)
 
 	return res, resp, err
 }
@@ -213,7 +215,7 @@ func (s *KeywordService) FindNegativeKeywords(ctx context.Context, campaignID in
 func (s *KeywordService) FindAdGroupNegativeKeywords(ctx context.Context, campaignID int64, selector *Selector) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/negativekeywords/find", campaignID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.post(ctx, url, selector, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "FindAdGroupNegativeKeywords"}, url, selector, res)
 
 	return res, resp, err
 }
@@ -233,7 +235,7 @@ type NegativeKeywordResponse struct {
 func (s *KeywordService) GetNegativeKeyword(ctx context.Context, campaignID int64, keywordID int64) (*NegativeKeywordResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/%d", campaignID, keywordID)
 	res := new(NegativeKeywordResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetNegativeKeyword"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -244,7 +246,7 @@ func (s *KeywordService) GetNegativeKeyword(ctx context.Context, campaignID int6
 func (s *KeywordService) GetAdGroupNegativeKeyword(ctx context.Context, campaignID int64, adGroupID int64, keywordID int64) (*NegativeKeywordResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/negativekeywords/%d", campaignID, adGroupID, keywordID)
 	res := new(NegativeKeywordResponse)
-	resp, err := s.client.get(ctx, url, nil, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetAdGroupNegativeKeyword"}, url, nil, res)
 
 	return res, resp, err
 }
@@ -261,7 +263,7 @@ type GetAllNegativeKeywordsQuery struct {
 func (s *KeywordService) GetAllNegativeKeywords(ctx context.Context, campaignID int64, params *GetAllNegativeKeywordsQuery) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/", campaignID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.get(ctx, url, params, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetAllNegativeKeywords"}, url, params, res)
 
 	return res, resp, err
 }
@@ -272,7 +274,7 @@ func (s *KeywordService) GetAllNegativeKeywords(ctx context.Context, campaignID
 func (s *KeywordService) GetAllAdGroupNegativeKeywords(ctx context.Context, campaignID int64, adGroupID int64, params *GetAllNegativeKeywordsQuery) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/negativekeywords/", campaignID, adGroupID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.get(ctx, url, params, res)
+	resp, err := s.client.get(ctx, operationName{"Keywords", "GetAllAdGroupNegativeKeywords"}, url, params, res)
 
 	return res, resp, err
 }
@@ -283,7 +285,7 @@ func (s *KeywordService) GetAllAdGroupNegativeKeywords(ctx context.Context, camp
 func (s *KeywordService) UpdateNegativeKeywords(ctx context.Context, campaignID int64, updateRequests []*NegativeKeyword) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/bulk", campaignID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.put(ctx, url, updateRequests, res)
+	resp, err := s.client.put(ctx, operationName{"Keywords", "UpdateNegativeKeywords"}, url, updateRequests, res)
 
 	return res, resp, err
 }
@@ -294,7 +296,7 @@ func (s *KeywordService) UpdateNegativeKeywords(ctx context.Context, campaignID
 func (s *KeywordService) UpdateAdGroupNegativeKeywords(ctx context.Context, campaignID int64, adGroupID int64, updateRequests []*NegativeKeyword) (*NegativeKeywordListResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/negativekeywords/bulk", campaignID, adGroupID)
 	res := new(NegativeKeywordListResponse)
-	resp, err := s.client.put(ctx, url, updateRequests, res)
+	resp, err := s.client.put(ctx, operationName{"Keywords", "UpdateAdGroupNegativeKeywords"}, url, updateRequests, res)
 
 	return res, resp, err
 }
@@ -314,7 +316,7 @@ type IntegerResponse struct {
 func (s *KeywordService) DeleteNegativeKeywords(ctx context.Context, campaignID int64, keywordIds []int64) (*IntegerResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/negativekeywords/delete/bulk", campaignID)
 	res := new(IntegerResponse)
-	resp, err := s.client.post(ctx, url, keywordIds, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "DeleteNegativeKeywords"}, url, keywordIds, res)
 
 	return res, resp, err
 }
@@ -325,7 +327,87 @@ func (s *KeywordService) DeleteNegativeKeywords(ctx context.Context, campaignID
 func (s *KeywordService) DeleteAdGroupNegativeKeywords(ctx context.Context, campaignID int64, adGroupID int64, keywordIds []int64) (*IntegerResponse, *Response, error) {
 	url := fmt.Sprintf("campaigns/%d/adgroups/%d/negativekeywords/delete/bulk", campaignID, adGroupID)
 	res := new(IntegerResponse)
-	resp, err := s.client.post(ctx, url, keywordIds, res)
+	resp, err := s.client.post(ctx, operationName{"Keywords", "DeleteAdGroupNegativeKeywords"}, url, keywordIds, res)
 
 	return res, resp, err
 }
+
+// findPageLimit is the maximum number of records the find endpoints return per page.
+const findPageLimit = 1000
+
+// pagedSelector returns a copy of selector that requests the page starting at offset.
+func pagedSelector(selector *Selector, offset int) *Selector {
+	paged := &Selector{}
+	if selector != nil {
+		*paged = *selector
+	}
+
+	paged.Pagination = &Pagination{
+		Limit:  findPageLimit,
+		Offset: uint32(offset),
+	}
+
+	return paged
+}
+
+// hasNextPage reports whether a find response holding count records at offset has more pages.
+func hasNextPage(pagination *PageDetail, offset int, count int) bool {
+	if pagination == nil || count == 0 {
+		return false
+	}
+
+	return offset+count < pagination.TotalResults
+}
+
+// findAllTargetingKeywords pages through FindTargetingKeywords and returns every matching keyword.
+func (s *KeywordService) findAllTargetingKeywords(ctx context.Context, campaignID int64, selector *Selector) ([]*Keyword, error) {
+	var keywords []*Keyword
+
+	for offset := 0; ; {
+		res, _, err := s.FindTargetingKeywords(ctx, campaignID, pagedSelector(selector, offset))
+		if err != nil {
+			return nil, err
+		}
+
+		keywords = append(keywords, res.Keywords...)
+
+		if !hasNextPage(res.Pagination, offset, len(res.Keywords)) {
+			return keywords, nil
+		}
+
+		offset += len(res.Keywords)
+	}
+}
+
+// findAllNegativeKeywords pages through FindNegativeKeywords and returns every matching campaign negative keyword.
+func (s *KeywordService) findAllNegativeKeywords(ctx context.Context, campaignID int64, selector *Selector) ([]*NegativeKeyword, error) {
+	return s.findAllNegatives(ctx, selector, func(ctx context.Context, selector *Selector) (*NegativeKeywordListResponse, *Response, error) {
+		return s.FindNegativeKeywords(ctx, campaignID, selector)
+	})
+}
+
+// findAllAdGroupNegativeKeywords pages through FindAdGroupNegativeKeywords and returns every matching ad group negative keyword.
+func (s *KeywordService) findAllAdGroupNegativeKeywords(ctx context.Context, campaignID int64, selector *Selector) ([]*NegativeKeyword, error) {
+	return s.findAllNegatives(ctx, selector, func(ctx context.Context, selector *Selector) (*NegativeKeywordListResponse, *Response, error) {
+		return s.FindAdGroupNegativeKeywords(ctx, campaignID, selector)
+	})
+}
+
+func (s *KeywordService) findAllNegatives(ctx context.Context, selector *Selector, find func(context.Context, *Selector) (*NegativeKeywordListResponse, *Response, error)) ([]*NegativeKeyword, error) {
+	var keywords []*NegativeKeyword
+
+	for offset := 0; ; {
+		res, _, err := find(ctx, pagedSelector(selector, offset))
+		if err != nil {
+			return nil, err
+		}
+
+		keywords = append(keywords, res.Keywords...)
+
+		if !hasNextPage(res.Pagination, offset, len(res.Keywords)) {
+			return keywords, nil
+		}
+
+		offset += len(res.Keywords)
+	}
+}
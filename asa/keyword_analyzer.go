@@ -0,0 +1,336 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// KeywordIssueKind is the kind of problem found by the keyword analyzer.
+type KeywordIssueKind string
+
+const (
+	// KeywordIssueKindDuplicate is for the same keyword text and match type targeted more than once in a campaign.
+	KeywordIssueKindDuplicate KeywordIssueKind = "DUPLICATE"
+	// KeywordIssueKindCannibalization is for a Broad keyword that competes with a keyword of another ad group for the same searches.
+	KeywordIssueKindCannibalization KeywordIssueKind = "CANNIBALIZATION"
+	// KeywordIssueKindNegativeConflict is for a negative keyword that blocks a targeting keyword of the same campaign.
+	KeywordIssueKindNegativeConflict KeywordIssueKind = "NEGATIVE_CONFLICT"
+)
+
+// KeywordIssue is a single problem found by the keyword analyzer.
+type KeywordIssue struct {
+	Kind KeywordIssueKind `json:"kind"`
+	// Text is the normalized keyword text the issue is about.
+	Text string `json:"text"`
+	// Keywords are the targeting keywords involved in the issue.
+	Keywords []*Keyword `json:"keywords,omitempty"`
+	// NegativeKeyword is the blocking negative keyword of a KeywordIssueKindNegativeConflict issue.
+	NegativeKeyword *NegativeKeyword `json:"negativeKeyword,omitempty"`
+	// Detail is a human-readable explanation of the issue.
+	Detail string `json:"detail"`
+}
+
+// KeywordAnalysis is the result of analyzing the targeting and negative keywords of a campaign.
+type KeywordAnalysis struct {
+	CampaignID int64           `json:"campaignId,omitempty"`
+	Issues     []*KeywordIssue `json:"issues,omitempty"`
+}
+
+// IssuesOfKind returns the issues of the given kind in the order they were found.
+func (a *KeywordAnalysis) IssuesOfKind(kind KeywordIssueKind) []*KeywordIssue {
+	var issues []*KeywordIssue
+
+	for _, issue := range a.Issues {
+		if issue.Kind == kind {
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// NormalizeKeywordText returns the form of a keyword text used to compare keywords: lower case,
+// without surrounding brackets or quotes, and with runs of whitespace collapsed to a single space.
+func NormalizeKeywordText(text string) string {
+	text = strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`[]"'`, r)
+	})
+
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// AnalyzeCampaignKeywords loads the targeting keywords, campaign negative keywords and ad group negative keywords
+// of a campaign and reports duplicates, cannibalization and negative keyword conflicts between them.
+func (s *KeywordService) AnalyzeCampaignKeywords(ctx context.Context, campaignID int64) (*KeywordAnalysis, error) {
+	targeting, err := s.findAllTargetingKeywords(ctx, campaignID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	campaignNegatives, err := s.findAllNegativeKeywords(ctx, campaignID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	adGroupNegatives, err := s.findAllAdGroupNegativeKeywords(ctx, campaignID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := AnalyzeKeywords(targeting, campaignNegatives, adGroupNegatives)
+	analysis.CampaignID = campaignID
+
+	return analysis, nil
+}
+
+// AnalyzeKeywords reports duplicates, cannibalization and negative keyword conflicts between the targeting keywords
+// of a campaign and its campaign-level and ad group-level negative keywords. Deleted keywords and paused negative
+// keywords are ignored.
+//
+// Keyword texts are compared after NormalizeKeywordText. A Broad keyword is considered to match every search that
+// contains all of its words, while an Exact keyword only matches its own text.
+func AnalyzeKeywords(targeting []*Keyword, campaignNegatives []*NegativeKeyword, adGroupNegatives []*NegativeKeyword) *KeywordAnalysis {
+	keywords := make([]*analyzedKeyword, 0, len(targeting))
+
+	for _, keyword := range targeting {
+		if keyword == nil || keyword.Deleted {
+			continue
+		}
+
+		keywords = append(keywords, newAnalyzedKeyword(keyword))
+	}
+
+	analysis := &KeywordAnalysis{}
+	analysis.Issues = append(analysis.Issues, findDuplicateKeywords(keywords)...)
+	analysis.Issues = append(analysis.Issues, findCannibalizedKeywords(keywords)...)
+	analysis.Issues = append(analysis.Issues, findNegativeConflicts(keywords, campaignNegatives, false)...)
+	analysis.Issues = append(analysis.Issues, findNegativeConflicts(keywords, adGroupNegatives, true)...)
+
+	return analysis
+}
+
+type analyzedKeyword struct {
+	keyword *Keyword
+	text    string
+	words   map[string]bool
+}
+
+func newAnalyzedKeyword(keyword *Keyword) *analyzedKeyword {
+	text := NormalizeKeywordText(keyword.Text)
+
+	return &analyzedKeyword{
+		keyword: keyword,
+		text:    text,
+		words:   keywordWords(text),
+	}
+}
+
+func keywordWords(normalized string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(normalized) {
+		words[word] = true
+	}
+
+	return words
+}
+
+// containsWords reports whether every word of subset is part of words.
+func containsWords(words map[string]bool, subset map[string]bool) bool {
+	for word := range subset {
+		if !words[word] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func findDuplicateKeywords(keywords []*analyzedKeyword) []*KeywordIssue {
+	type duplicateKey struct {
+		text      string
+		matchType KeywordMatchType
+	}
+
+	var order []duplicateKey
+
+	groups := make(map[duplicateKey][]*Keyword)
+
+	for _, k := range keywords {
+		key := duplicateKey{text: k.text, matchType: k.keyword.MatchType}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+
+		groups[key] = append(groups[key], k.keyword)
+	}
+
+	var issues []*KeywordIssue
+
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 {
+			continue
+		}
+
+		issues = append(issues, &KeywordIssue{
+			Kind:     KeywordIssueKindDuplicate,
+			Text:     key.text,
+			Keywords: group,
+			Detail:   fmt.Sprintf("%s keyword %q is targeted %d times in ad groups %s", key.matchType, key.text, len(group), adGroupList(group)),
+		})
+	}
+
+	return issues
+}
+
+func findCannibalizedKeywords(keywords []*analyzedKeyword) []*KeywordIssue {
+	byWord := make(map[string][]*analyzedKeyword)
+
+	for _, k := range keywords {
+		for word := range k.words {
+			byWord[word] = append(byWord[word], k)
+		}
+	}
+
+	var issues []*KeywordIssue
+
+	for _, broad := range keywords {
+		if broad.keyword.MatchType != KeywordMatchTypeBroad || len(broad.words) == 0 {
+			continue
+		}
+
+		for _, other := range rarestWordCandidates(broad, byWord) {
+			if other.keyword.AdGroupID == broad.keyword.AdGroupID {
+				continue
+			}
+			// identical text and match type across ad groups is reported as a duplicate instead
+			if other.text == broad.text && other.keyword.MatchType == KeywordMatchTypeBroad {
+				continue
+			}
+
+			if !containsWords(other.words, broad.words) {
+				continue
+			}
+
+			issues = append(issues, &KeywordIssue{
+				Kind:     KeywordIssueKindCannibalization,
+				Text:     broad.text,
+				Keywords: []*Keyword{broad.keyword, other.keyword},
+				Detail: fmt.Sprintf("Broad keyword %q in ad group %d competes with %s keyword %q in ad group %d",
+					broad.text, broad.keyword.AdGroupID, other.keyword.MatchType, other.text, other.keyword.AdGroupID),
+			})
+		}
+	}
+
+	return issues
+}
+
+// rarestWordCandidates returns the keywords sharing the least common word of k, which is the smallest
+// set of keywords that can contain all words of k.
+func rarestWordCandidates(k *analyzedKeyword, byWord map[string][]*analyzedKeyword) []*analyzedKeyword {
+	var candidates []*analyzedKeyword
+
+	first := true
+
+	for word := range k.words {
+		if list := byWord[word]; first || len(list) < len(candidates) {
+			candidates = list
+			first = false
+		}
+	}
+
+	return candidates
+}
+
+func findNegativeConflicts(keywords []*analyzedKeyword, negatives []*NegativeKeyword, adGroupLevel bool) []*KeywordIssue {
+	var issues []*KeywordIssue
+
+	for _, negative := range negatives {
+		if negative == nil || negative.Deleted || negative.Status == KeywordStatusPaused {
+			continue
+		}
+
+		text := NormalizeKeywordText(negative.Text)
+		words := keywordWords(text)
+
+		for _, k := range keywords {
+			if adGroupLevel && negative.AdGroupID != k.keyword.AdGroupID {
+				continue
+			}
+
+			if !negativeBlocks(negative.MatchType, text, words, k) {
+				continue
+			}
+
+			scope := "campaign"
+			if adGroupLevel {
+				scope = fmt.Sprintf("ad group %d", negative.AdGroupID)
+			}
+
+			issues = append(issues, &KeywordIssue{
+				Kind:            KeywordIssueKindNegativeConflict,
+				Text:            k.text,
+				Keywords:        []*Keyword{k.keyword},
+				NegativeKeyword: negative,
+				Detail: fmt.Sprintf("%s negative keyword %q of the %s blocks %s keyword %q in ad group %d",
+					negative.MatchType, text, scope, k.keyword.MatchType, k.text, k.keyword.AdGroupID),
+			})
+		}
+	}
+
+	return issues
+}
+
+// negativeBlocks reports whether a negative keyword prevents a targeting keyword from serving on its own text.
+// A Broad negative blocks every search containing all of its words, an Exact negative blocks only its own text.
+func negativeBlocks(matchType KeywordMatchType, text string, words map[string]bool, k *analyzedKeyword) bool {
+	if text == "" {
+		return false
+	}
+
+	if matchType == KeywordMatchTypeBroad {
+		return containsWords(k.words, words)
+	}
+
+	return k.text == text
+}
+
+func adGroupList(keywords []*Keyword) string {
+	seen := make(map[int64]bool)
+	ids := make([]int64, 0, len(keywords))
+
+	for _, k := range keywords {
+		if !seen[k.AdGroupID] {
+			seen[k.AdGroupID] = true
+			ids = append(ids, k.AdGroupID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,120 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeKeywordText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "photo editor", NormalizeKeywordText("  [Photo   EDITOR] "))
+	assert.Equal(t, "photo editor", NormalizeKeywordText(`"photo editor"`))
+	assert.Equal(t, "", NormalizeKeywordText("  "))
+}
+
+func TestAnalyzeKeywordsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	analysis := AnalyzeKeywords([]*Keyword{
+		{ID: 1, AdGroupID: 10, Text: "Photo Editor", MatchType: KeywordMatchTypeExact},
+		{ID: 2, AdGroupID: 20, Text: "photo  editor", MatchType: KeywordMatchTypeExact},
+		{ID: 3, AdGroupID: 30, Text: "photo editor", MatchType: KeywordMatchTypeExact, Deleted: true},
+		{ID: 4, AdGroupID: 10, Text: "photo editor", MatchType: KeywordMatchTypeBroad},
+	}, nil, nil)
+
+	duplicates := analysis.IssuesOfKind(KeywordIssueKindDuplicate)
+	assert.Len(t, duplicates, 1)
+	assert.Equal(t, "photo editor", duplicates[0].Text)
+	assert.Len(t, duplicates[0].Keywords, 2)
+	assert.Contains(t, duplicates[0].Detail, "10, 20")
+}
+
+func TestAnalyzeKeywordsCannibalization(t *testing.T) {
+	t.Parallel()
+
+	analysis := AnalyzeKeywords([]*Keyword{
+		{ID: 1, AdGroupID: 10, Text: "photo", MatchType: KeywordMatchTypeBroad},
+		{ID: 2, AdGroupID: 20, Text: "photo editor", MatchType: KeywordMatchTypeExact},
+		{ID: 3, AdGroupID: 10, Text: "photo filter", MatchType: KeywordMatchTypeExact},
+		{ID: 4, AdGroupID: 30, Text: "video editor", MatchType: KeywordMatchTypeExact},
+	}, nil, nil)
+
+	issues := analysis.IssuesOfKind(KeywordIssueKindCannibalization)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, int64(1), issues[0].Keywords[0].ID)
+	assert.Equal(t, int64(2), issues[0].Keywords[1].ID)
+}
+
+func TestAnalyzeKeywordsNegativeConflicts(t *testing.T) {
+	t.Parallel()
+
+	targeting := []*Keyword{
+		{ID: 1, AdGroupID: 10, Text: "free photo editor", MatchType: KeywordMatchTypeExact},
+		{ID: 2, AdGroupID: 20, Text: "photo editor", MatchType: KeywordMatchTypeBroad},
+		{ID: 3, AdGroupID: 20, Text: "collage", MatchType: KeywordMatchTypeExact},
+	}
+	campaignNegatives := []*NegativeKeyword{
+		{ID: 100, Text: "free", MatchType: KeywordMatchTypeBroad},
+		{ID: 101, Text: "collage", MatchType: KeywordMatchTypeExact, Status: KeywordStatusPaused},
+	}
+	adGroupNegatives := []*NegativeKeyword{
+		{ID: 200, AdGroupID: 20, Text: "Photo Editor", MatchType: KeywordMatchTypeExact},
+		{ID: 201, AdGroupID: 10, Text: "collage", MatchType: KeywordMatchTypeExact},
+	}
+
+	issues := AnalyzeKeywords(targeting, campaignNegatives, adGroupNegatives).IssuesOfKind(KeywordIssueKindNegativeConflict)
+	assert.Len(t, issues, 2)
+	assert.Equal(t, int64(100), issues[0].NegativeKeyword.ID)
+	assert.Equal(t, int64(1), issues[0].Keywords[0].ID)
+	assert.Equal(t, int64(200), issues[1].NegativeKeyword.ID)
+	assert.Equal(t, int64(2), issues[1].Keywords[0].ID)
+}
+
+func TestAnalyzeCampaignKeywords(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"POST /campaigns/1/adgroups/targetingkeywords/find": `{"data":[
+			{"id":1,"adGroupId":10,"text":"photo editor","matchType":"Exact"},
+			{"id":2,"adGroupId":20,"text":"photo editor","matchType":"Exact"}
+		],"pagination":{"totalResults":2,"startIndex":0,"itemsPerPage":2}}`,
+		"POST /campaigns/1/negativekeywords/find":          `{"data":[{"id":100,"campaignId":1,"text":"editor","matchType":"Broad"}]}`,
+		"POST /campaigns/1/adgroups/negativekeywords/find": `{"data":[]}`,
+	})
+	defer server.Close()
+
+	analysis, err := client.Keywords.AnalyzeCampaignKeywords(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), analysis.CampaignID)
+	assert.Len(t, analysis.IssuesOfKind(KeywordIssueKindDuplicate), 1)
+	assert.Len(t, analysis.IssuesOfKind(KeywordIssueKindNegativeConflict), 2)
+}
+
+func TestAnalyzeCampaignKeywordsError(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{})
+	defer server.Close()
+
+	_, err := client.Keywords.AnalyzeCampaignKeywords(context.Background(), 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+)
+
+// Operation describes a call of a service method as it passes through the middleware of a client.
+type Operation struct {
+	// Service is the name of the service field of Client, e.g. "Campaigns", or empty for Client.Do and Client.DoRaw.
+	Service string
+	// Method is the name of the service method, e.g. "CreateCampaign", or "Do" and "DoRaw".
+	Method string
+	// HTTPMethod is the HTTP method of the request, e.g. "POST".
+	HTTPMethod string
+	// Path is the path of the request relative to the base URL of the client, e.g. "campaigns/1".
+	Path string
+	// Query holds the URL query parameters of the request, or nil.
+	Query interface{}
+	// Body is the typed request body, such as a *Campaign or a []*Keyword, or nil.
+	Body interface{}
+	// Result is the value the response body is decoded into, or nil. Middleware that answers an operation without
+	// calling the next handler should fill it.
+	Result interface{}
+
+	options []requestOption
+}
+
+// Mutating reports whether the operation changes data, rather than only reading it. Searches sent as POST requests,
+// such as the find endpoints and reports, are not mutating.
+func (op *Operation) Mutating() bool {
+	return isMutatingRequest(op.HTTPMethod, op.Path)
+}
+
+// Handler executes an operation.
+type Handler func(ctx context.Context, op *Operation) (*Response, error)
+
+// Middleware wraps the handler of a client. It may observe or change the operation and the response, or answer the
+// operation itself without calling next.
+type Middleware func(next Handler) Handler
+
+// Use appends middleware to the chain of the client. The first middleware added is the outermost, seeing operations
+// first and responses last. Use is not safe to call concurrently with requests, so add middleware before using the
+// client.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// WithMiddleware adds middleware to the chain of the client, see Use.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) error {
+		c.Use(middleware...)
+
+		return nil
+	}
+}
+
+// operationName identifies the service method issuing a request.
+type operationName struct {
+	service string
+	method  string
+}
+
+func (n operationName) operation(httpMethod string, path string, query interface{}, body interface{}, v interface{}) *Operation {
+	return &Operation{
+		Service:    n.service,
+		Method:     n.method,
+		HTTPMethod: httpMethod,
+		Path:       path,
+		Query:      query,
+		Body:       body,
+		Result:     v,
+	}
+}
+
+// call validates op when the client validates requests, and passes it through the middleware chain of the client.
+func (c *Client) call(ctx context.Context, op *Operation, options ...requestOption) (*Response, error) {
+	op.options = options
+
+	if c.validate && op.Body != nil && op.Mutating() {
+		if err := validateRequest(op.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	handler := c.execute
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		handler = c.middleware[i](handler)
+	}
+
+	return handler(ctx, op)
+}
+
+// execute is the innermost handler, sending op to the API.
+func (c *Client) execute(ctx context.Context, op *Operation) (*Response, error) {
+	path := op.Path
+
+	if op.Query != nil {
+		var err error
+
+		path, err = appendingQueryOptions(path, op.Query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.newRequest(ctx, op.HTTPMethod, path, op.Body, op.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, req, op.Result)
+}
@@ -0,0 +1,129 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareObservesOperations(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(`{"data":{"id":1,"name":"Campaign"}}`, http.StatusOK, false)
+	defer server.Close()
+
+	var seen []*Operation
+
+	var order []string
+
+	client.Use(
+		func(next Handler) Handler {
+			return func(ctx context.Context, op *Operation) (*Response, error) {
+				order = append(order, "outer")
+				seen = append(seen, op)
+
+				return next(ctx, op)
+			}
+		},
+		func(next Handler) Handler {
+			return func(ctx context.Context, op *Operation) (*Response, error) {
+				order = append(order, "inner")
+
+				return next(ctx, op)
+			}
+		},
+	)
+
+	campaign := &Campaign{Name: "Campaign"}
+	res, resp, err := client.Campaigns.CreateCampaign(context.Background(), campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(1), res.Campaign.ID)
+
+	_, _, err = client.Campaigns.GetCampaign(context.Background(), 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"outer", "inner", "outer", "inner"}, order)
+	assert.Len(t, seen, 2)
+	assert.Equal(t, "Campaigns", seen[0].Service)
+	assert.Equal(t, "CreateCampaign", seen[0].Method)
+	assert.Equal(t, http.MethodPost, seen[0].HTTPMethod)
+	assert.Equal(t, "campaigns", seen[0].Path)
+	assert.Same(t, campaign, seen[0].Body)
+	assert.True(t, seen[0].Mutating())
+	assert.Equal(t, "GetCampaign", seen[1].Method)
+	assert.Equal(t, "campaigns/1", seen[1].Path)
+	assert.False(t, seen[1].Mutating())
+}
+
+func TestMiddlewareShortCircuits(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{})
+	defer server.Close()
+
+	errBlocked := errors.New("blocked by policy")
+
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (*Response, error) {
+			if op.Mutating() {
+				return nil, errBlocked
+			}
+
+			if res, ok := op.Result.(*CampaignResponse); ok {
+				res.Campaign = &Campaign{ID: 7, Name: "Fake"}
+
+				return &Response{}, nil
+			}
+
+			return next(ctx, op)
+		}
+	})
+
+	res, _, err := client.Campaigns.GetCampaign(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fake", res.Campaign.Name)
+
+	_, err = client.Campaigns.DeleteCampaign(context.Background(), 7)
+	assert.ErrorIs(t, err, errBlocked)
+}
+
+func TestMiddlewareMutatesOperations(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"GET /campaigns/2": `{"data":{"id":2,"name":"Redirected"}}`,
+	})
+	defer server.Close()
+
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (*Response, error) {
+			op.Path = "campaigns/2"
+
+			return next(ctx, op)
+		}
+	})
+
+	res, _, err := client.Campaigns.GetCampaign(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Redirected", res.Campaign.Name)
+}
@@ -0,0 +1,467 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotInGraph happens when a subtree to refresh hangs from an entity that is not in the OrgGraph.
+var ErrNotInGraph = errors.New("entity not in graph")
+
+type adGroupNameKey struct {
+	campaignID int64
+	name       string
+}
+
+type keywordTextKey struct {
+	campaignID int64
+	// adGroupID is zero for campaign negative keywords.
+	adGroupID int64
+	text      string
+	matchType KeywordMatchType
+}
+
+// OrgGraph is the Hierarchy of an organization with its budget orders, indexed to look entities up by ID or by the
+// names of their path and to navigate from children to parents. It serializes to JSON for offline use, and decoding
+// it rebuilds the indexes.
+//
+// Name lookups skip deleted keywords; campaign and ad group names are unique among the entities the graph holds.
+// An OrgGraph is not safe for concurrent use while it is refreshed.
+type OrgGraph struct {
+	*Hierarchy
+	BudgetOrders []*BudgetOrder `json:"budgetOrders,omitempty"`
+
+	campaigns          map[int64]*CampaignNode
+	campaignsByName    map[string]*CampaignNode
+	adGroups           map[int64]*AdGroupNode
+	adGroupsByName     map[adGroupNameKey]*AdGroupNode
+	adGroupCampaigns   map[int64]*CampaignNode
+	keywords           map[int64]*Keyword
+	keywordsByText     map[keywordTextKey]*Keyword
+	keywordAdGroups    map[int64]*AdGroupNode
+	negatives          map[int64]*NegativeKeyword
+	negativesByText    map[keywordTextKey]*NegativeKeyword
+	negativeCampaigns  map[int64]*CampaignNode
+	negativeAdGroups   map[int64]*AdGroupNode
+	creativeSets       map[int64]*AdGroupCreativeSet
+	creativeSetGroups  map[int64]*AdGroupNode
+	budgetOrders       map[int64]*BudgetOrder
+	budgetOrderTargets map[int64][]*CampaignNode
+}
+
+// NewOrgGraph indexes hierarchy and budgetOrders.
+func NewOrgGraph(hierarchy *Hierarchy, budgetOrders []*BudgetOrder) *OrgGraph {
+	if hierarchy == nil {
+		hierarchy = &Hierarchy{}
+	}
+
+	g := &OrgGraph{Hierarchy: hierarchy, BudgetOrders: budgetOrders}
+	g.reindex()
+
+	return g
+}
+
+// UnmarshalJSON decodes a graph encoded with encoding/json and indexes it.
+func (g *OrgGraph) UnmarshalJSON(data []byte) error {
+	type orgGraph OrgGraph
+
+	var graph orgGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return err
+	}
+
+	*g = *NewOrgGraph(graph.Hierarchy, graph.BudgetOrders)
+
+	return nil
+}
+
+func (g *OrgGraph) reindex() {
+	g.campaigns = make(map[int64]*CampaignNode)
+	g.campaignsByName = make(map[string]*CampaignNode)
+	g.adGroups = make(map[int64]*AdGroupNode)
+	g.adGroupsByName = make(map[adGroupNameKey]*AdGroupNode)
+	g.adGroupCampaigns = make(map[int64]*CampaignNode)
+	g.keywords = make(map[int64]*Keyword)
+	g.keywordsByText = make(map[keywordTextKey]*Keyword)
+	g.keywordAdGroups = make(map[int64]*AdGroupNode)
+	g.negatives = make(map[int64]*NegativeKeyword)
+	g.negativesByText = make(map[keywordTextKey]*NegativeKeyword)
+	g.negativeCampaigns = make(map[int64]*CampaignNode)
+	g.negativeAdGroups = make(map[int64]*AdGroupNode)
+	g.creativeSets = make(map[int64]*AdGroupCreativeSet)
+	g.creativeSetGroups = make(map[int64]*AdGroupNode)
+	g.budgetOrders = make(map[int64]*BudgetOrder)
+	g.budgetOrderTargets = make(map[int64][]*CampaignNode)
+
+	for _, order := range g.BudgetOrders {
+		g.budgetOrders[order.ID] = order
+	}
+
+	for _, campaign := range g.Campaigns {
+		campaignID := campaign.Campaign.ID
+		g.campaigns[campaignID] = campaign
+		g.campaignsByName[campaign.Campaign.Name] = campaign
+
+		for _, orderID := range campaign.Campaign.BudgetOrders {
+			g.budgetOrderTargets[orderID] = append(g.budgetOrderTargets[orderID], campaign)
+		}
+
+		for _, negative := range campaign.NegativeKeywords {
+			g.indexNegative(negative, campaign, nil)
+		}
+
+		for _, adGroup := range campaign.AdGroups {
+			g.indexAdGroup(campaign, adGroup)
+		}
+	}
+}
+
+func (g *OrgGraph) indexAdGroup(campaign *CampaignNode, adGroup *AdGroupNode) {
+	adGroupID := adGroup.AdGroup.ID
+	g.adGroups[adGroupID] = adGroup
+	g.adGroupsByName[adGroupNameKey{campaign.Campaign.ID, adGroup.AdGroup.Name}] = adGroup
+	g.adGroupCampaigns[adGroupID] = campaign
+
+	for _, keyword := range adGroup.Keywords {
+		g.keywords[keyword.ID] = keyword
+		g.keywordAdGroups[keyword.ID] = adGroup
+
+		if !keyword.Deleted {
+			g.keywordsByText[keywordTextKey{campaign.Campaign.ID, adGroupID, keyword.Text, keyword.MatchType}] = keyword
+		}
+	}
+
+	for _, negative := range adGroup.NegativeKeywords {
+		g.indexNegative(negative, campaign, adGroup)
+	}
+
+	for _, creativeSet := range adGroup.CreativeSets {
+		g.creativeSets[creativeSet.ID] = creativeSet
+		g.creativeSetGroups[creativeSet.ID] = adGroup
+	}
+}
+
+func (g *OrgGraph) indexNegative(negative *NegativeKeyword, campaign *CampaignNode, adGroup *AdGroupNode) {
+	key := keywordTextKey{campaignID: campaign.Campaign.ID, text: negative.Text, matchType: negative.MatchType}
+
+	g.negatives[negative.ID] = negative
+	g.negativeCampaigns[negative.ID] = campaign
+
+	if adGroup != nil {
+		key.adGroupID = adGroup.AdGroup.ID
+		g.negativeAdGroups[negative.ID] = adGroup
+	}
+
+	if !negative.Deleted {
+		g.negativesByText[key] = negative
+	}
+}
+
+// Campaign returns the campaign with the ID, or nil.
+func (g *OrgGraph) Campaign(campaignID int64) *CampaignNode {
+	return g.campaigns[campaignID]
+}
+
+// AdGroup returns the ad group with the ID, or nil.
+func (g *OrgGraph) AdGroup(adGroupID int64) *AdGroupNode {
+	return g.adGroups[adGroupID]
+}
+
+// Keyword returns the targeting keyword with the ID, or nil.
+func (g *OrgGraph) Keyword(keywordID int64) *Keyword {
+	return g.keywords[keywordID]
+}
+
+// NegativeKeyword returns the campaign or ad group negative keyword with the ID, or nil.
+func (g *OrgGraph) NegativeKeyword(keywordID int64) *NegativeKeyword {
+	return g.negatives[keywordID]
+}
+
+// AdGroupCreativeSet returns the assignment of a Creative Set to an ad group with the ID, or nil.
+func (g *OrgGraph) AdGroupCreativeSet(adGroupCreativeSetID int64) *AdGroupCreativeSet {
+	return g.creativeSets[adGroupCreativeSetID]
+}
+
+// BudgetOrder returns the budget order with the ID, or nil.
+func (g *OrgGraph) BudgetOrder(budgetOrderID int64) *BudgetOrder {
+	return g.budgetOrders[budgetOrderID]
+}
+
+// FindCampaign returns the campaign named campaignName, or nil.
+func (g *OrgGraph) FindCampaign(campaignName string) *CampaignNode {
+	return g.campaignsByName[campaignName]
+}
+
+// FindAdGroup returns the ad group named adGroupName in the campaign named campaignName, or nil.
+func (g *OrgGraph) FindAdGroup(campaignName string, adGroupName string) *AdGroupNode {
+	campaign := g.FindCampaign(campaignName)
+	if campaign == nil {
+		return nil
+	}
+
+	return g.adGroupsByName[adGroupNameKey{campaign.Campaign.ID, adGroupName}]
+}
+
+// FindKeyword returns the targeting keyword with the text and match type in the ad group named adGroupName of the
+// campaign named campaignName, or nil.
+func (g *OrgGraph) FindKeyword(campaignName string, adGroupName string, text string, matchType KeywordMatchType) *Keyword {
+	campaign := g.FindCampaign(campaignName)
+	if campaign == nil {
+		return nil
+	}
+
+	adGroup := g.adGroupsByName[adGroupNameKey{campaign.Campaign.ID, adGroupName}]
+	if adGroup == nil {
+		return nil
+	}
+
+	return g.keywordsByText[keywordTextKey{campaign.Campaign.ID, adGroup.AdGroup.ID, text, matchType}]
+}
+
+// FindNegativeKeyword returns the negative keyword with the text and match type of the campaign named campaignName,
+// or of its ad group named adGroupName when that is not empty, or nil.
+func (g *OrgGraph) FindNegativeKeyword(campaignName string, adGroupName string, text string, matchType KeywordMatchType) *NegativeKeyword {
+	campaign := g.FindCampaign(campaignName)
+	if campaign == nil {
+		return nil
+	}
+
+	key := keywordTextKey{campaignID: campaign.Campaign.ID, text: text, matchType: matchType}
+
+	if adGroupName != "" {
+		adGroup := g.adGroupsByName[adGroupNameKey{campaign.Campaign.ID, adGroupName}]
+		if adGroup == nil {
+			return nil
+		}
+
+		key.adGroupID = adGroup.AdGroup.ID
+	}
+
+	return g.negativesByText[key]
+}
+
+// AdGroupCampaign returns the campaign of the ad group with the ID, or nil.
+func (g *OrgGraph) AdGroupCampaign(adGroupID int64) *CampaignNode {
+	return g.adGroupCampaigns[adGroupID]
+}
+
+// KeywordAdGroup returns the ad group of the targeting keyword with the ID, or nil.
+func (g *OrgGraph) KeywordAdGroup(keywordID int64) *AdGroupNode {
+	return g.keywordAdGroups[keywordID]
+}
+
+// NegativeKeywordParent returns the campaign of the negative keyword with the ID, and its ad group when it is an ad
+// group negative keyword. Both are nil when the keyword is not in the graph.
+func (g *OrgGraph) NegativeKeywordParent(keywordID int64) (*CampaignNode, *AdGroupNode) {
+	return g.negativeCampaigns[keywordID], g.negativeAdGroups[keywordID]
+}
+
+// AdGroupCreativeSetAdGroup returns the ad group of the Creative Set assignment with the ID, or nil.
+func (g *OrgGraph) AdGroupCreativeSetAdGroup(adGroupCreativeSetID int64) *AdGroupNode {
+	return g.creativeSetGroups[adGroupCreativeSetID]
+}
+
+// CampaignBudgetOrders returns the budget orders in the graph that the campaign with the ID spends from.
+func (g *OrgGraph) CampaignBudgetOrders(campaignID int64) []*BudgetOrder {
+	campaign := g.Campaign(campaignID)
+	if campaign == nil {
+		return nil
+	}
+
+	var orders []*BudgetOrder
+
+	for _, orderID := range campaign.Campaign.BudgetOrders {
+		if order, ok := g.budgetOrders[orderID]; ok {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders
+}
+
+// BudgetOrderCampaigns returns the campaigns spending from the budget order with the ID.
+func (g *OrgGraph) BudgetOrderCampaigns(budgetOrderID int64) []*CampaignNode {
+	return g.budgetOrderTargets[budgetOrderID]
+}
+
+// replaceCampaign puts node in place of the campaign with the ID, appends it when the campaign is new or removes the
+// campaign when node is nil.
+func (g *OrgGraph) replaceCampaign(campaignID int64, node *CampaignNode) {
+	campaigns := g.Campaigns[:0:0]
+	replaced := false
+
+	for _, campaign := range g.Campaigns {
+		if campaign.Campaign.ID != campaignID {
+			campaigns = append(campaigns, campaign)
+		} else if node != nil && !replaced {
+			campaigns = append(campaigns, node)
+			replaced = true
+		}
+	}
+
+	if node != nil && !replaced {
+		campaigns = append(campaigns, node)
+	}
+
+	g.Campaigns = campaigns
+	g.reindex()
+}
+
+// replaceAdGroup is replaceCampaign for the ad groups of campaign.
+func (g *OrgGraph) replaceAdGroup(campaign *CampaignNode, adGroupID int64, node *AdGroupNode) {
+	adGroups := campaign.AdGroups[:0:0]
+	replaced := false
+
+	for _, adGroup := range campaign.AdGroups {
+		if adGroup.AdGroup.ID != adGroupID {
+			adGroups = append(adGroups, adGroup)
+		} else if node != nil && !replaced {
+			adGroups = append(adGroups, node)
+			replaced = true
+		}
+	}
+
+	if node != nil && !replaced {
+		adGroups = append(adGroups, node)
+	}
+
+	campaign.AdGroups = adGroups
+	g.reindex()
+}
+
+// LoadOrgGraph loads the Hierarchy and the budget orders of the organization into an OrgGraph. Like Load, it only
+// returns an error when the campaigns or the budget orders cannot be listed or ctx ends; failed branches are reported
+// by the Errors and Err of the graph.
+func (l *HierarchyLoader) LoadOrgGraph(ctx context.Context) (*OrgGraph, error) {
+	load := l.newLoad()
+
+	var orders []*BudgetOrder
+
+	err := load.pages(ctx, func(offset int) (int, *PageDetail, error) {
+		res, _, err := l.client.Budget.GetAllBudgetOrders(ctx, &GetAllBudgetOrdersQuery{Limit: findPageLimit, Offset: int32(offset)})
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, info := range res.BudgetOrderInfos {
+			if info.Bo != nil {
+				orders = append(orders, info.Bo)
+			}
+		}
+
+		return len(res.BudgetOrderInfos), res.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing budget orders: %w", err)
+	}
+
+	hierarchy, err := l.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOrgGraph(hierarchy, orders), nil
+}
+
+// RefreshCampaign reloads a campaign of graph with its branches, adding it when it is new and removing it when it is
+// deleted. The graph is left unchanged when any branch fails to load.
+func (l *HierarchyLoader) RefreshCampaign(ctx context.Context, graph *OrgGraph, campaignID int64) error {
+	load := l.newLoad()
+
+	var campaign *Campaign
+
+	err := load.request(ctx, func() error {
+		res, _, err := l.client.Campaigns.GetCampaign(ctx, campaignID)
+		campaign = res.Campaign
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if campaign == nil || campaign.Deleted {
+		graph.replaceCampaign(campaignID, nil)
+
+		return nil
+	}
+
+	node := &CampaignNode{Campaign: campaign}
+	load.spawn(func() { load.loadCampaign(ctx, node) })
+	load.wait()
+
+	if err := load.hierarchy.Err(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	graph.replaceCampaign(campaignID, node)
+
+	return nil
+}
+
+// RefreshAdGroup reloads an ad group of graph with its branches, adding it to its campaign when it is new and removing
+// it when it is deleted. The campaign must be in the graph. The graph is left unchanged when any branch fails to load.
+func (l *HierarchyLoader) RefreshAdGroup(ctx context.Context, graph *OrgGraph, campaignID int64, adGroupID int64) error {
+	campaign := graph.Campaign(campaignID)
+	if campaign == nil {
+		return fmt.Errorf("%w: campaign %d", ErrNotInGraph, campaignID)
+	}
+
+	load := l.newLoad()
+
+	var adGroup *AdGroup
+
+	err := load.request(ctx, func() error {
+		res, _, err := l.client.AdGroups.GetAdGroup(ctx, campaignID, adGroupID)
+		adGroup = res.AdGroup
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if adGroup == nil || adGroup.Deleted {
+		graph.replaceAdGroup(campaign, adGroupID, nil)
+
+		return nil
+	}
+
+	node := &AdGroupNode{AdGroup: adGroup}
+	load.loadAdGroup(ctx, campaignID, node)
+	load.wait()
+
+	if err := load.hierarchy.Err(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	graph.replaceAdGroup(campaign, adGroupID, node)
+
+	return nil
+}
@@ -0,0 +1,185 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orgGraphTestRoutes() map[string]string {
+	routes := hierarchyTestRoutes()
+	routes["GET /campaigns"] = `{"data":[{"id":1,"name":"Brand","budgetOrders":[7]},{"id":3,"name":"Broken"}],"pagination":{"totalResults":2,"startIndex":0,"itemsPerPage":2}}`
+	routes["GET /campaigns/1/adgroups/10/targetingkeywords/"] = `{"data":[{"id":1000,"text":"photo","matchType":"Exact"},{"id":1001,"text":"photo","matchType":"Broad","deleted":true}]}`
+	routes["GET /campaigns/1/adgroups/10/negativekeywords/"] = `{"data":[{"id":1100,"text":"cheap","matchType":"Exact"}]}`
+	routes["GET /campaigns/1/adgroups/11/targetingkeywords/"] = `{"data":[]}`
+	routes["GET /campaigns/1/negativekeywords/"] = `{"data":[{"id":100,"text":"free","matchType":"Broad"}]}`
+	routes["GET /budgetorders"] = `{"data":[{"bo":{"id":7,"name":"Q3"}},{"bo":{"id":8,"name":"Q4"}}],"pagination":{"totalResults":2,"startIndex":0,"itemsPerPage":2}}`
+
+	return routes
+}
+
+func assertOrgGraphIndexes(t *testing.T, graph *OrgGraph) {
+	t.Helper()
+
+	brand := graph.FindCampaign("Brand")
+	require.NotNil(t, brand)
+	assert.Same(t, brand, graph.Campaign(1))
+
+	exact := graph.FindAdGroup("Brand", "Exact")
+	require.NotNil(t, exact)
+	assert.Same(t, exact, graph.AdGroup(10))
+	assert.Same(t, brand, graph.AdGroupCampaign(10))
+	assert.Nil(t, graph.FindAdGroup("Broken", "Exact"))
+	assert.Nil(t, graph.FindAdGroup("Missing", "Exact"))
+
+	keyword := graph.FindKeyword("Brand", "Exact", "photo", KeywordMatchTypeExact)
+	require.NotNil(t, keyword)
+	assert.Equal(t, int64(1000), keyword.ID)
+	assert.Same(t, exact, graph.KeywordAdGroup(1000))
+	assert.Nil(t, graph.FindKeyword("Brand", "Exact", "photo", KeywordMatchTypeBroad), "deleted keywords are not found by text")
+	assert.NotNil(t, graph.Keyword(1001))
+
+	campaignNegative := graph.FindNegativeKeyword("Brand", "", "free", KeywordMatchTypeBroad)
+	require.NotNil(t, campaignNegative)
+	campaign, adGroup := graph.NegativeKeywordParent(campaignNegative.ID)
+	assert.Same(t, brand, campaign)
+	assert.Nil(t, adGroup)
+
+	adGroupNegative := graph.FindNegativeKeyword("Brand", "Exact", "cheap", KeywordMatchTypeExact)
+	require.NotNil(t, adGroupNegative)
+	assert.Same(t, adGroupNegative, graph.NegativeKeyword(1100))
+	campaign, adGroup = graph.NegativeKeywordParent(1100)
+	assert.Same(t, brand, campaign)
+	assert.Same(t, exact, adGroup)
+	assert.Nil(t, graph.FindNegativeKeyword("Brand", "", "cheap", KeywordMatchTypeExact))
+
+	require.NotNil(t, graph.AdGroupCreativeSet(500))
+	// the test server lists the same Creative Set for both ad groups
+	assert.NotNil(t, graph.AdGroupCreativeSetAdGroup(500))
+
+	assert.Equal(t, "Q3", graph.BudgetOrder(7).Name)
+	require.Len(t, graph.CampaignBudgetOrders(1), 1)
+	assert.Equal(t, int64(7), graph.CampaignBudgetOrders(1)[0].ID)
+	assert.Equal(t, []*CampaignNode{brand}, graph.BudgetOrderCampaigns(7))
+	assert.Empty(t, graph.BudgetOrderCampaigns(8))
+}
+
+func TestLoadOrgGraph(t *testing.T) {
+	t.Parallel()
+
+	client, server, _ := newConcurrencyServer(orgGraphTestRoutes())
+	defer server.Close()
+
+	graph, err := NewHierarchyLoader(client).LoadOrgGraph(context.Background())
+	require.NoError(t, err)
+	assertOrgGraphIndexes(t, graph)
+
+	assert.ErrorIs(t, graph.Err(), ErrPartialHierarchy)
+	require.Len(t, graph.Errors, 1)
+	assert.Equal(t, int64(3), graph.Errors[0].CampaignID)
+}
+
+func TestLoadOrgGraphBudgetOrdersFailure(t *testing.T) {
+	t.Parallel()
+
+	routes := orgGraphTestRoutes()
+	delete(routes, "GET /budgetorders")
+
+	client, server, _ := newConcurrencyServer(routes)
+	defer server.Close()
+
+	_, err := NewHierarchyLoader(client).LoadOrgGraph(context.Background())
+	assert.Error(t, err)
+}
+
+func TestOrgGraphJSON(t *testing.T) {
+	t.Parallel()
+
+	client, server, _ := newConcurrencyServer(orgGraphTestRoutes())
+	defer server.Close()
+
+	graph, err := NewHierarchyLoader(client).LoadOrgGraph(context.Background())
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(graph)
+	require.NoError(t, err)
+
+	var decoded OrgGraph
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assertOrgGraphIndexes(t, &decoded)
+	assert.True(t, graph.LoadedAt.Equal(decoded.LoadedAt))
+	assert.Empty(t, decoded.Errors)
+
+	var empty OrgGraph
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &empty))
+	assert.Nil(t, empty.FindCampaign("Brand"))
+	assert.NoError(t, empty.Err())
+}
+
+func TestOrgGraphRefresh(t *testing.T) {
+	t.Parallel()
+
+	graph := NewOrgGraph(&Hierarchy{Campaigns: []*CampaignNode{
+		{Campaign: &Campaign{ID: 1, Name: "Brand"}, AdGroups: []*AdGroupNode{
+			{AdGroup: &AdGroup{ID: 10, Name: "Exact"}},
+			{AdGroup: &AdGroup{ID: 11, Name: "Broad"}},
+		}},
+		{Campaign: &Campaign{ID: 2, Name: "Old"}},
+	}}, nil)
+
+	routes := orgGraphTestRoutes()
+	routes["GET /campaigns/1/adgroups/10"] = `{"data":{"id":10,"name":"Exact match"}}`
+	routes["GET /campaigns/1/adgroups/11"] = `{"data":{"id":11,"name":"Broad","deleted":true}}`
+	routes["GET /campaigns/2"] = `{"data":{"id":2,"name":"Old","deleted":true}}`
+	routes["GET /campaigns/3"] = `{"data":{"id":3,"name":"Broken"}}`
+	routes["GET /campaigns/1"] = `{"data":{"id":1,"name":"Brand","budgetOrders":[7]}}`
+
+	client, server, _ := newConcurrencyServer(routes)
+	defer server.Close()
+
+	loader := NewHierarchyLoader(client)
+	ctx := context.Background()
+
+	require.NoError(t, loader.RefreshAdGroup(ctx, graph, 1, 10))
+	assert.Nil(t, graph.FindAdGroup("Brand", "Exact"))
+	assert.NotNil(t, graph.FindKeyword("Brand", "Exact match", "photo", KeywordMatchTypeExact))
+	assert.Equal(t, "Exact match", graph.Campaign(1).AdGroups[0].AdGroup.Name, "refreshed ad groups keep their place")
+
+	require.NoError(t, loader.RefreshAdGroup(ctx, graph, 1, 11))
+	assert.Nil(t, graph.AdGroup(11))
+	assert.Len(t, graph.Campaign(1).AdGroups, 1)
+
+	assert.ErrorIs(t, loader.RefreshAdGroup(ctx, graph, 9, 90), ErrNotInGraph)
+
+	require.NoError(t, loader.RefreshCampaign(ctx, graph, 2))
+	assert.Nil(t, graph.FindCampaign("Old"))
+
+	err := loader.RefreshCampaign(ctx, graph, 3)
+	assert.ErrorIs(t, err, ErrPartialHierarchy)
+	assert.Nil(t, graph.Campaign(3), "a failed refresh leaves the graph unchanged")
+
+	require.NoError(t, loader.RefreshCampaign(ctx, graph, 1))
+	assert.Len(t, graph.Campaign(1).AdGroups, 2)
+	assert.NotNil(t, graph.FindAdGroup("Brand", "Exact"))
+	assert.NotNil(t, graph.FindNegativeKeyword("Brand", "", "free", KeywordMatchTypeBroad))
+	assert.Len(t, graph.Campaigns, 1)
+}
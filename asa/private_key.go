@@ -0,0 +1,282 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	pemTypeECPrivateKey        = "EC PRIVATE KEY"
+	pemTypePrivateKey          = "PRIVATE KEY"
+	pemTypeEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemTypeECParameters        = "EC PARAMETERS"
+	maxPBKDF2Iterations        = 10000000
+	oidPBES2                   = "1.2.840.113549.1.5.13"
+	oidPBKDF2                  = "1.2.840.113549.1.5.12"
+	oidHMACWithSHA1            = "1.2.840.113549.2.7"
+	oidHMACWithSHA256          = "1.2.840.113549.2.9"
+)
+
+// ErrMissingPEM happens when the bytes cannot be decoded as a PEM block.
+var ErrMissingPEM = errors.New("no PEM blob found")
+
+// ErrInvalidPrivateKey happens when a key cannot be parsed as a ECDSA PKCS8 private key.
+var ErrInvalidPrivateKey = errors.New("key could not be parsed as a valid ecdsa.PrivateKey")
+
+// ErrUnsupportedCurve happens when a private key is not on the P-256 curve required by ES256.
+var ErrUnsupportedCurve = errors.New("private key must use the P-256 curve")
+
+// ErrPassphraseRequired happens when a private key is encrypted and no passphrase is given.
+var ErrPassphraseRequired = errors.New("private key is encrypted and requires a passphrase")
+
+// ErrIncorrectPassphrase happens when an encrypted private key cannot be decrypted with the given passphrase.
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase for the private key")
+
+// ErrUnsupportedKeyEncryption happens when a private key is encrypted with an unsupported algorithm.
+var ErrUnsupportedKeyEncryption = errors.New("unsupported private key encryption")
+
+// aesCBCKeyLengths returns the key length of the AES-CBC ciphers of PBES2 by object identifier.
+func aesCBCKeyLengths() map[string]int {
+	return map[string]int{
+		"2.16.840.1.101.3.4.1.2":  16,
+		"2.16.840.1.101.3.4.1.22": 24,
+		"2.16.840.1.101.3.4.1.42": 32,
+	}
+}
+
+// parsePrivateKey parses a PEM encoded P-256 private key in SEC 1 ("EC PRIVATE KEY") or PKCS#8 ("PRIVATE KEY")
+// form. Keys encrypted as PKCS#8 ("ENCRYPTED PRIVATE KEY", PBES2) or with legacy PEM encryption are decrypted with
+// passphrase. EC PARAMETERS blocks preceding the key are skipped.
+func parsePrivateKey(blob []byte, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	block, rest := pem.Decode(blob)
+	for block != nil && block.Type == pemTypeECParameters {
+		block, rest = pem.Decode(rest)
+	}
+
+	if block == nil {
+		return nil, ErrMissingPEM
+	}
+
+	der := block.Bytes
+	blockType := block.Type
+
+	switch {
+	case blockType == pemTypeEncryptedPrivateKey:
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+
+		decrypted, err := decryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		der, blockType = decrypted, pemTypePrivateKey
+	case x509.IsEncryptedPEMBlock(block): // nolint: staticcheck
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+
+		decrypted, err := x509.DecryptPEMBlock(block, passphrase) // nolint: staticcheck
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIncorrectPassphrase
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyEncryption, err.Error())
+		}
+
+		der = decrypted
+	}
+
+	key, err := parsePrivateKeyDER(blockType, der)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCurve(&key.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func parsePrivateKeyDER(blockType string, der []byte) (*ecdsa.PrivateKey, error) {
+	switch blockType {
+	case pemTypeECPrivateKey:
+		key, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPrivateKey, err.Error())
+		}
+
+		return key, nil
+	case pemTypePrivateKey:
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPrivateKey, err.Error())
+		}
+
+		key, ok := parsed.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: PKCS#8 block holds a %T", ErrInvalidPrivateKey, parsed)
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidPrivateKey, blockType)
+	}
+}
+
+// checkCurve verifies that a public key can verify ES256 signatures.
+func checkCurve(public crypto.PublicKey) error {
+	key, ok := public.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: got a %T", ErrUnsupportedCurve, public)
+	}
+
+	if key.Curve != elliptic.P256() {
+		return fmt.Errorf("%w: got %s", ErrUnsupportedCurve, key.Curve.Params().Name)
+	}
+
+	return nil
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts a PKCS#8 EncryptedPrivateKeyInfo using PBES2 with PBKDF2 and AES-CBC, as written by
+// openssl pkcs8 -topk8.
+func decryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPrivateKey, err.Error())
+	}
+
+	if info.Algorithm.Algorithm.String() != oidPBES2 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyEncryption, info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyEncryption, err.Error())
+	}
+
+	if params.KeyDerivationFunc.Algorithm.String() != oidPBKDF2 {
+		return nil, fmt.Errorf("%w: key derivation %s", ErrUnsupportedKeyEncryption, params.KeyDerivationFunc.Algorithm)
+	}
+
+	keyLength, ok := aesCBCKeyLengths()[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: cipher %s", ErrUnsupportedKeyEncryption, params.EncryptionScheme.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyEncryption, err.Error())
+	}
+
+	// the iteration count comes from the file; an absurd one would keep the process busy for hours
+	if kdf.IterationCount <= 0 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("%w: PBKDF2 iteration count %d", ErrUnsupportedKeyEncryption, kdf.IterationCount)
+	}
+
+	var prf func() hash.Hash
+
+	switch kdf.PRF.Algorithm.String() {
+	case "", oidHMACWithSHA1:
+		prf = sha1.New
+	case oidHMACWithSHA256:
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("%w: PRF %s", ErrUnsupportedKeyEncryption, kdf.PRF.Algorithm)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyEncryption, err.Error())
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keyLength, prf))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != block.BlockSize() || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("%w: malformed AES-CBC data", ErrInvalidPrivateKey)
+	}
+
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	unpadded, ok := unpadPKCS7(decrypted, block.BlockSize())
+	if !ok {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	// a wrong passphrase passes the padding check now and then, but never yields a valid PKCS#8 structure
+	if _, err := x509.ParsePKCS8PrivateKey(unpadded); err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return unpadded, nil
+}
+
+func unpadPKCS7(data []byte, blockSize int) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, false
+	}
+
+	if !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, false
+	}
+
+	return data[:len(data)-padding], true
+}
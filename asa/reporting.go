@@ -18,9 +18,19 @@ package asa
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 )
 
+// ErrStopReport can be returned by a RowHandler to stop streaming a report early without failing.
+var ErrStopReport = errors.New("stop report")
+
+// ErrMalformedReport happens when a streamed report does not have the shape of a ReportingResponseBody.
+var ErrMalformedReport = errors.New("malformed report response")
+
 // ReportingService handles communication with build-related methods of the Apple Search Ads API
 //
 // https://developer.apple.com/documentation/apple_search_ads/reports
@@ -156,6 +166,7 @@ type CampaignAppDetail struct {
 type MetaDataObject struct {
 	AdGroupID                          int64                                       `json:"adGroupID,omitempty"`
 	AdGroupName                        string                                      `json:"adGroupName,omitempty"`
+	AdGroupStatus                      AdGroupStatus                               `json:"adGroupStatus,omitempty"`
 	CampaignID                         int64                                       `json:"campaignId,omitempty"`
 	CampaignName                       string                                      `json:"campaignName,omitempty"`
 	Deleted                            bool                                        `json:"deleted,omitempty"`
@@ -174,6 +185,10 @@ type MetaDataObject struct {
 	CountryOrRegionServingStateReasons *CampaignCountryOrRegionServingStateReasons `json:"countryOrRegionServingStateReasons,omitempty"`
 	BillingEvent                       string                                      `json:"billingEvent,omitempty"`
 	KeywordID                          int64                                       `json:"keywordID,omitempty"`
+	Keyword                            string                                      `json:"keyword,omitempty"`
+	KeywordStatus                      KeywordStatus                               `json:"keywordStatus,omitempty"`
+	BidAmount                          *Money                                      `json:"bidAmount,omitempty"`
+	DefaultCpcBid                      *Money                                      `json:"defaultCpcBid,omitempty"`
 	MatchType                          *ReportingKeywordMatchType                  `json:"matchType,omitempty"`
 	CountryOrRegion                    string                                      `json:"countryOrRegion,omitempty"`
 	SearchTermText                     *string                                     `json:"SearchTermText,omitempty"`
@@ -248,7 +263,7 @@ type KeywordBidRecommendation struct {
 func (s *ReportingService) GetCampaignLevelReports(ctx context.Context, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
 	url := "reports/campaigns"
 	res := new(ReportingResponseBody)
-	resp, err := s.client.post(ctx, url, &params, res)
+	resp, err := s.client.post(ctx, operationName{"Reporting", "GetCampaignLevelReports"}, url, params, res)
 
 	return res, resp, err
 }
@@ -259,7 +274,7 @@ func (s *ReportingService) GetCampaignLevelReports(ctx context.Context, params *
 func (s *ReportingService) GetAdGroupLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
 	url := fmt.Sprintf("reports/campaigns/%d/adgroups", campaignID)
 	res := new(ReportingResponseBody)
-	resp, err := s.client.post(ctx, url, &params, res)
+	resp, err := s.client.post(ctx, operationName{"Reporting", "GetAdGroupLevelReports"}, url, params, res)
 
 	return res, resp, err
 }
@@ -270,7 +285,7 @@ func (s *ReportingService) GetAdGroupLevelReports(ctx context.Context, campaignI
 func (s *ReportingService) GetKeywordLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
 	url := fmt.Sprintf("reports/campaigns/%d/keywords", campaignID)
 	res := new(ReportingResponseBody)
-	resp, err := s.client.post(ctx, url, &params, res)
+	resp, err := s.client.post(ctx, operationName{"Reporting", "GetKeywordLevelReports"}, url, params, res)
 
 	return res, resp, err
 }
@@ -281,7 +296,7 @@ func (s *ReportingService) GetKeywordLevelReports(ctx context.Context, campaignI
 func (s *ReportingService) GetSearchTermLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
 	url := fmt.Sprintf("reports/campaigns/%d/searchterms", campaignID)
 	res := new(ReportingResponseBody)
-	resp, err := s.client.post(ctx, url, &params, res)
+	resp, err := s.client.post(ctx, operationName{"Reporting", "GetSearchTermLevelReports"}, url, params, res)
 
 	return res, resp, err
 }
@@ -292,7 +307,236 @@ func (s *ReportingService) GetSearchTermLevelReports(ctx context.Context, campai
 func (s *ReportingService) GetCreativeSetLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
 	url := fmt.Sprintf("reports/campaigns/%d/creativesets", campaignID)
 	res := new(ReportingResponseBody)
-	resp, err := s.client.post(ctx, url, &params, res)
+	resp, err := s.client.post(ctx, operationName{"Reporting", "GetCreativeSetLevelReports"}, url, params, res)
 
 	return res, resp, err
 }
+
+// reportFetcher fetches a single page of a report.
+type reportFetcher func(ctx context.Context, params *ReportingRequest) (*ReportingResponseBody, *Response, error)
+
+// fetchAllReportRows pages through a report using the pagination of the request selector and returns every row.
+func fetchAllReportRows(ctx context.Context, params *ReportingRequest, fetch reportFetcher) ([]Row, error) {
+	var rows []Row
+
+	for offset := 0; ; {
+		paged := *params
+		paged.Selector = pagedSelector(params.Selector, offset)
+
+		res, _, err := fetch(ctx, &paged)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []Row
+		if res.ReportingCampaign != nil && res.ReportingCampaign.ReportingDataResponse != nil {
+			page = res.ReportingCampaign.ReportingDataResponse.Rows
+		}
+
+		rows = append(rows, page...)
+
+		if !hasNextPage(res.Pagination, offset, len(page)) {
+			return rows, nil
+		}
+
+		offset += len(page)
+	}
+}
+
+// RowHandler receives the rows of a streamed report one at a time. Returning ErrStopReport stops the stream without
+// error, any other error stops it and is returned by the stream method.
+type RowHandler func(row *Row) error
+
+// ReportSummary holds the parts of a streamed report other than its rows.
+type ReportSummary struct {
+	// Rows is the number of rows passed to the RowHandler.
+	Rows        int
+	GrandTotals *GrandTotalsRow
+	Pagination  *PageDetail
+	Error       *ErrorResponseBody
+}
+
+// StreamCampaignLevelReports is like GetCampaignLevelReports, but decodes the rows one at a time and passes them to fn,
+// so that memory use does not grow with the size of the report.
+func (s *ReportingService) StreamCampaignLevelReports(ctx context.Context, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	return s.streamReport(ctx, operationName{"Reporting", "StreamCampaignLevelReports"}, "reports/campaigns", params, fn)
+}
+
+// StreamAdGroupLevelReports is like GetAdGroupLevelReports, but decodes the rows one at a time and passes them to fn.
+func (s *ReportingService) StreamAdGroupLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	url := fmt.Sprintf("reports/campaigns/%d/adgroups", campaignID)
+
+	return s.streamReport(ctx, operationName{"Reporting", "StreamAdGroupLevelReports"}, url, params, fn)
+}
+
+// StreamKeywordLevelReports is like GetKeywordLevelReports, but decodes the rows one at a time and passes them to fn.
+func (s *ReportingService) StreamKeywordLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	url := fmt.Sprintf("reports/campaigns/%d/keywords", campaignID)
+
+	return s.streamReport(ctx, operationName{"Reporting", "StreamKeywordLevelReports"}, url, params, fn)
+}
+
+// StreamSearchTermLevelReports is like GetSearchTermLevelReports, but decodes the rows one at a time and passes them
+// to fn.
+func (s *ReportingService) StreamSearchTermLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	url := fmt.Sprintf("reports/campaigns/%d/searchterms", campaignID)
+
+	return s.streamReport(ctx, operationName{"Reporting", "StreamSearchTermLevelReports"}, url, params, fn)
+}
+
+// StreamCreativeSetLevelReports is like GetCreativeSetLevelReports, but decodes the rows one at a time and passes them
+// to fn.
+func (s *ReportingService) StreamCreativeSetLevelReports(ctx context.Context, campaignID int64, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	url := fmt.Sprintf("reports/campaigns/%d/creativesets", campaignID)
+
+	return s.streamReport(ctx, operationName{"Reporting", "StreamCreativeSetLevelReports"}, url, params, fn)
+}
+
+func (s *ReportingService) streamReport(ctx context.Context, name operationName, url string, params *ReportingRequest, fn RowHandler) (*ReportSummary, *Response, error) {
+	op := name.operation(http.MethodPost, url, nil, params, rawResult{})
+
+	resp, err := s.client.call(ctx, op, withContentType("application/json"))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	// middleware may answer the operation without a body
+	if resp == nil || resp.Response == nil || resp.Body == nil {
+		return &ReportSummary{}, resp, nil
+	}
+
+	defer closeDesc(resp.Body)
+
+	summary, err := decodeReportStream(ctx, resp.Body, fn)
+
+	return summary, resp, err
+}
+
+// decodeReportStream decodes a ReportingResponseBody from r, passing the rows to fn as they are read instead of
+// collecting them.
+func decodeReportStream(ctx context.Context, r io.Reader, fn RowHandler) (*ReportSummary, error) {
+	dec := json.NewDecoder(r)
+	summary := &ReportSummary{}
+
+	onRow := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row := &Row{}
+		if err := dec.Decode(row); err != nil {
+			return err
+		}
+
+		summary.Rows++
+
+		return fn(row)
+	}
+
+	onDataResponse := func(key string) error {
+		switch key {
+		case "row":
+			return decodeArray(dec, onRow)
+		case "grandTotals":
+			return dec.Decode(&summary.GrandTotals)
+		default:
+			return skipValue(dec)
+		}
+	}
+
+	onData := func(key string) error {
+		if key == "reportingDataResponse" {
+			return decodeObject(dec, onDataResponse)
+		}
+
+		return skipValue(dec)
+	}
+
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "data":
+			return decodeObject(dec, onData)
+		case "pagination":
+			return dec.Decode(&summary.Pagination)
+		case "error":
+			return dec.Decode(&summary.Error)
+		default:
+			return skipValue(dec)
+		}
+	})
+	if errors.Is(err, ErrStopReport) {
+		return summary, nil
+	}
+
+	return summary, err
+}
+
+// decodeObject reads the JSON object at the head of dec, calling member with each key so that it decodes the value.
+// A null value is read as an empty object.
+func decodeObject(dec *json.Decoder, member func(key string) error) error {
+	if open, err := openDelim(dec, '{'); err != nil || !open {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("%w: unexpected %v", ErrMalformedReport, token)
+		}
+
+		if err := member(key); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token()
+
+	return err
+}
+
+// decodeArray reads the JSON array at the head of dec, calling element for each value so that it decodes it. A null
+// value is read as an empty array.
+func decodeArray(dec *json.Decoder, element func() error) error {
+	if open, err := openDelim(dec, '['); err != nil || !open {
+		return err
+	}
+
+	for dec.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token()
+
+	return err
+}
+
+// openDelim reads the opening delimiter of an object or array, and reports false when the value is null instead.
+func openDelim(dec *json.Decoder, delim json.Delim) (bool, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+
+	if token == nil {
+		return false, nil
+	}
+
+	if token != delim {
+		return false, fmt.Errorf("%w: expected %v, got %v", ErrMalformedReport, delim, token)
+	}
+
+	return true, nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+
+	return dec.Decode(&raw)
+}
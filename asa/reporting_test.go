@@ -19,7 +19,9 @@ package asa
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 	"testing"
 	"time"
@@ -324,3 +326,122 @@ func TestSearchTermLevelReportsRequestSerialization(t *testing.T) {
 
 	require.JSONEq(t, string(val1), string(val2))
 }
+
+func TestStreamReportsMatchBufferedDecode(t *testing.T) {
+	t.Parallel()
+
+	fixtures := []string{
+		"get_campaign_level_reports.json",
+		"get_campaign_level_reports_with_granularity.json",
+		"get_ad_group_level_reports.json",
+		"get_keyword_level_reports.json",
+		"get_search_term_level_reports.json",
+		"reporting_response_body_with_empty.json",
+	}
+
+	for _, fixture := range fixtures {
+		path := filepath.Join("../test/response_body_json_files", fixture)
+		want := deserializeFileToReportingResponse(t, path)
+
+		content, err := ioutil.ReadFile(filepath.Clean(path))
+		require.NoError(t, err)
+
+		client, server := newServer(string(content), http.StatusOK, false)
+
+		var rows []Row
+
+		summary, _, err := client.Reporting.StreamKeywordLevelReports(context.Background(), 1, &ReportingRequest{}, func(row *Row) error {
+			rows = append(rows, *row)
+
+			return nil
+		})
+		server.Close()
+
+		require.NoError(t, err, fixture)
+		assert.Equal(t, want.ReportingCampaign.ReportingDataResponse.Rows, rowsOrEmpty(rows), fixture)
+		assert.Equal(t, len(rows), summary.Rows, fixture)
+		assert.Equal(t, want.ReportingCampaign.ReportingDataResponse.GrandTotals, summary.GrandTotals, fixture)
+		assert.Equal(t, want.Pagination, summary.Pagination, fixture)
+	}
+}
+
+func rowsOrEmpty(rows []Row) []Row {
+	if len(rows) == 0 {
+		return []Row{}
+	}
+
+	return rows
+}
+
+const streamedReport = `{"data":{"reportingDataResponse":{"row":[
+	{"metadata":{"campaignId":1}},
+	{"metadata":{"campaignId":2}},
+	{"metadata":{"campaignId":3}}
+],"grandTotals":{"other":false}}},"pagination":{"totalResults":3}}`
+
+func TestStreamReportStopsEarly(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(streamedReport, http.StatusOK, false)
+	defer server.Close()
+
+	var seen []int64
+
+	summary, _, err := client.Reporting.StreamCampaignLevelReports(context.Background(), &ReportingRequest{}, func(row *Row) error {
+		seen = append(seen, row.Metadata.CampaignID)
+		if len(seen) == 2 {
+			return ErrStopReport
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, seen)
+	assert.Equal(t, 2, summary.Rows)
+	assert.Nil(t, summary.GrandTotals)
+
+	errHandler := errors.New("handler failed")
+	_, _, err = client.Reporting.StreamCampaignLevelReports(context.Background(), &ReportingRequest{}, func(row *Row) error {
+		return errHandler
+	})
+	assert.ErrorIs(t, err, errHandler)
+}
+
+func TestStreamReportContextCancel(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(streamedReport, http.StatusOK, false)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary, _, err := client.Reporting.StreamAdGroupLevelReports(ctx, 1, &ReportingRequest{}, func(row *Row) error {
+		cancel()
+
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, summary.Rows)
+}
+
+func TestStreamReportErrors(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(`{"data":{"reportingDataResponse":{"row":{"metadata":{}}}}}`, http.StatusOK, false)
+	defer server.Close()
+
+	_, _, err := client.Reporting.StreamSearchTermLevelReports(context.Background(), 1, &ReportingRequest{}, func(row *Row) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrMalformedReport)
+
+	failing, failingServer := newServer(`{"error":{"errors":[{"messageCode":"INVALID_INPUT"}]}}`, http.StatusBadRequest, false)
+	defer failingServer.Close()
+
+	_, resp, err := failing.Reporting.StreamCreativeSetLevelReports(context.Background(), 1, &ReportingRequest{}, func(row *Row) error {
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,107 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+var (
+	errTransientStatus   = errors.New("transient response status")
+	errBodyNotRewindable = errors.New("request body cannot be sent again")
+)
+
+const (
+	defaultMaxRetries      = 3
+	defaultInitialInterval = 500 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+)
+
+// RetryPolicy controls how transient failures are retried with exponential backoff.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// InitialInterval is the delay before the first retry, growing exponentially up to MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// RetryNonIdempotent also retries non-idempotent requests, such as creates, after network errors and 5xx
+	// statuses. A request that failed after the server applied it is then applied twice.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the policy retrying three times, starting after half a second.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:      defaultMaxRetries,
+		InitialInterval: defaultInitialInterval,
+		MaxInterval:     defaultMaxInterval,
+	}
+}
+
+// backOff returns the backoff of the policy, stopping when ctx is done.
+func (p RetryPolicy) backOff(ctx context.Context) backoff.BackOff {
+	exponential := backoff.NewExponentialBackOff()
+	exponential.MaxElapsedTime = 0
+
+	if p.InitialInterval > 0 {
+		exponential.InitialInterval = p.InitialInterval
+	}
+
+	if p.MaxInterval > 0 {
+		exponential.MaxInterval = p.MaxInterval
+	}
+
+	retries := 0
+	if p.MaxRetries > 0 {
+		retries = p.MaxRetries
+	}
+
+	return backoff.WithContext(backoff.WithMaxRetries(exponential, uint64(retries)), ctx)
+}
+
+type noRetryKey struct{}
+
+// withoutRetries marks ctx so that requests made with it are sent once, whatever the retry policy of the client. It is
+// used by callers that must inspect a failure before trying again, such as the IdempotentCreator.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// retryPolicy returns the retry policy of the client for requests made with ctx.
+func (c *Client) retryPolicy(ctx context.Context) RetryPolicy {
+	if noRetry, _ := ctx.Value(noRetryKey{}).(bool); noRetry {
+		return RetryPolicy{}
+	}
+
+	return c.retry
+}
+
+// retryable reports whether a request may be sent again after a network error or a 5xx status. Every method but POST
+// is idempotent, as are the POSTs that only read data; other POSTs are only retried when the policy opts in.
+func (p RetryPolicy) retryable(method string, path string) bool {
+	return p.RetryNonIdempotent || method != http.MethodPost || !isMutatingRequest(method, path)
+}
+
+// isTransientStatus reports whether a response status is worth retrying.
+func isTransientStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
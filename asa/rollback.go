@@ -0,0 +1,602 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RollbackStatus is the outcome of rolling back the recorded changes of an entity.
+type RollbackStatus string
+
+const (
+	// RollbackStatusRestored is for an entity restored to its state before the recorded changes.
+	RollbackStatusRestored RollbackStatus = "RESTORED"
+	// RollbackStatusUnchanged is for an entity already in its state before the recorded changes.
+	RollbackStatusUnchanged RollbackStatus = "UNCHANGED"
+	// RollbackStatusConflict is for an entity changed again since the recorded changes, left as is.
+	RollbackStatusConflict RollbackStatus = "CONFLICT"
+	// RollbackStatusUnsupported is for changes that cannot be undone, such as creates and campaign deletes.
+	RollbackStatusUnsupported RollbackStatus = "UNSUPPORTED"
+	// RollbackStatusFailed is for an entity whose current state could not be read or restored.
+	RollbackStatusFailed RollbackStatus = "FAILED"
+)
+
+type rollbackKind int
+
+const (
+	rollbackCampaign rollbackKind = iota
+	rollbackAdGroup
+	rollbackTargetingKeyword
+	rollbackNegativeKeyword
+	rollbackAdGroupNegativeKeyword
+	rollbackCreativeSet
+)
+
+// RollbackChange is the rollback of an entity changed by one or more audit records.
+type RollbackChange struct {
+	// Path is the path of the entity, e.g. "campaigns/1/adgroups/2", or of the call for unsupported records.
+	Path      string         `json:"path"`
+	RecordIDs []string       `json:"recordIds"`
+	Status    RollbackStatus `json:"status"`
+	Reason    string         `json:"reason,omitempty"`
+
+	kind    rollbackKind
+	ids     map[string]int64
+	before  json.RawMessage
+	after   json.RawMessage
+	request json.RawMessage
+	deleted bool
+	// update is the inverse operation computed from the current state, applied by the Rollback.
+	update interface{}
+}
+
+// String returns a one-line description of the change.
+func (c *RollbackChange) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s %s", c.Status, c.Path)
+	}
+
+	return fmt.Sprintf("%s %s: %s", c.Status, c.Path, c.Reason)
+}
+
+func (c *RollbackChange) fail(status RollbackStatus, reason string) {
+	c.Status, c.Reason, c.update = status, reason, nil
+}
+
+func (c *RollbackChange) setResult(err error) {
+	if err != nil {
+		c.fail(RollbackStatusFailed, err.Error())
+
+		return
+	}
+
+	c.Status, c.update = RollbackStatusRestored, nil
+}
+
+// RollbackRun is the outcome of a Rollback.
+type RollbackRun struct {
+	StartedAt time.Time         `json:"startedAt"`
+	Changes   []*RollbackChange `json:"changes,omitempty"`
+}
+
+// String returns a human-readable report of the run.
+func (r *RollbackRun) String() string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf("Rollback at %s: %d entities\n", r.StartedAt.Format(time.RFC3339), len(r.Changes)))
+
+	for _, change := range r.Changes {
+		b.WriteString("  " + change.String() + "\n")
+	}
+
+	return b.String()
+}
+
+// Rollback undoes the changes journaled by an Auditor: it restores previous bids, statuses and names of campaigns,
+// ad groups, keywords and creative sets, and recreates deleted negative keywords. Each entity is restored once, to its
+// state before the earliest of the records, and is left as is when it was modified again since the latest of them.
+// Creates and deletes of campaigns and ad groups cannot be undone.
+type Rollback struct {
+	client *Client
+
+	// Force restores entities even when they were modified again since the recorded changes.
+	Force bool
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewRollback creates a Rollback applying the inverse operations with client. Use a client with a DryRun to plan a
+// rollback without applying it.
+func NewRollback(client *Client) *Rollback {
+	return &Rollback{
+		client: client,
+		Now:    time.Now,
+	}
+}
+
+// Undo rolls back the changes of records, such as the journal of the last bid rule run. Records of failed calls and
+// dry runs are ignored, as they changed nothing. Failures to roll back an entity are recorded on its change and do not
+// stop the run; an error is only returned when ctx ends.
+func (r *Rollback) Undo(ctx context.Context, records []*AuditRecord) (*RollbackRun, error) {
+	run := &RollbackRun{StartedAt: r.Now(), Changes: collectRollbackChanges(records)}
+
+	for i := len(run.Changes) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return run, err
+		}
+
+		if change := run.Changes[i]; change.Status == "" {
+			r.prepare(ctx, change)
+		}
+	}
+
+	r.apply(ctx, run.Changes)
+
+	return run, ctx.Err()
+}
+
+// collectRollbackChanges merges the entities of records, in the order they were first changed.
+func collectRollbackChanges(records []*AuditRecord) []*RollbackChange {
+	var changes []*RollbackChange
+
+	byPath := make(map[string]*RollbackChange)
+
+	for _, record := range records {
+		if record.Error != "" || record.DryRun {
+			continue
+		}
+
+		if !rollbackSupported(record) {
+			changes = append(changes, &RollbackChange{
+				Path:      record.Path,
+				RecordIDs: []string{record.ID},
+				Status:    RollbackStatusUnsupported,
+				Reason:    record.Name() + " cannot be undone",
+			})
+
+			continue
+		}
+
+		paths, err := entityPaths(&Operation{Path: record.Path, Body: record.Request})
+		if err != nil {
+			changes = append(changes, &RollbackChange{Path: record.Path, RecordIDs: []string{record.ID}, Status: RollbackStatusFailed, Reason: err.Error()})
+
+			continue
+		}
+
+		after := responseEntities(record.Response)
+
+		for i, path := range paths {
+			change, ok := byPath[path]
+			if !ok {
+				change = &RollbackChange{Path: path, ids: pathIDs(path), kind: rollbackKindOf(path)}
+				byPath[path] = change
+				changes = append(changes, change)
+
+				if i < len(record.Before) {
+					change.before = record.Before[i]
+				}
+			}
+
+			change.RecordIDs = append(change.RecordIDs, record.ID)
+			change.after = after[change.ids[entityResource(path)]]
+			change.request = record.Request
+			change.deleted = strings.HasPrefix(record.Method, "Delete")
+		}
+	}
+
+	return changes
+}
+
+func rollbackSupported(record *AuditRecord) bool {
+	name := record.Name()
+	switch name {
+	case "Campaigns.DeleteCampaign", "AdGroups.DeleteAdGroup",
+		"CreativeSets.UpdateAdGroupCreativeSets", "CreativeSets.DeleteAdGroupCreativeSets":
+		return false
+	}
+
+	read, ok := auditReads()[name]
+
+	return ok && !read.creates
+}
+
+// prepare reads the current state of the entity of change, detects conflicts and computes the inverse operation.
+func (r *Rollback) prepare(ctx context.Context, change *RollbackChange) {
+	if len(change.before) == 0 {
+		change.fail(RollbackStatusFailed, "no state recorded before the change")
+
+		return
+	}
+
+	current, err := r.current(ctx, change)
+	if err != nil {
+		change.fail(RollbackStatusFailed, err.Error())
+
+		return
+	}
+
+	if !r.Force {
+		if reason := conflictReason(change, current); reason != "" {
+			change.fail(RollbackStatusConflict, reason)
+
+			return
+		}
+	}
+
+	if err := change.inverse(current); err != nil {
+		change.fail(RollbackStatusFailed, err.Error())
+
+		return
+	}
+
+	if change.update == nil {
+		change.Status = RollbackStatusUnchanged
+	}
+}
+
+// current returns the entity of change as the API returns it now, or nil when a deleted entity is not found.
+func (r *Rollback) current(ctx context.Context, change *RollbackChange) (json.RawMessage, error) {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	service, getMethod := change.kind.getMethod()
+
+	resp, err := r.client.get(ctx, operationName{service, getMethod}, change.Path, nil, &envelope)
+	if err != nil {
+		if change.deleted && resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return envelope.Data, nil
+}
+
+// conflictReason returns why the entity of change cannot be rolled back safely, or an empty string.
+func conflictReason(change *RollbackChange, current json.RawMessage) string {
+	var state struct {
+		Deleted          bool      `json:"deleted"`
+		ModificationTime *DateTime `json:"modificationTime"`
+	}
+
+	if change.deleted {
+		if current != nil && json.Unmarshal(current, &state) == nil && !state.Deleted {
+			return "recreated since it was deleted"
+		}
+
+		return ""
+	}
+
+	if len(change.after) == 0 {
+		return "no state recorded after the change"
+	}
+
+	var after struct {
+		ModificationTime *DateTime `json:"modificationTime"`
+	}
+
+	if err := json.Unmarshal(change.after, &after); err != nil {
+		return err.Error()
+	}
+
+	if err := json.Unmarshal(current, &state); err != nil {
+		return err.Error()
+	}
+
+	if after.ModificationTime != nil && state.ModificationTime != nil {
+		if !after.ModificationTime.Equal(state.ModificationTime.Time) {
+			return fmt.Sprintf("modified at %s, after the recorded change at %s",
+				state.ModificationTime.Format(time.RFC3339), after.ModificationTime.Format(time.RFC3339))
+		}
+
+		return ""
+	}
+
+	// entities without a modification time, such as creative sets, are compared on the fields of the request
+	if changed := changedFields(change.request, current); len(changed) > 0 {
+		return "modified since the recorded change: " + strings.Join(changed, ", ")
+	}
+
+	return ""
+}
+
+// changedFields returns the top-level fields of request holding another value in current.
+func changedFields(request json.RawMessage, current json.RawMessage) []string {
+	var sent, now map[string]json.RawMessage
+
+	if json.Unmarshal(request, &sent) != nil || json.Unmarshal(current, &now) != nil {
+		return nil
+	}
+
+	var changed []string
+
+	for field, value := range sent {
+		if string(now[field]) != string(value) {
+			changed = append(changed, field)
+		}
+	}
+
+	return changed
+}
+
+// inverse computes the operation turning current back into the state before the recorded changes.
+func (c *RollbackChange) inverse(current json.RawMessage) error {
+	switch c.kind {
+	case rollbackCampaign:
+		before, now := &Campaign{}, &Campaign{}
+		if err := decodeRollbackStates(c.before, before, current, now); err != nil {
+			return err
+		}
+
+		if update := DiffCampaign(now, before); update != nil {
+			c.update = &UpdateCampaignRequest{Campaign: update}
+		}
+	case rollbackAdGroup:
+		before, now := &AdGroup{}, &AdGroup{}
+		if err := decodeRollbackStates(c.before, before, current, now); err != nil {
+			return err
+		}
+
+		if update := DiffAdGroup(now, before); update != nil {
+			c.update = update
+		}
+	case rollbackTargetingKeyword:
+		before, now := &Keyword{}, &Keyword{}
+		if err := decodeRollbackStates(c.before, before, current, now); err != nil {
+			return err
+		}
+
+		if update := DiffKeyword(now, before); update != nil {
+			c.update = update
+		}
+	case rollbackNegativeKeyword, rollbackAdGroupNegativeKeyword:
+		return c.inverseNegativeKeyword(current)
+	case rollbackCreativeSet:
+		before, now := &CreativeSet{}, &CreativeSet{}
+		if err := decodeRollbackStates(c.before, before, current, now); err != nil {
+			return err
+		}
+
+		if before.Name != "" && before.Name != now.Name {
+			c.update = &CreativeSetUpdate{Name: before.Name}
+		}
+	}
+
+	return nil
+}
+
+func (c *RollbackChange) inverseNegativeKeyword(current json.RawMessage) error {
+	before, now := &NegativeKeyword{}, &NegativeKeyword{}
+	if err := decodeRollbackStates(c.before, before, current, now); err != nil {
+		return err
+	}
+
+	if c.deleted && (current == nil || now.Deleted) {
+		c.update = &NegativeKeyword{Text: before.Text, MatchType: before.MatchType, Status: before.Status}
+
+		return nil
+	}
+
+	if before.Status != "" && before.Status != now.Status {
+		c.update = &NegativeKeyword{ID: now.ID, Status: before.Status}
+	}
+
+	return nil
+}
+
+func decodeRollbackStates(before json.RawMessage, beforeEntity interface{}, current json.RawMessage, currentEntity interface{}) error {
+	if err := json.Unmarshal(before, beforeEntity); err != nil {
+		return err
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	return json.Unmarshal(current, currentEntity)
+}
+
+// rollbackBatchKey groups the keyword changes sent in the same bulk request.
+type rollbackBatchKey struct {
+	kind       rollbackKind
+	recreate   bool
+	campaignID int64
+	adGroupID  int64
+}
+
+// apply sends the inverse operations, in bulk for keywords of the same campaign or ad group.
+func (r *Rollback) apply(ctx context.Context, changes []*RollbackChange) {
+	var order []rollbackBatchKey
+
+	batches := make(map[rollbackBatchKey][]*RollbackChange)
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.update == nil {
+			continue
+		}
+
+		campaignID, adGroupID := change.ids["campaigns"], change.ids["adgroups"]
+
+		switch update := change.update.(type) {
+		case *UpdateCampaignRequest:
+			_, _, err := r.client.Campaigns.UpdateCampaign(ctx, campaignID, update)
+			change.setResult(err)
+		case *AdGroupUpdateRequest:
+			_, _, err := r.client.AdGroups.UpdateAdGroup(ctx, campaignID, adGroupID, update)
+			change.setResult(err)
+		case *CreativeSetUpdate:
+			_, _, err := r.client.CreativeSets.UpdateCreativeSets(ctx, change.ids["creativesets"], update)
+			change.setResult(err)
+		default:
+			key := rollbackBatchKey{kind: change.kind, recreate: change.deleted, campaignID: campaignID, adGroupID: adGroupID}
+			if _, ok := batches[key]; !ok {
+				order = append(order, key)
+			}
+
+			batches[key] = append(batches[key], change)
+		}
+	}
+
+	for _, key := range order {
+		batch := batches[key]
+		err := r.applyBatch(ctx, key, batch)
+
+		for _, change := range batch {
+			change.setResult(err)
+		}
+	}
+}
+
+func (r *Rollback) applyBatch(ctx context.Context, key rollbackBatchKey, batch []*RollbackChange) error {
+	if key.kind == rollbackTargetingKeyword {
+		updates := make([]*KeywordUpdateRequest, len(batch))
+		for i, change := range batch {
+			updates[i], _ = change.update.(*KeywordUpdateRequest)
+		}
+
+		_, _, err := r.client.Keywords.UpdateTargetingKeywords(ctx, key.campaignID, key.adGroupID, updates)
+
+		return err
+	}
+
+	keywords := make([]*NegativeKeyword, len(batch))
+	for i, change := range batch {
+		keywords[i], _ = change.update.(*NegativeKeyword)
+	}
+
+	var err error
+
+	switch {
+	case key.kind == rollbackNegativeKeyword && key.recreate:
+		_, _, err = r.client.Keywords.CreateNegativeKeywords(ctx, key.campaignID, keywords)
+	case key.kind == rollbackNegativeKeyword:
+		_, _, err = r.client.Keywords.UpdateNegativeKeywords(ctx, key.campaignID, keywords)
+	case key.recreate:
+		_, _, err = r.client.Keywords.CreateAdGroupNegativeKeywords(ctx, key.campaignID, key.adGroupID, keywords)
+	default:
+		_, _, err = r.client.Keywords.UpdateAdGroupNegativeKeywords(ctx, key.campaignID, key.adGroupID, keywords)
+	}
+
+	return err
+}
+
+// getMethod returns the service and the method reading an entity of the kind.
+func (k rollbackKind) getMethod() (string, string) {
+	switch k {
+	case rollbackCampaign:
+		return "Campaigns", "GetCampaign"
+	case rollbackAdGroup:
+		return "AdGroups", "GetAdGroup"
+	case rollbackTargetingKeyword:
+		return "Keywords", "GetTargetingKeyword"
+	case rollbackNegativeKeyword:
+		return "Keywords", "GetNegativeKeyword"
+	case rollbackAdGroupNegativeKeyword:
+		return "Keywords", "GetAdGroupNegativeKeyword"
+	case rollbackCreativeSet:
+		return "CreativeSets", "GetCreativeSetVariation"
+	}
+
+	return "", ""
+}
+
+// rollbackKindOf returns the kind of the entity at path, e.g. rollbackAdGroup for "campaigns/1/adgroups/2".
+func rollbackKindOf(path string) rollbackKind {
+	switch entityResource(path) {
+	case "adgroups":
+		return rollbackAdGroup
+	case "targetingkeywords":
+		return rollbackTargetingKeyword
+	case "negativekeywords":
+		if strings.Contains(path, "/adgroups/") {
+			return rollbackAdGroupNegativeKeyword
+		}
+
+		return rollbackNegativeKeyword
+	case "creativesets":
+		return rollbackCreativeSet
+	}
+
+	return rollbackCampaign
+}
+
+// pathIDs returns the identifiers of a path by resource, e.g. {"campaigns": 1, "adgroups": 2} for
+// "campaigns/1/adgroups/2".
+func pathIDs(path string) map[string]int64 {
+	ids := make(map[string]int64)
+	segments := strings.Split(path, "/")
+
+	for i := 1; i < len(segments); i++ {
+		if id, err := strconv.ParseInt(segments[i], 10, 64); err == nil {
+			ids[segments[i-1]] = id
+		}
+	}
+
+	return ids
+}
+
+// entityResource returns the resource of the entity at path, e.g. "adgroups" for "campaigns/1/adgroups/2".
+func entityResource(path string) string {
+	segments := strings.Split(path, "/")
+
+	if len(segments) < 2 {
+		return path
+	}
+
+	return segments[len(segments)-2]
+}
+
+// responseEntities returns the entities of a recorded response by identifier.
+func responseEntities(response json.RawMessage) map[int64]json.RawMessage {
+	entities := make(map[int64]json.RawMessage)
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	if json.Unmarshal(response, &envelope) != nil || len(envelope.Data) == 0 {
+		return entities
+	}
+
+	items := []json.RawMessage{envelope.Data}
+	if envelope.Data[0] == '[' {
+		_ = json.Unmarshal(envelope.Data, &items)
+	}
+
+	for _, item := range items {
+		var entity struct {
+			ID int64 `json:"id"`
+		}
+
+		if json.Unmarshal(item, &entity) == nil {
+			entities[entity.ID] = item
+		}
+	}
+
+	return entities
+}
@@ -0,0 +1,204 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRecordingServer is like newMuxServer, and also returns the bodies of the mutating requests it received by route.
+func newRecordingServer(routes map[string]string) (*Client, *httptest.Server, func() map[string]string) {
+	var mu sync.Mutex
+
+	received := make(map[string]string)
+	mux := muxHandler(routes)
+
+	client, server := newHandlerServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			body, _ := io.ReadAll(r.Body)
+
+			mu.Lock()
+			received[r.Method+" "+r.URL.Path] = string(body)
+			mu.Unlock()
+		}
+
+		mux(w, r)
+	}))
+
+	return client, server, func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return received
+	}
+}
+
+func rollbackTestRecords() []*AuditRecord {
+	return []*AuditRecord{
+		{
+			ID: "bids", Service: "Keywords", Method: "UpdateTargetingKeywords", HTTPMethod: http.MethodPut,
+			Path:    "campaigns/1/adgroups/2/targetingkeywords/bulk",
+			Request: json.RawMessage(`[{"id":11,"bidAmount":{"amount":"1.5","currency":"USD"}},{"id":12,"status":"PAUSED"}]`),
+			Before: []json.RawMessage{
+				json.RawMessage(`{"id":11,"adGroupId":2,"bidAmount":{"amount":"1","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-01T10:00:00.000"}`),
+				json.RawMessage(`{"id":12,"adGroupId":2,"bidAmount":{"amount":"2","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-01T10:00:00.000"}`),
+			},
+			Response: json.RawMessage(`{"data":[` +
+				`{"id":11,"adGroupId":2,"bidAmount":{"amount":"1.5","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-02T10:00:00.000"},` +
+				`{"id":12,"adGroupId":2,"bidAmount":{"amount":"2","currency":"USD"},"status":"PAUSED","modificationTime":"2021-06-02T10:00:00.000"}]}`),
+		},
+		{
+			ID: "pause", Service: "AdGroups", Method: "UpdateAdGroup", HTTPMethod: http.MethodPut, Path: "campaigns/1/adgroups/2",
+			Request:  json.RawMessage(`{"status":"PAUSED"}`),
+			Before:   []json.RawMessage{json.RawMessage(`{"id":2,"campaignId":1,"status":"ENABLED","modificationTime":"2021-06-01T10:00:00.000"}`)},
+			Response: json.RawMessage(`{"data":{"id":2,"campaignId":1,"status":"PAUSED","modificationTime":"2021-06-02T10:00:00.000"}}`),
+		},
+		{
+			ID: "negatives", Service: "Keywords", Method: "DeleteNegativeKeywords", HTTPMethod: http.MethodPost,
+			Path:     "campaigns/1/negativekeywords/delete/bulk",
+			Request:  json.RawMessage(`[21]`),
+			Before:   []json.RawMessage{json.RawMessage(`{"id":21,"campaignId":1,"text":"free","matchType":"EXACT","status":"ACTIVE"}`)},
+			Response: json.RawMessage(`{"data":1}`),
+		},
+		{ID: "create", Service: "Campaigns", Method: "CreateCampaign", HTTPMethod: http.MethodPost, Path: "campaigns"},
+		{ID: "failed", Service: "Campaigns", Method: "UpdateCampaign", HTTPMethod: http.MethodPut, Path: "campaigns/9", Error: "404 Not Found"},
+		{
+			ID: "rename", Service: "CreativeSets", Method: "UpdateCreativeSets", HTTPMethod: http.MethodPut, Path: "creativesets/5",
+			Request:  json.RawMessage(`{"name":"Summer"}`),
+			Before:   []json.RawMessage{json.RawMessage(`{"id":5,"name":"Spring"}`)},
+			Response: json.RawMessage(`{"data":{"id":5,"name":"Summer"}}`),
+		},
+	}
+}
+
+func TestRollbackUndo(t *testing.T) {
+	t.Parallel()
+
+	client, server, received := newRecordingServer(map[string]string{
+		"GET /campaigns/1/adgroups/2/targetingkeywords/11":   `{"data":{"id":11,"adGroupId":2,"bidAmount":{"amount":"1.50","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-02T10:00:00.000"}}`,
+		"GET /campaigns/1/adgroups/2/targetingkeywords/12":   `{"data":{"id":12,"adGroupId":2,"bidAmount":{"amount":"3","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-03T10:00:00.000"}}`,
+		"PUT /campaigns/1/adgroups/2/targetingkeywords/bulk": `{"data":[]}`,
+		"GET /campaigns/1/adgroups/2":                        `{"data":{"id":2,"campaignId":1,"status":"PAUSED","modificationTime":"2021-06-02T10:00:00.000"}}`,
+		"PUT /campaigns/1/adgroups/2":                        `{"data":{"id":2}}`,
+		"POST /campaigns/1/negativekeywords/bulk":            `{"data":[]}`,
+		"GET /creativesets/5":                                `{"data":{"id":5,"name":"Summer"}}`,
+		"PUT /creativesets/5":                                `{"data":{"id":5,"name":"Spring"}}`,
+	})
+	defer server.Close()
+
+	run, err := NewRollback(client).Undo(context.Background(), rollbackTestRecords())
+	require.NoError(t, err)
+
+	statuses := make(map[string]RollbackStatus)
+	for _, change := range run.Changes {
+		statuses[change.Path] = change.Status
+	}
+
+	assert.Equal(t, map[string]RollbackStatus{
+		"campaigns/1/adgroups/2/targetingkeywords/11": RollbackStatusRestored,
+		"campaigns/1/adgroups/2/targetingkeywords/12": RollbackStatusConflict,
+		"campaigns/1/adgroups/2":                      RollbackStatusRestored,
+		"campaigns/1/negativekeywords/21":             RollbackStatusRestored,
+		"campaigns":                                   RollbackStatusUnsupported,
+		"creativesets/5":                              RollbackStatusRestored,
+	}, statuses)
+
+	bodies := received()
+	assert.JSONEq(t, `[{"id":11,"adGroupId":2,"bidAmount":{"amount":"1","currency":"USD"}}]`, bodies["PUT /campaigns/1/adgroups/2/targetingkeywords/bulk"])
+	assert.JSONEq(t, `{"status":"ENABLED"}`, bodies["PUT /campaigns/1/adgroups/2"])
+	assert.JSONEq(t, `[{"text":"free","matchType":"EXACT","status":"ACTIVE","modificationTime":"0001-01-01T00:00:00"}]`, bodies["POST /campaigns/1/negativekeywords/bulk"])
+	assert.JSONEq(t, `{"name":"Spring"}`, bodies["PUT /creativesets/5"])
+
+	assert.Contains(t, run.String(), "CONFLICT campaigns/1/adgroups/2/targetingkeywords/12: modified at 2021-06-03T10:00:00Z")
+	assert.Contains(t, run.String(), "UNSUPPORTED campaigns: Campaigns.CreateCampaign cannot be undone")
+}
+
+func TestRollbackMergesRecordsOfAnEntity(t *testing.T) {
+	t.Parallel()
+
+	client, server, received := newRecordingServer(map[string]string{
+		"GET /campaigns/1": `{"data":{"id":1,"name":"Third","modificationTime":"2021-06-03T10:00:00.000"}}`,
+		"PUT /campaigns/1": `{"data":{"id":1}}`,
+	})
+	defer server.Close()
+
+	records := []*AuditRecord{
+		{
+			ID: "first", Service: "Campaigns", Method: "UpdateCampaign", Path: "campaigns/1",
+			Before:   []json.RawMessage{json.RawMessage(`{"id":1,"name":"First","modificationTime":"2021-06-01T10:00:00.000"}`)},
+			Response: json.RawMessage(`{"data":{"id":1,"name":"Second","modificationTime":"2021-06-02T10:00:00.000"}}`),
+		},
+		{
+			ID: "second", Service: "Campaigns", Method: "UpdateCampaign", Path: "campaigns/1",
+			Before:   []json.RawMessage{json.RawMessage(`{"id":1,"name":"Second","modificationTime":"2021-06-02T10:00:00.000"}`)},
+			Response: json.RawMessage(`{"data":{"id":1,"name":"Third","modificationTime":"2021-06-03T10:00:00.000"}}`),
+		},
+	}
+
+	run, err := NewRollback(client).Undo(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, run.Changes, 1)
+	assert.Equal(t, RollbackStatusRestored, run.Changes[0].Status)
+	assert.Equal(t, []string{"first", "second"}, run.Changes[0].RecordIDs)
+	assert.JSONEq(t, `{"campaign":{"name":"First"},"clearGeoTargetingOnCountryOrRegionChange":false}`, received()["PUT /campaigns/1"])
+}
+
+func TestRollbackForce(t *testing.T) {
+	t.Parallel()
+
+	client, server, received := newRecordingServer(map[string]string{
+		"GET /campaigns/1/adgroups/2/targetingkeywords/11":   `{"data":{"id":11,"adGroupId":2,"bidAmount":{"amount":"1","currency":"USD"},"status":"ACTIVE","modificationTime":"2021-06-03T10:00:00.000"}}`,
+		"GET /campaigns/1/adgroups/2/targetingkeywords/12":   `{"data":{"id":12,"adGroupId":2,"bidAmount":{"amount":"3","currency":"USD"},"status":"PAUSED","modificationTime":"2021-06-03T10:00:00.000"}}`,
+		"PUT /campaigns/1/adgroups/2/targetingkeywords/bulk": `{"data":[]}`,
+	})
+	defer server.Close()
+
+	rollback := NewRollback(client)
+	rollback.Force = true
+
+	run, err := rollback.Undo(context.Background(), rollbackTestRecords()[:1])
+	require.NoError(t, err)
+	require.Len(t, run.Changes, 2)
+	assert.Equal(t, RollbackStatusUnchanged, run.Changes[0].Status)
+	assert.Equal(t, RollbackStatusRestored, run.Changes[1].Status)
+	assert.JSONEq(t, `[{"id":12,"adGroupId":2,"bidAmount":{"amount":"2","currency":"USD"},"status":"ACTIVE"}]`, received()["PUT /campaigns/1/adgroups/2/targetingkeywords/bulk"])
+}
+
+func TestRollbackRecreatedNegativeKeyword(t *testing.T) {
+	t.Parallel()
+
+	client, server, received := newRecordingServer(map[string]string{
+		"GET /campaigns/1/negativekeywords/21": `{"data":{"id":21,"text":"free","deleted":false}}`,
+	})
+	defer server.Close()
+
+	run, err := NewRollback(client).Undo(context.Background(), rollbackTestRecords()[2:3])
+	require.NoError(t, err)
+	require.Len(t, run.Changes, 1)
+	assert.Equal(t, RollbackStatusConflict, run.Changes[0].Status)
+	assert.Equal(t, "recreated since it was deleted", run.Changes[0].Reason)
+	assert.Empty(t, received())
+}
@@ -20,11 +20,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"time"
 )
 
 const (
+	moneyPrecision      = 2
 	dateFormat          = "2006-01-02"
+	hourlyDateFormat    = "2006-01-02 15"
 	customISO8601Format = "2006-01-02T15:04:05.999"
 	emailRegexString    = "^(?:(?:(?:(?:[a-zA-Z]|\\d|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])+(?:\\.([a-zA-Z]|\\d|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])+)*)|(?:(?:\\x22)(?:(?:(?:(?:\\x20|\\x09)*(?:\\x0d\\x0a))?(?:\\x20|\\x09)+)?(?:(?:[\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x7f]|\\x21|[\\x23-\\x5b]|[\\x5d-\\x7e]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(?:(?:[\\x01-\\x09\\x0b\\x0c\\x0d-\\x7f]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}]))))*(?:(?:(?:\\x20|\\x09)*(?:\\x0d\\x0a))?(\\x20|\\x09)+)?(?:\\x22))))@(?:(?:(?:[a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(?:(?:[a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])(?:[a-zA-Z]|\\d|-|\\.|~|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])*(?:[a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])))\\.)+(?:(?:[a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(?:(?:[a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])(?:[a-zA-Z]|\\d|-|\\.|~|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])*(?:[a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])))\\.?$"
 )
@@ -64,7 +67,11 @@ func (d *Date) UnmarshalJSON(data []byte) error {
 
 	parsed, err := time.Parse(dateFormat, dateStr)
 	if err != nil {
-		return err
+		// rows of hourly reports carry the hour next to the date
+		parsed, err = time.Parse(hourlyDateFormat, dateStr)
+		if err != nil {
+			return err
+		}
 	}
 
 	d.Time = parsed
@@ -156,3 +163,25 @@ func Float(v float64) *float64 {
 func String(v string) *string {
 	return &v
 }
+
+// moneyAmount returns the amount of m as a number, or zero when m is nil or its amount is not a number.
+func moneyAmount(m *Money) float64 {
+	if m == nil {
+		return 0
+	}
+
+	amount, err := strconv.ParseFloat(m.Amount, 64)
+	if err != nil {
+		return 0
+	}
+
+	return amount
+}
+
+// newMoney returns a Money value holding amount rounded to cents in the given currency.
+func newMoney(amount float64, currency string) *Money {
+	return &Money{
+		Amount:   strconv.FormatFloat(amount, 'f', moneyPrecision, 64),
+		Currency: currency,
+	}
+}
@@ -63,6 +63,18 @@ func TestDateUnmarshal(t *testing.T) {
 	assert.Equal(t, want, b.Field.Time)
 }
 
+func TestDateUnmarshalHourly(t *testing.T) {
+	t.Parallel()
+
+	want := time.Date(2020, 4, 1, 14, 0, 0, 0, time.UTC)
+	jsonStr := dateContainerJSON("2020-04-01 14")
+
+	var b dateContainer
+	err := json.Unmarshal([]byte(jsonStr), &b)
+	assert.NoError(t, err)
+	assert.Equal(t, want, b.Field.Time)
+}
+
 func TestDateUnmarshalWrongType(t *testing.T) {
 	t.Parallel()
 
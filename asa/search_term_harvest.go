@@ -0,0 +1,348 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SearchTermAction is the decision taken for a mined search term.
+type SearchTermAction string
+
+const (
+	// SearchTermActionPromote is for a search term to add as an Exact targeting keyword.
+	SearchTermActionPromote SearchTermAction = "PROMOTE"
+	// SearchTermActionNegate is for a search term to add as an Exact negative keyword.
+	SearchTermActionNegate SearchTermAction = "NEGATE"
+	// SearchTermActionKeep is for a search term that matches no threshold or is already handled.
+	SearchTermActionKeep SearchTermAction = "KEEP"
+	// SearchTermActionUnbiddable is for a search term to promote that has no bid, as neither the config nor the
+	// report gives one. It is left out of the plan.
+	SearchTermActionUnbiddable SearchTermAction = "UNBIDDABLE"
+)
+
+// SearchTermHarvestConfig configures the search term mining of a discovery campaign into an exact campaign.
+type SearchTermHarvestConfig struct {
+	// DiscoveryCampaignID is the Broad or Search Match campaign whose search terms are mined.
+	DiscoveryCampaignID int64 `json:"discoveryCampaignId"`
+	// ExactCampaignID is the campaign that receives the promoted search terms.
+	ExactCampaignID int64 `json:"exactCampaignId"`
+	// ExactAdGroupID is the ad group of ExactCampaignID that receives the promoted search terms.
+	ExactAdGroupID int64 `json:"exactAdGroupId"`
+	// StartTime and EndTime bound the search term report window.
+	StartTime Date `json:"startTime"`
+	EndTime   Date `json:"endTime"`
+	// MinInstalls is the number of installs a search term needs to be promoted.
+	MinInstalls int64 `json:"minInstalls"`
+	// MaxCPA is the highest average cost per acquisition of a promoted search term. Zero disables the check.
+	MaxCPA float64 `json:"maxCpa,omitempty"`
+	// NegativeMinTaps negates search terms with at least this many taps and no installs. Zero disables the check.
+	NegativeMinTaps int64 `json:"negativeMinTaps,omitempty"`
+	// NegativeMaxCPA negates search terms with an average cost per acquisition above this value. Zero disables the check.
+	NegativeMaxCPA float64 `json:"negativeMaxCpa,omitempty"`
+	// Bid is the bid of the new Exact keywords. When nil, the bid of the discovery keyword the term matched is reused,
+	// and terms without one are reported as SearchTermActionUnbiddable.
+	Bid *Money `json:"bid,omitempty"`
+	// NegateInDiscovery also adds promoted search terms as Exact negatives of the discovery campaign,
+	// so that their traffic moves to the exact campaign.
+	NegateInDiscovery bool `json:"negateInDiscovery,omitempty"`
+}
+
+// SearchTermCandidate is the aggregated performance of a search term and the action decided for it.
+type SearchTermCandidate struct {
+	Text        string           `json:"text"`
+	Impressions int64            `json:"impressions"`
+	Taps        int64            `json:"taps"`
+	Installs    int64            `json:"installs"`
+	Spend       *Money           `json:"spend,omitempty"`
+	AvgCPA      *Money           `json:"avgCPA,omitempty"`
+	Bid         *Money           `json:"bid,omitempty"`
+	Action      SearchTermAction `json:"action"`
+	Reason      string           `json:"reason"`
+}
+
+// SearchTermHarvestPlan is the reviewable outcome of a search term mining run.
+type SearchTermHarvestPlan struct {
+	DiscoveryCampaignID int64                  `json:"discoveryCampaignId"`
+	ExactCampaignID     int64                  `json:"exactCampaignId"`
+	ExactAdGroupID      int64                  `json:"exactAdGroupId"`
+	Candidates          []*SearchTermCandidate `json:"candidates,omitempty"`
+	// TargetingKeywords are the Exact keywords to create in the exact ad group.
+	TargetingKeywords []*Keyword `json:"targetingKeywords,omitempty"`
+	// DiscoveryNegatives are the Exact campaign negative keywords to create in the discovery campaign.
+	DiscoveryNegatives []*NegativeKeyword `json:"discoveryNegatives,omitempty"`
+}
+
+// SearchTermHarvestResult is the outcome of applying a SearchTermHarvestPlan.
+type SearchTermHarvestResult struct {
+	TargetingKeywords []*Keyword         `json:"targetingKeywords,omitempty"`
+	NegativeKeywords  []*NegativeKeyword `json:"negativeKeywords,omitempty"`
+}
+
+// bulkLimit is the maximum number of keywords sent in a single bulk request.
+const bulkLimit = 1000
+
+// PlanSearchTermHarvest runs a search term report on the discovery campaign and builds a plan of Exact targeting
+// keywords for the exact ad group and Exact negative keywords for the discovery campaign. Terms already targeted
+// in the exact campaign or already negated in the discovery campaign are left out of the plan.
+func (s *KeywordService) PlanSearchTermHarvest(ctx context.Context, config *SearchTermHarvestConfig) (*SearchTermHarvestPlan, error) {
+	params := &ReportingRequest{
+		StartTime:       config.StartTime,
+		EndTime:         config.EndTime,
+		TimeZone:        ReportingRequestTimeZoneORTZ,
+		ReturnRowTotals: true,
+		Selector: &Selector{
+			OrderBy: []*Sorting{{Field: "localSpend", SortOrder: SortingOrderDescending}},
+		},
+	}
+
+	rows, err := fetchAllReportRows(ctx, params, func(ctx context.Context, params *ReportingRequest) (*ReportingResponseBody, *Response, error) {
+		return s.client.Reporting.GetSearchTermLevelReports(ctx, config.DiscoveryCampaignID, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.findAllTargetingKeywords(ctx, config.ExactCampaignID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	negatives, err := s.findAllNegativeKeywords(ctx, config.DiscoveryCampaignID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildSearchTermHarvestPlan(config, rows, existing, negatives), nil
+}
+
+// BuildSearchTermHarvestPlan classifies the rows of a search term report by the thresholds of config. Rows of the
+// same search term are aggregated, and terms already present in existingKeywords (the keywords of the exact campaign)
+// or existingNegatives (the negative keywords of the discovery campaign) are kept as they are.
+func BuildSearchTermHarvestPlan(config *SearchTermHarvestConfig, rows []Row, existingKeywords []*Keyword, existingNegatives []*NegativeKeyword) *SearchTermHarvestPlan {
+	plan := &SearchTermHarvestPlan{
+		DiscoveryCampaignID: config.DiscoveryCampaignID,
+		ExactCampaignID:     config.ExactCampaignID,
+		ExactAdGroupID:      config.ExactAdGroupID,
+		Candidates:          aggregateSearchTerms(rows),
+	}
+
+	targeted := make(map[string]bool)
+
+	for _, k := range existingKeywords {
+		if !k.Deleted && k.MatchType == KeywordMatchTypeExact {
+			targeted[NormalizeKeywordText(k.Text)] = true
+		}
+	}
+
+	negated := make(map[string]bool)
+
+	for _, k := range existingNegatives {
+		if !k.Deleted && k.MatchType == KeywordMatchTypeExact {
+			negated[NormalizeKeywordText(k.Text)] = true
+		}
+	}
+
+	for _, candidate := range plan.Candidates {
+		classifySearchTerm(config, candidate, targeted[candidate.Text], negated[candidate.Text])
+
+		bid, biddable := searchTermBid(config, candidate)
+		if candidate.Action == SearchTermActionPromote && !biddable {
+			candidate.Action = SearchTermActionUnbiddable
+			candidate.Reason += ", but no bid is configured or reported"
+		}
+
+		switch candidate.Action {
+		case SearchTermActionPromote:
+			plan.TargetingKeywords = append(plan.TargetingKeywords, &Keyword{
+				AdGroupID: config.ExactAdGroupID,
+				Text:      candidate.Text,
+				MatchType: KeywordMatchTypeExact,
+				BidAmount: bid,
+				Status:    KeywordStatusActive,
+			})
+
+			if config.NegateInDiscovery && !negated[candidate.Text] {
+				plan.DiscoveryNegatives = append(plan.DiscoveryNegatives, newExactNegative(config.DiscoveryCampaignID, candidate.Text))
+			}
+		case SearchTermActionNegate:
+			plan.DiscoveryNegatives = append(plan.DiscoveryNegatives, newExactNegative(config.DiscoveryCampaignID, candidate.Text))
+		case SearchTermActionKeep, SearchTermActionUnbiddable:
+		}
+	}
+
+	return plan
+}
+
+// ApplySearchTermHarvestPlan creates the targeting and negative keywords of a plan through the bulk endpoints.
+func (s *KeywordService) ApplySearchTermHarvestPlan(ctx context.Context, plan *SearchTermHarvestPlan) (*SearchTermHarvestResult, error) {
+	result := &SearchTermHarvestResult{}
+
+	for start := 0; start < len(plan.TargetingKeywords); start += bulkLimit {
+		end := minInt(start+bulkLimit, len(plan.TargetingKeywords))
+
+		res, _, err := s.CreateTargetingKeywords(ctx, plan.ExactCampaignID, plan.ExactAdGroupID, plan.TargetingKeywords[start:end])
+		if err != nil {
+			return result, err
+		}
+
+		result.TargetingKeywords = append(result.TargetingKeywords, res.Keywords...)
+	}
+
+	for start := 0; start < len(plan.DiscoveryNegatives); start += bulkLimit {
+		end := minInt(start+bulkLimit, len(plan.DiscoveryNegatives))
+
+		res, _, err := s.CreateNegativeKeywords(ctx, plan.DiscoveryCampaignID, plan.DiscoveryNegatives[start:end])
+		if err != nil {
+			return result, err
+		}
+
+		result.NegativeKeywords = append(result.NegativeKeywords, res.Keywords...)
+	}
+
+	return result, nil
+}
+
+// String returns a human-readable summary of the plan for review.
+func (p *SearchTermHarvestPlan) String() string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf("Search term harvest of campaign %d into campaign %d ad group %d\n", p.DiscoveryCampaignID, p.ExactCampaignID, p.ExactAdGroupID))
+
+	for _, c := range p.Candidates {
+		if c.Action == SearchTermActionKeep {
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf("  %-7s %q: %s\n", c.Action, c.Text, c.Reason))
+	}
+
+	b.WriteString(fmt.Sprintf("%d targeting keywords to create, %d negative keywords to create\n", len(p.TargetingKeywords), len(p.DiscoveryNegatives)))
+
+	return b.String()
+}
+
+func aggregateSearchTerms(rows []Row) []*SearchTermCandidate {
+	var order []string
+
+	candidates := make(map[string]*SearchTermCandidate)
+	spend := make(map[string]float64)
+
+	for _, row := range rows {
+		if row.Metadata == nil || row.Metadata.SearchTermText == nil || row.Total == nil {
+			continue
+		}
+
+		text := NormalizeKeywordText(*row.Metadata.SearchTermText)
+		if text == "" {
+			continue
+		}
+
+		candidate, ok := candidates[text]
+		if !ok {
+			candidate = &SearchTermCandidate{Text: text, Action: SearchTermActionKeep}
+			candidates[text] = candidate
+			order = append(order, text)
+		}
+
+		candidate.Impressions += row.Total.Impressions
+		candidate.Taps += row.Total.Taps
+		candidate.Installs += row.Total.Installs
+		spend[text] += moneyAmount(row.Total.LocalSpend)
+
+		if row.Total.LocalSpend != nil {
+			candidate.Spend = &Money{Currency: row.Total.LocalSpend.Currency}
+		}
+
+		if candidate.Bid == nil || moneyAmount(row.Metadata.BidAmount) > moneyAmount(candidate.Bid) {
+			candidate.Bid = row.Metadata.BidAmount
+		}
+	}
+
+	result := make([]*SearchTermCandidate, 0, len(order))
+
+	for _, text := range order {
+		candidate := candidates[text]
+		if candidate.Spend != nil {
+			candidate.Spend = newMoney(spend[text], candidate.Spend.Currency)
+
+			if candidate.Installs > 0 {
+				candidate.AvgCPA = newMoney(spend[text]/float64(candidate.Installs), candidate.Spend.Currency)
+			}
+		}
+
+		result = append(result, candidate)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return moneyAmount(result[i].Spend) > moneyAmount(result[j].Spend)
+	})
+
+	return result
+}
+
+func classifySearchTerm(config *SearchTermHarvestConfig, c *SearchTermCandidate, targeted bool, negated bool) {
+	cpa := moneyAmount(c.AvgCPA)
+
+	switch {
+	case targeted:
+		c.Reason = "already an Exact keyword of the exact campaign"
+	case negated:
+		c.Reason = "already an Exact negative keyword of the discovery campaign"
+	case c.Installs >= config.MinInstalls && c.Installs > 0 && (config.MaxCPA == 0 || cpa <= config.MaxCPA):
+		c.Action = SearchTermActionPromote
+		c.Reason = fmt.Sprintf("%d installs at an average CPA of %.2f", c.Installs, cpa)
+	case config.NegativeMinTaps > 0 && c.Installs == 0 && c.Taps >= config.NegativeMinTaps:
+		c.Action = SearchTermActionNegate
+		c.Reason = fmt.Sprintf("%d taps without installs", c.Taps)
+	case config.NegativeMaxCPA > 0 && c.Installs > 0 && cpa > config.NegativeMaxCPA:
+		c.Action = SearchTermActionNegate
+		c.Reason = fmt.Sprintf("average CPA of %.2f is above %.2f", cpa, config.NegativeMaxCPA)
+	default:
+		c.Reason = "no threshold reached"
+	}
+}
+
+// searchTermBid returns the bid of a promoted search term, and false when there is none.
+func searchTermBid(config *SearchTermHarvestConfig, c *SearchTermCandidate) (Money, bool) {
+	for _, bid := range []*Money{config.Bid, c.Bid} {
+		if bid != nil && bid.Amount != "" && bid.Currency != "" {
+			return *bid, true
+		}
+	}
+
+	return Money{}, false
+}
+
+func newExactNegative(campaignID int64, text string) *NegativeKeyword {
+	return &NegativeKeyword{
+		CampaignID: campaignID,
+		Text:       text,
+		MatchType:  KeywordMatchTypeExact,
+	}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,150 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTermRow(text string, taps int64, installs int64, spend string) Row {
+	return Row{
+		Metadata: &MetaDataObject{SearchTermText: String(text), BidAmount: &Money{Amount: "1.5", Currency: "USD"}},
+		Total: &SpendRow{
+			Taps:       taps,
+			Installs:   installs,
+			LocalSpend: &Money{Amount: spend, Currency: "USD"},
+		},
+	}
+}
+
+func TestBuildSearchTermHarvestPlan(t *testing.T) {
+	t.Parallel()
+
+	config := &SearchTermHarvestConfig{
+		DiscoveryCampaignID: 1,
+		ExactCampaignID:     2,
+		ExactAdGroupID:      20,
+		MinInstalls:         3,
+		MaxCPA:              2,
+		NegativeMinTaps:     10,
+		NegativeMaxCPA:      5,
+		NegateInDiscovery:   true,
+	}
+	rows := []Row{
+		searchTermRow("Photo Editor", 20, 4, "4"),
+		searchTermRow("photo editor", 5, 1, "2"),
+		searchTermRow("free stuff", 12, 0, "8"),
+		searchTermRow("expensive", 30, 1, "9"),
+		searchTermRow("collage", 10, 5, "5"),
+		searchTermRow("meh", 2, 0, "0.5"),
+		{Metadata: &MetaDataObject{}, Total: &SpendRow{Taps: 100}},
+	}
+	existing := []*Keyword{{Text: "collage", MatchType: KeywordMatchTypeExact}}
+
+	plan := BuildSearchTermHarvestPlan(config, rows, existing, nil)
+
+	assert.Len(t, plan.Candidates, 5)
+	assert.Equal(t, "expensive", plan.Candidates[0].Text)
+
+	assert.Len(t, plan.TargetingKeywords, 1)
+	assert.Equal(t, "photo editor", plan.TargetingKeywords[0].Text)
+	assert.Equal(t, KeywordMatchTypeExact, plan.TargetingKeywords[0].MatchType)
+	assert.Equal(t, int64(20), plan.TargetingKeywords[0].AdGroupID)
+	assert.Equal(t, "1.5", plan.TargetingKeywords[0].BidAmount.Amount)
+
+	var negatives []string
+	for _, n := range plan.DiscoveryNegatives {
+		negatives = append(negatives, n.Text)
+		assert.Equal(t, int64(1), n.CampaignID)
+	}
+
+	assert.ElementsMatch(t, []string{"photo editor", "free stuff", "expensive"}, negatives)
+	assert.Contains(t, plan.String(), `PROMOTE "photo editor"`)
+}
+
+func TestBuildSearchTermHarvestPlanUnbiddable(t *testing.T) {
+	t.Parallel()
+
+	config := &SearchTermHarvestConfig{MinInstalls: 1, NegateInDiscovery: true}
+	row := searchTermRow("photo editor", 20, 4, "4")
+	row.Metadata.BidAmount = nil
+
+	plan := BuildSearchTermHarvestPlan(config, []Row{row}, nil, nil)
+
+	assert.Len(t, plan.Candidates, 1)
+	assert.Equal(t, SearchTermActionUnbiddable, plan.Candidates[0].Action)
+	assert.Empty(t, plan.TargetingKeywords)
+	assert.Empty(t, plan.DiscoveryNegatives)
+	assert.Contains(t, plan.String(), `UNBIDDABLE "photo editor"`)
+
+	config.Bid = &Money{Amount: "2", Currency: "USD"}
+	plan = BuildSearchTermHarvestPlan(config, []Row{row}, nil, nil)
+
+	assert.Len(t, plan.TargetingKeywords, 1)
+	assert.Equal(t, Money{Amount: "2", Currency: "USD"}, plan.TargetingKeywords[0].BidAmount)
+}
+
+func TestBuildSearchTermHarvestPlanFromReport(t *testing.T) {
+	t.Parallel()
+
+	report := deserializeFileToReportingResponse(t, "../test/response_body_json_files/get_search_term_level_reports.json")
+	config := &SearchTermHarvestConfig{NegativeMinTaps: 1, Bid: &Money{Amount: "1", Currency: "USD"}}
+
+	plan := BuildSearchTermHarvestPlan(config, report.ReportingCampaign.ReportingDataResponse.Rows, nil, nil)
+
+	assert.Len(t, plan.Candidates, 1)
+	assert.Equal(t, SearchTermActionNegate, plan.Candidates[0].Action)
+	assert.Equal(t, "1.27", plan.Candidates[0].Spend.Amount)
+	assert.Len(t, plan.DiscoveryNegatives, 1)
+}
+
+func TestPlanAndApplySearchTermHarvest(t *testing.T) {
+	t.Parallel()
+
+	client, server := newMuxServer(map[string]string{
+		"POST /reports/campaigns/1/searchterms": `{"data":{"reportingDataResponse":{"row":[
+			{"metadata":{"searchTermText":"photo editor"},"total":{"taps":10,"installs":5,"localSpend":{"amount":"5","currency":"USD"}}}
+		]}}}`,
+		"POST /campaigns/2/adgroups/targetingkeywords/find":    `{"data":[]}`,
+		"POST /campaigns/1/negativekeywords/find":              `{"data":[]}`,
+		"POST /campaigns/2/adgroups/20/targetingkeywords/bulk": `{"data":[{"id":7,"text":"photo editor","matchType":"Exact"}]}`,
+		"POST /campaigns/1/negativekeywords/bulk":              `{"data":[{"id":8,"text":"photo editor","matchType":"Exact"}]}`,
+	})
+	defer server.Close()
+
+	config := &SearchTermHarvestConfig{
+		DiscoveryCampaignID: 1,
+		ExactCampaignID:     2,
+		ExactAdGroupID:      20,
+		MinInstalls:         1,
+		NegateInDiscovery:   true,
+		Bid:                 &Money{Amount: "2", Currency: "USD"},
+	}
+
+	plan, err := client.Keywords.PlanSearchTermHarvest(context.Background(), config)
+	assert.NoError(t, err)
+	assert.Len(t, plan.TargetingKeywords, 1)
+	assert.Len(t, plan.DiscoveryNegatives, 1)
+
+	result, err := client.Keywords.ApplySearchTermHarvestPlan(context.Background(), plan)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), result.TargetingKeywords[0].ID)
+	assert.Equal(t, int64(8), result.NegativeKeywords[0].ID)
+}
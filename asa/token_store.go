@@ -0,0 +1,425 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	tokenLockPollInterval = 50 * time.Millisecond
+	tokenLockStaleAfter   = time.Minute
+	tokenLockIDSize       = 16
+	tokenFileExtension    = ".json"
+	tokenLockExtension    = ".lock"
+)
+
+// ErrTokenNotFound happens when a TokenStore has no token for a key.
+var ErrTokenNotFound = errors.New("token not found")
+
+// StoredToken is an access token shared through a TokenStore.
+type StoredToken struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAfter time.Time `json:"expires_after"`
+}
+
+// Valid reports whether the token can still be used at now.
+func (t *StoredToken) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && now.Before(t.ExpiresAfter)
+}
+
+// TokenStore shares access tokens between the AuthTransports of several processes, so that they are only refreshed
+// once they expire. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Load returns the token stored under key, or ErrTokenNotFound.
+	Load(ctx context.Context, key string) (*StoredToken, error)
+	Store(ctx context.Context, key string, token *StoredToken) error
+	Delete(ctx context.Context, key string) error
+	// Lock blocks until the caller holds the exclusive right to refresh the token under key, or ctx is done. The
+	// returned function releases the lock.
+	Lock(ctx context.Context, key string) (func() error, error)
+}
+
+// tokenStoreKey returns the key of the access token of a client.
+func tokenStoreKey(clientID string) string {
+	sum := sha256.Sum256([]byte(clientID))
+
+	return "asa-token-" + hex.EncodeToString(sum[:])
+}
+
+// MemoryTokenStore is a TokenStore sharing tokens between the AuthTransports of a single process.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]StoredToken
+	locks  map[string]chan struct{}
+}
+
+// NewMemoryTokenStore creates an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]StoredToken),
+		locks:  make(map[string]chan struct{}),
+	}
+}
+
+// Load returns the token stored under key.
+func (s *MemoryTokenStore) Load(_ context.Context, key string) (*StoredToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &token, nil
+}
+
+// Store saves token under key.
+func (s *MemoryTokenStore) Store(_ context.Context, key string, token *StoredToken) error {
+	s.mu.Lock()
+	s.tokens[key] = *token
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes the token stored under key.
+func (s *MemoryTokenStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.tokens, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Lock acquires the refresh lock of key.
+func (s *MemoryTokenStore) Lock(ctx context.Context, key string) (func() error, error) {
+	for {
+		s.mu.Lock()
+		held, ok := s.locks[key]
+
+		if !ok {
+			released := make(chan struct{})
+			s.locks[key] = released
+			s.mu.Unlock()
+
+			return func() error {
+				s.mu.Lock()
+				delete(s.locks, key)
+				s.mu.Unlock()
+				close(released)
+
+				return nil
+			}, nil
+		}
+
+		s.mu.Unlock()
+
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// FileTokenStore is a TokenStore sharing tokens between processes through files in a directory. Refreshes are
+// serialized with lock files; a lock older than a minute is considered abandoned by a crashed process and removed.
+type FileTokenStore struct {
+	dir string
+}
+
+// NewFileTokenStore creates a FileTokenStore in dir, creating the directory if needed.
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(dir, cacheDirPerm); err != nil {
+		return nil, err
+	}
+
+	return &FileTokenStore{dir: dir}, nil
+}
+
+func (s *FileTokenStore) path(key string, extension string) string {
+	return filepath.Join(s.dir, key+extension)
+}
+
+// Load reads the token stored under key.
+func (s *FileTokenStore) Load(_ context.Context, key string) (*StoredToken, error) {
+	raw, err := ioutil.ReadFile(s.path(key, tokenFileExtension))
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	token := &StoredToken{}
+	if err := json.Unmarshal(raw, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Store writes token under key through a temporary file so that readers never see a partial token.
+func (s *FileTokenStore) Store(_ context.Context, key string, token *StoredToken) error {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(key, tokenFileExtension))
+}
+
+// Delete removes the token stored under key.
+func (s *FileTokenStore) Delete(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key, tokenFileExtension)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Lock creates the lock file of key, waiting while another process holds it. The lock file holds a random nonce, so
+// that a process whose lock was taken over as stale does not remove the lock of the next holder.
+func (s *FileTokenStore) Lock(ctx context.Context, key string) (func() error, error) {
+	path := s.path(key, tokenLockExtension)
+
+	nonce, err := newTokenLockID()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, cacheFilePerm)
+		if err == nil {
+			_, err = file.WriteString(nonce)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+
+			if err != nil {
+				_ = os.Remove(path)
+
+				return nil, err
+			}
+
+			return func() error {
+				return releaseLockFile(path, nonce)
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > tokenLockStaleAfter {
+			takeOverLockFile(path, nonce)
+
+			continue
+		}
+
+		select {
+		case <-time.After(tokenLockPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// claimLockFile moves the lock file at path to a name of its own, so that no other process can remove or replace it
+// while it is inspected.
+func claimLockFile(path string, nonce string) (string, error) {
+	claimed := path + "." + nonce
+
+	return claimed, os.Rename(path, claimed)
+}
+
+// restoreLockFile puts back a lock file claimed by mistake. When another process locked meanwhile, the claimed lock
+// file is dropped in favor of the new one.
+func restoreLockFile(claimed string, path string) {
+	_ = os.Link(claimed, path)
+	_ = os.Remove(claimed)
+}
+
+// takeOverLockFile removes the lock file at path if it is still stale once claimed. Other processes taking it over at
+// the same time fail to claim it, and a lock file that was replaced since it was found stale is restored.
+func takeOverLockFile(path string, nonce string) {
+	claimed, err := claimLockFile(path, nonce)
+	if err != nil {
+		return
+	}
+
+	if info, err := os.Stat(claimed); err == nil && time.Since(info.ModTime()) > tokenLockStaleAfter {
+		_ = os.Remove(claimed)
+
+		return
+	}
+
+	restoreLockFile(claimed, path)
+}
+
+// releaseLockFile removes the lock file at path when it still holds nonce.
+func releaseLockFile(path string, nonce string) error {
+	claimed, err := claimLockFile(path, nonce)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(claimed)
+	if err == nil && string(raw) == nonce {
+		return os.Remove(claimed)
+	}
+
+	restoreLockFile(claimed, path)
+
+	return err
+}
+
+func newTokenLockID() (string, error) {
+	id := make([]byte, tokenLockIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// KeyValueStore is the subset of a Redis-style key-value store needed by a TokenStore.
+type KeyValueStore interface {
+	// Get returns the value of key, or ErrTokenNotFound when the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, expiring it after ttl when ttl is positive.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores value under key only when the key does not exist, and reports whether it did.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// DeleteIfEquals deletes key only when its value is value, atomically, and reports whether it did. With Redis,
+	// a Lua script comparing the value before calling DEL does this.
+	DeleteIfEquals(ctx context.Context, key string, value []byte) (bool, error)
+}
+
+// KeyValueTokenStore is a TokenStore backed by a KeyValueStore such as Redis or Memcached. Tokens expire from the
+// store with the access token, and refresh locks expire after a minute so that a crashed process cannot hold them.
+type KeyValueTokenStore struct {
+	kv     KeyValueStore
+	prefix string
+	now    func() time.Time
+}
+
+// NewKeyValueTokenStore creates a KeyValueTokenStore storing its keys with prefix.
+func NewKeyValueTokenStore(kv KeyValueStore, prefix string) *KeyValueTokenStore {
+	return &KeyValueTokenStore{kv: kv, prefix: prefix, now: time.Now}
+}
+
+// Load reads the token stored under key.
+func (s *KeyValueTokenStore) Load(ctx context.Context, key string) (*StoredToken, error) {
+	raw, err := s.kv.Get(ctx, s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &StoredToken{}
+	if err := json.Unmarshal(raw, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Store writes token under key until it expires. A token that has already expired is not stored, and removes the
+// token stored under key.
+func (s *KeyValueTokenStore) Store(ctx context.Context, key string, token *StoredToken) error {
+	ttl := token.ExpiresAfter.Sub(s.now())
+	if ttl <= 0 {
+		return s.Delete(ctx, key)
+	}
+
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Set(ctx, s.prefix+key, raw, ttl)
+}
+
+// Delete removes the token stored under key.
+func (s *KeyValueTokenStore) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, s.prefix+key)
+}
+
+// Lock sets the lock key of key to a random value, waiting while another process holds it. The lock is released only
+// while it still holds that value, so that a process whose lock expired does not release the lock of the next holder.
+func (s *KeyValueTokenStore) Lock(ctx context.Context, key string) (func() error, error) {
+	id, err := newTokenLockID()
+	if err != nil {
+		return nil, err
+	}
+
+	lockKey := s.prefix + key + tokenLockExtension
+
+	for {
+		acquired, err := s.kv.SetNX(ctx, lockKey, []byte(id), tokenLockStaleAfter)
+		if err != nil {
+			return nil, err
+		}
+
+		if acquired {
+			return func() error {
+				_, err := s.kv.DeleteIfEquals(context.Background(), lockKey, []byte(id))
+
+				return err
+			}, nil
+		}
+
+		select {
+		case <-time.After(tokenLockPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,259 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapKeyValueStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func newMapKeyValueStore() *mapKeyValueStore {
+	return &mapKeyValueStore{values: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (s *mapKeyValueStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return value, nil
+}
+
+func (s *mapKeyValueStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.values[key] = value
+	s.ttls[key] = ttl
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *mapKeyValueStore) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+
+	s.values[key] = value
+	s.ttls[key] = ttl
+
+	return true, nil
+}
+
+func (s *mapKeyValueStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.values, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *mapKeyValueStore) DeleteIfEquals(_ context.Context, key string, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.values[key]; !ok || string(current) != string(value) {
+		return false, nil
+	}
+
+	delete(s.values, key)
+
+	return true, nil
+}
+
+func testTokenStore(t *testing.T, store TokenStore) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "key")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	token := &StoredToken{AccessToken: "access", TokenType: "Bearer", ExpiresAfter: time.Now().Add(time.Hour).Round(0).UTC()}
+	assert.NoError(t, store.Store(ctx, "key", token))
+
+	loaded, err := store.Load(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, token.AccessToken, loaded.AccessToken)
+	assert.True(t, token.ExpiresAfter.Equal(loaded.ExpiresAfter))
+	assert.True(t, loaded.Valid(time.Now()))
+	assert.False(t, loaded.Valid(time.Now().Add(2*time.Hour)))
+
+	unlock, err := store.Lock(ctx, "key")
+	assert.NoError(t, err)
+
+	timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	_, err = store.Lock(timeout, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, unlock())
+
+	unlock, err = store.Lock(ctx, "key")
+	assert.NoError(t, err)
+	assert.NoError(t, unlock())
+
+	assert.NoError(t, store.Delete(ctx, "key"))
+
+	_, err = store.Load(ctx, "key")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	t.Parallel()
+
+	testTokenStore(t, NewMemoryTokenStore())
+}
+
+func TestFileTokenStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := NewFileTokenStore(dir)
+	assert.NoError(t, err)
+
+	testTokenStore(t, store)
+
+	// a lock abandoned by a crashed process is taken over
+	lockPath := filepath.Join(dir, "stale"+tokenLockExtension)
+	assert.NoError(t, os.WriteFile(lockPath, []byte("1"), cacheFilePerm))
+
+	old := time.Now().Add(-2 * tokenLockStaleAfter)
+	assert.NoError(t, os.Chtimes(lockPath, old, old))
+
+	timeout, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	unlock, err := store.Lock(timeout, "stale")
+	assert.NoError(t, err)
+	assert.NoError(t, unlock())
+
+	// a process whose lock was taken over does not release the lock of the next holder
+	unlock, err = store.Lock(timeout, "slow")
+	assert.NoError(t, err)
+
+	slowPath := filepath.Join(dir, "slow"+tokenLockExtension)
+	assert.NoError(t, os.WriteFile(slowPath, []byte("next"), cacheFilePerm))
+	assert.NoError(t, unlock())
+
+	raw, err := os.ReadFile(slowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "next", string(raw))
+
+	// a lock replaced after it was found stale is restored
+	takeOverLockFile(slowPath, "waiter")
+	raw, err = os.ReadFile(slowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "next", string(raw))
+	assert.NoFileExists(t, slowPath+".waiter")
+}
+
+func TestKeyValueTokenStore(t *testing.T) {
+	t.Parallel()
+
+	kv := newMapKeyValueStore()
+	testTokenStore(t, NewKeyValueTokenStore(kv, "asa:"))
+
+	store := NewKeyValueTokenStore(kv, "asa:")
+	ctx := context.Background()
+
+	assert.NoError(t, store.Store(ctx, "ttl", &StoredToken{AccessToken: "a", ExpiresAfter: time.Now().Add(time.Hour)}))
+	assert.InDelta(t, time.Hour.Seconds(), kv.ttls["asa:ttl"].Seconds(), 1)
+
+	// an expired token is not stored without expiry, and drops the stored one
+	assert.NoError(t, store.Store(ctx, "ttl", &StoredToken{AccessToken: "b", ExpiresAfter: time.Now().Add(-time.Second)}))
+	_, err := store.Load(ctx, "ttl")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	// a process whose lock expired does not release the lock of the next holder
+	unlock, err := store.Lock(ctx, "slow")
+	assert.NoError(t, err)
+	assert.NoError(t, kv.Set(ctx, "asa:slow"+tokenLockExtension, []byte("next"), tokenLockStaleAfter))
+	assert.NoError(t, unlock())
+
+	value, err := kv.Get(ctx, "asa:slow"+tokenLockExtension)
+	assert.NoError(t, err)
+	assert.Equal(t, "next", string(value))
+}
+
+func TestAuthTransportSharedTokenStore(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, `{"access_token":"shared","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	store, err := NewFileTokenStore(t.TempDir())
+	assert.NoError(t, err)
+
+	// every transport stands for a process using the same client
+	transports := make([]*AuthTransport, 3)
+	for i := range transports {
+		transports[i], err = NewAuthTransport(WithClientID("client"), WithClientSecret("secret"), WithTokenURL(server.URL), WithTokenStore(store))
+		assert.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+
+		go func(transport *AuthTransport) {
+			defer wg.Done()
+
+			token, err := transport.jwtGenerator.AccessToken(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "shared", token)
+		}(transports[i%len(transports)])
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	stored, err := store.Load(context.Background(), tokenStoreKey("client"))
+	assert.NoError(t, err)
+	assert.Equal(t, "shared", stored.AccessToken)
+}
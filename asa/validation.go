@@ -0,0 +1,469 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxKeywordLength = 80
+	maxDaypartHour   = 167
+	minTargetAge     = 18
+	maxTargetAge     = 65
+	currencyLength   = 3
+)
+
+// ErrValidation happens when a request payload is rejected locally, before it is sent to the API.
+var ErrValidation = errors.New("invalid request")
+
+// FieldError is a single problem of a request payload. Pointer locates the field in the JSON body of the request,
+// as defined by RFC 6901, e.g. "/campaign/budgetAmount/currency" or "/2/text" for the third keyword of a bulk request.
+type FieldError struct {
+	Pointer string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Message)
+}
+
+// ValidationError holds every problem found in a request payload.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrValidation.Error(), strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrValidation) match every ValidationError.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Validator is implemented by request payloads that can be checked locally before they are sent.
+type Validator interface {
+	Validate() error
+}
+
+// fieldValidator validates a payload nested at pointer in a request body.
+type fieldValidator interface {
+	validate(pointer string, errs *fieldErrors)
+}
+
+// amountCarrier is implemented by payloads holding money amounts that must share a currency.
+type amountCarrier interface {
+	amounts(pointer string) []namedAmount
+}
+
+type namedAmount struct {
+	pointer string
+	money   *Money
+}
+
+type fieldErrors []*FieldError
+
+func (e *fieldErrors) add(pointer string, format string, args ...interface{}) {
+	*e = append(*e, &FieldError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e fieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: e}
+}
+
+// jsonPointer appends reference tokens to pointer, escaping them as required by RFC 6901.
+func jsonPointer(pointer string, tokens ...interface{}) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range tokens {
+		pointer += "/" + escaper.Replace(fmt.Sprint(token))
+	}
+
+	return pointer
+}
+
+func validateFields(v fieldValidator) error {
+	var errs fieldErrors
+
+	v.validate("", &errs)
+
+	return errs.err()
+}
+
+// eachPayload calls fn with the pointer of body, or of every element when body is a slice of payloads as sent by
+// the bulk endpoints.
+func eachPayload(body interface{}, fn func(pointer string, payload interface{})) {
+	value := reflect.ValueOf(body)
+	if value.Kind() != reflect.Slice {
+		fn("", body)
+
+		return
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		fn(jsonPointer("", i), value.Index(i).Interface())
+	}
+}
+
+// validateRequest validates a request body, which is either a Validator or a slice of them. Bodies that cannot be
+// validated are accepted.
+func validateRequest(body interface{}) error {
+	var errs fieldErrors
+
+	eachPayload(body, func(pointer string, payload interface{}) {
+		if v, ok := payload.(fieldValidator); ok && !reflect.ValueOf(payload).IsNil() {
+			v.validate(pointer, &errs)
+		}
+	})
+
+	return errs.err()
+}
+
+// ValidateCurrency checks that every money amount of a request uses currency, which is usually the currency of the
+// campaign the request applies to, as Apple rejects bids and budgets in any other currency. The request may be any
+// payload of this package holding amounts, or a slice of them such as the keywords of a bulk request.
+func ValidateCurrency(request interface{}, currency string) error {
+	var errs fieldErrors
+
+	eachPayload(request, func(pointer string, payload interface{}) {
+		carrier, ok := payload.(amountCarrier)
+		if !ok || reflect.ValueOf(payload).IsNil() {
+			return
+		}
+
+		for _, amount := range carrier.amounts(pointer) {
+			if amount.money.Currency != currency {
+				errs.add(jsonPointer(amount.pointer, "currency"), "is %q, the campaign uses %q", amount.money.Currency, currency)
+			}
+		}
+	})
+
+	return errs.err()
+}
+
+// SetValidation makes the client validate the payload of every mutating request before sending it. Invalid requests
+// fail with a *ValidationError and never reach the API.
+func (c *Client) SetValidation(flag bool) {
+	c.validate = flag
+}
+
+func validateMoney(pointer string, money *Money, errs *fieldErrors) {
+	if amount, err := strconv.ParseFloat(money.Amount, 64); err != nil {
+		errs.add(jsonPointer(pointer, "amount"), "%q is not a decimal amount", money.Amount)
+	} else if amount < 0 {
+		errs.add(jsonPointer(pointer, "amount"), "must not be negative")
+	}
+
+	if len(money.Currency) != currencyLength || strings.ToUpper(money.Currency) != money.Currency {
+		errs.add(jsonPointer(pointer, "currency"), "%q is not an ISO 4217 currency code", money.Currency)
+	}
+}
+
+// validateAmounts validates every amount and checks that they share a single currency.
+func validateAmounts(amounts []namedAmount, errs *fieldErrors) {
+	for i, amount := range amounts {
+		validateMoney(amount.pointer, amount.money, errs)
+
+		if i > 0 && amount.money.Currency != amounts[0].money.Currency {
+			errs.add(jsonPointer(amount.pointer, "currency"), "%q does not match the %q of %s",
+				amount.money.Currency, amounts[0].money.Currency, amounts[0].pointer)
+		}
+	}
+}
+
+func appendAmount(amounts []namedAmount, pointer string, money *Money) []namedAmount {
+	if money == nil {
+		return amounts
+	}
+
+	return append(amounts, namedAmount{pointer: pointer, money: money})
+}
+
+func validateTimeRange(pointer string, start *DateTime, end *DateTime, errs *fieldErrors) {
+	if start == nil || end == nil || start.IsZero() || end.IsZero() {
+		return
+	}
+
+	if end.Before(start.Time) {
+		errs.add(jsonPointer(pointer, "endTime"), "must not be before startTime")
+	}
+}
+
+func validateCountries(pointer string, countries []string, errs *fieldErrors) {
+	for i, country := range countries {
+		if country == "" {
+			errs.add(jsonPointer(pointer, i), "must not be empty")
+		}
+	}
+}
+
+func validateMatchType(pointer string, matchType KeywordMatchType, errs *fieldErrors) {
+	switch matchType {
+	case KeywordMatchTypeBroad, KeywordMatchTypeExact:
+	default:
+		errs.add(pointer, "%q is not a supported match type", matchType)
+	}
+}
+
+func validateKeywordText(pointer string, text string, errs *fieldErrors) {
+	switch length := utf8.RuneCountInString(text); {
+	case strings.TrimSpace(text) == "":
+		errs.add(pointer, "is required")
+	case length > maxKeywordLength:
+		errs.add(pointer, "is %d characters long, the limit is %d", length, maxKeywordLength)
+	}
+}
+
+// Validate checks a campaign before it is created.
+func (c *Campaign) Validate() error {
+	return validateFields(c)
+}
+
+func (c *Campaign) amounts(pointer string) []namedAmount {
+	amounts := appendAmount(nil, jsonPointer(pointer, "budgetAmount"), c.BudgetAmount)
+
+	return appendAmount(amounts, jsonPointer(pointer, "dailyBudgetAmount"), c.DailyBudgetAmount)
+}
+
+func (c *Campaign) validate(pointer string, errs *fieldErrors) {
+	if strings.TrimSpace(c.Name) == "" {
+		errs.add(jsonPointer(pointer, "name"), "is required")
+	}
+
+	if c.AdamID <= 0 {
+		errs.add(jsonPointer(pointer, "adamId"), "is required")
+	}
+
+	if len(c.CountriesOrRegions) == 0 {
+		errs.add(jsonPointer(pointer, "countriesOrRegions"), "must include at least one country or region")
+	}
+
+	validateCountries(jsonPointer(pointer, "countriesOrRegions"), c.CountriesOrRegions, errs)
+	validateAmounts(c.amounts(pointer), errs)
+	validateTimeRange(pointer, &c.StartTime, c.EndTime, errs)
+}
+
+// Validate checks a campaign update before it is sent.
+func (r *UpdateCampaignRequest) Validate() error {
+	return validateFields(r)
+}
+
+func (r *UpdateCampaignRequest) amounts(pointer string) []namedAmount {
+	if r.Campaign == nil {
+		return nil
+	}
+
+	return r.Campaign.amounts(jsonPointer(pointer, "campaign"))
+}
+
+func (r *UpdateCampaignRequest) validate(pointer string, errs *fieldErrors) {
+	if r.Campaign == nil {
+		errs.add(jsonPointer(pointer, "campaign"), "is required")
+
+		return
+	}
+
+	r.Campaign.validate(jsonPointer(pointer, "campaign"), errs)
+}
+
+// Validate checks the updated fields of a campaign.
+func (c *CampaignUpdate) Validate() error {
+	return validateFields(c)
+}
+
+func (c *CampaignUpdate) amounts(pointer string) []namedAmount {
+	amounts := appendAmount(nil, jsonPointer(pointer, "budgetAmount"), c.BudgetAmount)
+
+	return appendAmount(amounts, jsonPointer(pointer, "dailyBudgetAmount"), c.DailyBudgetAmount)
+}
+
+func (c *CampaignUpdate) validate(pointer string, errs *fieldErrors) {
+	validateCountries(jsonPointer(pointer, "countriesOrRegions"), c.CountriesOrRegions, errs)
+	validateAmounts(c.amounts(pointer), errs)
+}
+
+// Validate checks an ad group before it is created.
+func (a *AdGroup) Validate() error {
+	return validateFields(a)
+}
+
+func (a *AdGroup) amounts(pointer string) []namedAmount {
+	amounts := appendAmount(nil, jsonPointer(pointer, "defaultBidAmount"), a.DefaultBidAmount)
+
+	return appendAmount(amounts, jsonPointer(pointer, "cpaGoal"), a.CpaGoal)
+}
+
+func (a *AdGroup) validate(pointer string, errs *fieldErrors) {
+	if strings.TrimSpace(a.Name) == "" {
+		errs.add(jsonPointer(pointer, "name"), "is required")
+	}
+
+	if a.DefaultBidAmount == nil {
+		errs.add(jsonPointer(pointer, "defaultBidAmount"), "is required")
+	}
+
+	validateAmounts(a.amounts(pointer), errs)
+	validateTimeRange(pointer, &a.StartTime, &a.EndTime, errs)
+
+	if a.TargetDimensions != nil {
+		a.TargetDimensions.validate(jsonPointer(pointer, "targetDimensions"), errs)
+	}
+}
+
+// Validate checks an ad group update before it is sent.
+func (r *AdGroupUpdateRequest) Validate() error {
+	return validateFields(r)
+}
+
+func (r *AdGroupUpdateRequest) amounts(pointer string) []namedAmount {
+	amounts := appendAmount(nil, jsonPointer(pointer, "defaultBidAmount"), r.DefaultBidAmount)
+
+	return appendAmount(amounts, jsonPointer(pointer, "cpaGoal"), r.CpaGoal)
+}
+
+func (r *AdGroupUpdateRequest) validate(pointer string, errs *fieldErrors) {
+	validateAmounts(r.amounts(pointer), errs)
+	validateTimeRange(pointer, r.StartTime, r.EndTime, errs)
+
+	if r.TargetingDimensions != nil {
+		r.TargetingDimensions.validate(jsonPointer(pointer, "targetingDimensions"), errs)
+	}
+}
+
+// Validate checks the targeting criteria of an ad group.
+func (t *TargetDimensions) Validate() error {
+	return validateFields(t)
+}
+
+func (t *TargetDimensions) validate(pointer string, errs *fieldErrors) {
+	if t.Age != nil {
+		for i, ageRange := range t.Age.Included {
+			if ageRange != nil {
+				ageRange.validate(jsonPointer(pointer, "age", "included", i), errs)
+			}
+		}
+	}
+
+	if t.DayPart != nil && t.DayPart.UserTime != nil {
+		for i, hour := range t.DayPart.UserTime.Included {
+			if hour < 0 || hour > maxDaypartHour {
+				errs.add(jsonPointer(pointer, "daypart", "userTime", "included", i),
+					"hour %d of the week is outside 0-%d", hour, maxDaypartHour)
+			}
+		}
+	}
+
+	if t.DeviceClass != nil {
+		for i, deviceClass := range t.DeviceClass.Included {
+			if deviceClass != AdGroupDeviceClassIpad && deviceClass != AdGroupDeviceClassIphone {
+				errs.add(jsonPointer(pointer, "deviceClass", "included", i), "%q is not a device class", deviceClass)
+			}
+		}
+	}
+
+	if t.Gender != nil {
+		for i, gender := range t.Gender.Included {
+			if gender != AdGroupGenderFemale && gender != AdGroupGenderMale {
+				errs.add(jsonPointer(pointer, "gender", "included", i), "%q is not a gender", gender)
+			}
+		}
+	}
+}
+
+func (r *AgeRange) validate(pointer string, errs *fieldErrors) {
+	if r.MinAge != 0 && (r.MinAge < minTargetAge || r.MinAge > maxTargetAge) {
+		errs.add(jsonPointer(pointer, "minAge"), "%d is outside %d-%d", r.MinAge, minTargetAge, maxTargetAge)
+	}
+
+	if r.MaxAge != 0 && (r.MaxAge < minTargetAge || r.MaxAge > maxTargetAge) {
+		errs.add(jsonPointer(pointer, "maxAge"), "%d is outside %d-%d", r.MaxAge, minTargetAge, maxTargetAge)
+	}
+
+	if r.MinAge != 0 && r.MaxAge != 0 && r.MinAge > r.MaxAge {
+		errs.add(jsonPointer(pointer, "minAge"), "%d is greater than maxAge %d", r.MinAge, r.MaxAge)
+	}
+}
+
+// Validate checks a targeting keyword before it is created.
+func (k *Keyword) Validate() error {
+	return validateFields(k)
+}
+
+func (k *Keyword) amounts(pointer string) []namedAmount {
+	if k.BidAmount == (Money{}) {
+		return nil
+	}
+
+	return []namedAmount{{pointer: jsonPointer(pointer, "bidAmount"), money: &k.BidAmount}}
+}
+
+func (k *Keyword) validate(pointer string, errs *fieldErrors) {
+	validateKeywordText(jsonPointer(pointer, "text"), k.Text, errs)
+	validateMatchType(jsonPointer(pointer, "matchType"), k.MatchType, errs)
+	validateAmounts(k.amounts(pointer), errs)
+}
+
+// Validate checks a targeting keyword update before it is sent.
+func (r *KeywordUpdateRequest) Validate() error {
+	return validateFields(r)
+}
+
+func (r *KeywordUpdateRequest) amounts(pointer string) []namedAmount {
+	return appendAmount(nil, jsonPointer(pointer, "bidAmount"), r.BidAmount)
+}
+
+func (r *KeywordUpdateRequest) validate(pointer string, errs *fieldErrors) {
+	if r.ID <= 0 {
+		errs.add(jsonPointer(pointer, "id"), "is required")
+	}
+
+	if r.MatchType != "" {
+		validateMatchType(jsonPointer(pointer, "matchType"), r.MatchType, errs)
+	}
+
+	validateAmounts(r.amounts(pointer), errs)
+}
+
+// Validate checks a negative keyword. New keywords need a text and a match type, while updates of existing ones,
+// identified by their ID, may omit them.
+func (k *NegativeKeyword) Validate() error {
+	return validateFields(k)
+}
+
+func (k *NegativeKeyword) validate(pointer string, errs *fieldErrors) {
+	if k.ID == 0 || k.Text != "" {
+		validateKeywordText(jsonPointer(pointer, "text"), k.Text, errs)
+	}
+
+	if k.ID == 0 || k.MatchType != "" {
+		validateMatchType(jsonPointer(pointer, "matchType"), k.MatchType, errs)
+	}
+}
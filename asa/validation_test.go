@@ -0,0 +1,187 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldPointers(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a *ValidationError, got %v", err)
+	}
+
+	pointers := make([]string, len(validationErr.Errors))
+	for i, fieldErr := range validationErr.Errors {
+		pointers[i] = fieldErr.Pointer
+	}
+
+	return pointers
+}
+
+func TestCampaignValidate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := DateTime{start.Add(-time.Hour)}
+
+	campaign := &Campaign{
+		Name:              "Campaign",
+		BudgetAmount:      &Money{Amount: "100", Currency: "USD"},
+		DailyBudgetAmount: &Money{Amount: "ten", Currency: "EUR"},
+		StartTime:         DateTime{start},
+		EndTime:           &end,
+	}
+
+	err := campaign.Validate()
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, []string{
+		"/adamId",
+		"/countriesOrRegions",
+		"/dailyBudgetAmount/amount",
+		"/dailyBudgetAmount/currency",
+		"/endTime",
+	}, fieldPointers(t, err))
+
+	valid := &Campaign{Name: "Campaign", AdamID: 1, CountriesOrRegions: []string{"US"}, DailyBudgetAmount: &Money{Amount: "10", Currency: "USD"}}
+	assert.NoError(t, valid.Validate())
+}
+
+func TestUpdateCampaignRequestValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"/campaign"}, fieldPointers(t, (&UpdateCampaignRequest{}).Validate()))
+
+	req := &UpdateCampaignRequest{Campaign: &CampaignUpdate{BudgetAmount: &Money{Amount: "-1", Currency: "usd"}}}
+	assert.Equal(t, []string{"/campaign/budgetAmount/amount", "/campaign/budgetAmount/currency"}, fieldPointers(t, req.Validate()))
+}
+
+func TestAdGroupValidate(t *testing.T) {
+	t.Parallel()
+
+	adGroup := &AdGroup{
+		Name:             "Ad Group",
+		DefaultBidAmount: &Money{Amount: "1", Currency: "USD"},
+		CpaGoal:          &Money{Amount: "2", Currency: "GBP"},
+		TargetDimensions: &TargetDimensions{
+			Age:     &AgeCriteria{Included: []*AgeRange{{MinAge: 40, MaxAge: 30}, {MinAge: 12}}},
+			DayPart: &DayPartCriteria{UserTime: &DaypartDetail{Included: []int32{0, 167, 168}}},
+			Gender:  &GenderCriteria{Included: []AdGroupGender{"X"}},
+		},
+	}
+
+	err := adGroup.Validate()
+	assert.Equal(t, []string{
+		"/cpaGoal/currency",
+		"/targetDimensions/age/included/0/minAge",
+		"/targetDimensions/age/included/1/minAge",
+		"/targetDimensions/daypart/userTime/included/2",
+		"/targetDimensions/gender/included/0",
+	}, fieldPointers(t, err))
+	assert.Contains(t, err.Error(), `"GBP" does not match the "USD" of /defaultBidAmount`)
+
+	assert.Equal(t, []string{"/name", "/defaultBidAmount"}, fieldPointers(t, (&AdGroup{}).Validate()))
+}
+
+func TestAdGroupUpdateRequestValidate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	req := &AdGroupUpdateRequest{
+		StartTime:           &DateTime{start},
+		EndTime:             &DateTime{start.Add(-time.Minute)},
+		TargetingDimensions: &TargetDimensions{DeviceClass: &DeviceClassCriteria{Included: []AdGroupDeviceClass{"WATCH"}}},
+	}
+
+	assert.Equal(t, []string{"/endTime", "/targetingDimensions/deviceClass/included/0"}, fieldPointers(t, req.Validate()))
+	assert.NoError(t, (&AdGroupUpdateRequest{Name: "Renamed"}).Validate())
+}
+
+func TestKeywordValidate(t *testing.T) {
+	t.Parallel()
+
+	keyword := &Keyword{Text: strings.Repeat("a", maxKeywordLength+1), MatchType: "Phrase"}
+	assert.Equal(t, []string{"/text", "/matchType"}, fieldPointers(t, keyword.Validate()))
+
+	keyword = &Keyword{Text: strings.Repeat("é", maxKeywordLength), MatchType: KeywordMatchTypeExact, BidAmount: Money{Amount: "1.5", Currency: "USD"}}
+	assert.NoError(t, keyword.Validate())
+
+	assert.Equal(t, []string{"/id", "/matchType"}, fieldPointers(t, (&KeywordUpdateRequest{MatchType: "Phrase"}).Validate()))
+	assert.NoError(t, (&KeywordUpdateRequest{ID: 1, Status: KeywordStatusPaused}).Validate())
+}
+
+func TestNegativeKeywordValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"/text", "/matchType"}, fieldPointers(t, (&NegativeKeyword{}).Validate()))
+	assert.NoError(t, (&NegativeKeyword{ID: 1, Deleted: true}).Validate())
+}
+
+func TestValidateCurrency(t *testing.T) {
+	t.Parallel()
+
+	keywords := []*Keyword{
+		{Text: "photo", MatchType: KeywordMatchTypeExact, BidAmount: Money{Amount: "1", Currency: "USD"}},
+		nil,
+		{Text: "video", MatchType: KeywordMatchTypeExact, BidAmount: Money{Amount: "1", Currency: "EUR"}},
+	}
+
+	err := ValidateCurrency(keywords, "USD")
+	assert.Equal(t, []string{"/2/bidAmount/currency"}, fieldPointers(t, err))
+	assert.Contains(t, err.Error(), `"EUR", the campaign uses "USD"`)
+
+	req := &UpdateCampaignRequest{Campaign: &CampaignUpdate{DailyBudgetAmount: &Money{Amount: "5", Currency: "USD"}}}
+	assert.NoError(t, ValidateCurrency(req, "USD"))
+}
+
+func TestJSONPointerEscaping(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/a~1b/m~0n/0", jsonPointer("", "a/b", "m~n", 0))
+}
+
+func TestClientValidation(t *testing.T) {
+	t.Parallel()
+
+	client, server := newServer(`{"data":[]}`, http.StatusOK, false)
+	defer server.Close()
+
+	keywords := []*Keyword{{Text: "photo", MatchType: "Phrase"}}
+
+	_, _, err := client.Keywords.CreateTargetingKeywords(context.Background(), 1, 2, keywords)
+	assert.NoError(t, err)
+
+	client.SetValidation(true)
+
+	_, resp, err := client.Keywords.CreateTargetingKeywords(context.Background(), 1, 2, keywords)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Nil(t, resp)
+	assert.Equal(t, []string{"/0/matchType"}, fieldPointers(t, err))
+
+	_, _, err = client.Keywords.FindTargetingKeywords(context.Background(), 1, &Selector{})
+	assert.NoError(t, err)
+}
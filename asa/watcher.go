@@ -0,0 +1,724 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 5 * time.Minute
+	cursorFilePerm       = 0o600
+)
+
+// WatchEntityType is the kind of entity a Watcher polls.
+type WatchEntityType string
+
+const (
+	// WatchEntityTypeCampaign is for campaigns.
+	WatchEntityTypeCampaign WatchEntityType = "CAMPAIGN"
+	// WatchEntityTypeAdGroup is for ad groups.
+	WatchEntityTypeAdGroup WatchEntityType = "AD_GROUP"
+	// WatchEntityTypeTargetingKeyword is for targeting keywords.
+	WatchEntityTypeTargetingKeyword WatchEntityType = "TARGETING_KEYWORD"
+	// WatchEntityTypeNegativeKeyword is for campaign negative keywords.
+	WatchEntityTypeNegativeKeyword WatchEntityType = "NEGATIVE_KEYWORD"
+	// WatchEntityTypeAdGroupNegativeKeyword is for ad group negative keywords.
+	WatchEntityTypeAdGroupNegativeKeyword WatchEntityType = "AD_GROUP_NEGATIVE_KEYWORD"
+	// WatchEntityTypeAdGroupCreativeSet is for Creative Sets assigned to ad groups.
+	WatchEntityTypeAdGroupCreativeSet WatchEntityType = "AD_GROUP_CREATIVE_SET"
+)
+
+// WatchEntityTypes returns every entity type a Watcher can poll, campaigns first.
+func WatchEntityTypes() []WatchEntityType {
+	return []WatchEntityType{
+		WatchEntityTypeCampaign,
+		WatchEntityTypeAdGroup,
+		WatchEntityTypeTargetingKeyword,
+		WatchEntityTypeNegativeKeyword,
+		WatchEntityTypeAdGroupNegativeKeyword,
+		WatchEntityTypeAdGroupCreativeSet,
+	}
+}
+
+// ChangeType is the kind of change a ChangeEvent reports.
+type ChangeType string
+
+const (
+	// ChangeTypeCreated is for an entity the watcher has not seen before.
+	ChangeTypeCreated ChangeType = "CREATED"
+	// ChangeTypeUpdated is for a known entity with a newer modification time.
+	ChangeTypeUpdated ChangeType = "UPDATED"
+	// ChangeTypeDeleted is for an entity that is now flagged as deleted.
+	ChangeTypeDeleted ChangeType = "DELETED"
+)
+
+// ChangeEvent is a change of an entity found by a Watcher. Exactly one of the entity fields is set, matching
+// EntityType.
+type ChangeEvent struct {
+	Type             ChangeType      `json:"type"`
+	EntityType       WatchEntityType `json:"entityType"`
+	ID               int64           `json:"id"`
+	CampaignID       int64           `json:"campaignId,omitempty"`
+	AdGroupID        int64           `json:"adGroupId,omitempty"`
+	ModificationTime time.Time       `json:"modificationTime"`
+
+	Campaign           *Campaign           `json:"campaign,omitempty"`
+	AdGroup            *AdGroup            `json:"adGroup,omitempty"`
+	Keyword            *Keyword            `json:"keyword,omitempty"`
+	NegativeKeyword    *NegativeKeyword    `json:"negativeKeyword,omitempty"`
+	AdGroupCreativeSet *AdGroupCreativeSet `json:"adGroupCreativeSet,omitempty"`
+}
+
+// WatchState is the cursor of a Watcher: the latest modification time seen and the known entities for each polled
+// entity type and campaign. Deleted entities, the entities of deleted ad groups and the state of campaigns that are
+// no longer watched are pruned, so the state only grows with the live entities.
+type WatchState struct {
+	Initialized bool                              `json:"initialized"`
+	Cursors     map[string]time.Time              `json:"cursors,omitempty"`
+	Known       map[string]map[int64]*KnownEntity `json:"known,omitempty"`
+}
+
+// KnownEntity is an entity seen by a Watcher and not deleted since.
+type KnownEntity struct {
+	AdGroupID        int64     `json:"adGroupId,omitempty"`
+	ModificationTime time.Time `json:"modificationTime"`
+}
+
+func newWatchState() *WatchState {
+	return &WatchState{
+		Cursors: make(map[string]time.Time),
+		Known:   make(map[string]map[int64]*KnownEntity),
+	}
+}
+
+// CursorStore persists the WatchState of a Watcher between polls and restarts.
+type CursorStore interface {
+	// Load returns the saved state, or an empty state when nothing was saved yet.
+	Load(ctx context.Context) (*WatchState, error)
+	Save(ctx context.Context, state *WatchState) error
+}
+
+// MemoryCursorStore is a CursorStore that keeps the state in memory.
+type MemoryCursorStore struct {
+	mu    sync.Mutex
+	state []byte
+}
+
+// NewMemoryCursorStore creates an empty MemoryCursorStore.
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{}
+}
+
+// Load returns a copy of the saved state.
+func (s *MemoryCursorStore) Load(ctx context.Context) (*WatchState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == nil {
+		return newWatchState(), nil
+	}
+
+	state := &WatchState{}
+
+	return state, json.Unmarshal(s.state, state)
+}
+
+// Save keeps a copy of state.
+func (s *MemoryCursorStore) Save(ctx context.Context, state *WatchState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.state = raw
+	s.mu.Unlock()
+
+	return nil
+}
+
+// FileCursorStore is a CursorStore that keeps the state in a JSON file.
+type FileCursorStore struct {
+	path string
+}
+
+// NewFileCursorStore creates a FileCursorStore writing to path.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+// Load reads the state file. A missing file is an empty state.
+func (s *FileCursorStore) Load(ctx context.Context) (*WatchState, error) {
+	raw, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return newWatchState(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state := &WatchState{}
+
+	return state, json.Unmarshal(raw, state)
+}
+
+// Save writes the state to a temporary file and renames it over the state file, so that a crash never leaves a
+// partially written cursor behind.
+func (s *FileCursorStore) Save(ctx context.Context, state *WatchState) error {
+	raw, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), cursorFilePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Watcher polls the find endpoints for entities modified since the last poll and turns them into change events.
+//
+// Changes are detected through the modificationTime of each entity, so entities that the API stops returning
+// altogether are not reported; entities flagged as deleted are reported as ChangeTypeDeleted.
+type Watcher struct {
+	client *Client
+	store  CursorStore
+
+	// CampaignIDs limits the watched campaigns. When empty, every campaign seen by the watcher is watched, which
+	// requires WatchEntityTypeCampaign among the EntityTypes.
+	CampaignIDs []int64
+	// EntityTypes are the entity types to poll and default to WatchEntityTypes.
+	EntityTypes []WatchEntityType
+	// Interval is the time between polls of Run and Watch and defaults to 5 minutes.
+	Interval time.Duration
+	// EmitInitial emits a created event for every existing entity on the first poll instead of only recording them.
+	EmitInitial bool
+}
+
+// NewWatcher creates a Watcher for client persisting its cursor in store. A nil store keeps the cursor in memory.
+func NewWatcher(client *Client, store CursorStore) *Watcher {
+	if store == nil {
+		store = NewMemoryCursorStore()
+	}
+
+	return &Watcher{
+		client:   client,
+		store:    store,
+		Interval: defaultWatchInterval,
+	}
+}
+
+// watchedEntity is the part of an entity the watcher needs to detect changes.
+type watchedEntity struct {
+	id               int64
+	campaignID       int64
+	adGroupID        int64
+	deleted          bool
+	modificationTime time.Time
+	event            func(event *ChangeEvent)
+}
+
+// watchFinder fetches one page of entities matching selector.
+type watchFinder func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error)
+
+// Poll fetches every entity modified since the previous poll, updates and saves the cursor and returns the
+// resulting events ordered by modification time within each entity type.
+//
+// The cursor is saved before the events are returned, so events the caller fails to process are not returned again:
+// Poll delivers at most once. Run and Watch save the cursor only after every event was handled.
+func (w *Watcher) Poll(ctx context.Context) ([]*ChangeEvent, error) {
+	events, state, err := w.poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, w.store.Save(ctx, state)
+}
+
+// poll fetches the changes since the stored cursor and returns the events and the updated state, without saving it.
+func (w *Watcher) poll(ctx context.Context) ([]*ChangeEvent, *WatchState, error) {
+	state, err := w.store.Load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if state.Cursors == nil {
+		state.Cursors = make(map[string]time.Time)
+	}
+
+	if state.Known == nil {
+		state.Known = make(map[string]map[int64]*KnownEntity)
+	}
+
+	var changes []*ChangeEvent
+
+	for _, entityType := range w.entityTypes() {
+		if entityType == WatchEntityTypeCampaign {
+			campaignChanges, err := w.pollKey(ctx, state, string(entityType), entityType, w.campaignFinder())
+			if err != nil {
+				return nil, nil, err
+			}
+
+			changes = append(changes, campaignChanges...)
+
+			continue
+		}
+
+		for _, campaignID := range w.campaignIDs(state) {
+			key := watchKey(entityType, campaignID)
+
+			entityChanges, err := w.pollKey(ctx, state, key, entityType, w.finder(entityType, campaignID))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			changes = append(changes, entityChanges...)
+		}
+	}
+
+	w.prune(state, changes)
+
+	emit := state.Initialized || w.EmitInitial
+	state.Initialized = true
+
+	if !emit {
+		return nil, state, nil
+	}
+
+	return changes, state, nil
+}
+
+func watchKey(entityType WatchEntityType, campaignID int64) string {
+	return fmt.Sprintf("%s/%d", entityType, campaignID)
+}
+
+// prune drops the known entities of the ad groups deleted in changes and the state of the campaigns that are no
+// longer watched.
+func (w *Watcher) prune(state *WatchState, changes []*ChangeEvent) {
+	deletedAdGroups := make(map[int64]map[int64]bool)
+
+	for _, change := range changes {
+		if change.Type != ChangeTypeDeleted || change.EntityType != WatchEntityTypeAdGroup {
+			continue
+		}
+
+		if deletedAdGroups[change.CampaignID] == nil {
+			deletedAdGroups[change.CampaignID] = make(map[int64]bool)
+		}
+
+		deletedAdGroups[change.CampaignID][change.ID] = true
+	}
+
+	for campaignID, adGroups := range deletedAdGroups {
+		for _, entityType := range WatchEntityTypes() {
+			if entityType == WatchEntityTypeCampaign || entityType == WatchEntityTypeAdGroup {
+				continue
+			}
+
+			for id, known := range state.Known[watchKey(entityType, campaignID)] {
+				if adGroups[known.AdGroupID] {
+					delete(state.Known[watchKey(entityType, campaignID)], id)
+				}
+			}
+		}
+	}
+
+	watched := make(map[string]bool)
+	for _, campaignID := range w.campaignIDs(state) {
+		for _, entityType := range WatchEntityTypes() {
+			watched[watchKey(entityType, campaignID)] = true
+		}
+	}
+
+	for key := range state.Cursors {
+		if key != string(WatchEntityTypeCampaign) && !watched[key] {
+			delete(state.Cursors, key)
+		}
+	}
+
+	for key := range state.Known {
+		if key != string(WatchEntityTypeCampaign) && !watched[key] {
+			delete(state.Known, key)
+		}
+	}
+}
+
+func (w *Watcher) entityTypes() []WatchEntityType {
+	if len(w.EntityTypes) > 0 {
+		return w.EntityTypes
+	}
+
+	return WatchEntityTypes()
+}
+
+// campaignIDs returns the configured campaigns, or else the campaigns known to the watcher.
+func (w *Watcher) campaignIDs(state *WatchState) []int64 {
+	if len(w.CampaignIDs) > 0 {
+		return w.CampaignIDs
+	}
+
+	known := state.Known[string(WatchEntityTypeCampaign)]
+
+	ids := make([]int64, 0, len(known))
+	for id := range known {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (w *Watcher) pollKey(ctx context.Context, state *WatchState, key string, entityType WatchEntityType, find watchFinder) ([]*ChangeEvent, error) {
+	cursor := state.Cursors[key]
+
+	entities, err := findModifiedSince(ctx, cursor, find)
+	if err != nil {
+		return nil, err
+	}
+
+	known := state.Known[key]
+	if known == nil {
+		known = make(map[int64]*KnownEntity)
+		state.Known[key] = known
+	}
+
+	var events []*ChangeEvent
+
+	for _, entity := range entities {
+		if entity.modificationTime.After(cursor) {
+			cursor = entity.modificationTime
+		}
+
+		previous, seen := known[entity.id]
+		if seen && !entity.modificationTime.After(previous.ModificationTime) {
+			continue
+		}
+
+		event := &ChangeEvent{
+			Type:             ChangeTypeUpdated,
+			EntityType:       entityType,
+			ID:               entity.id,
+			CampaignID:       entity.campaignID,
+			AdGroupID:        entity.adGroupID,
+			ModificationTime: entity.modificationTime,
+		}
+		entity.event(event)
+
+		switch {
+		case entity.deleted:
+			delete(known, entity.id)
+
+			if !seen {
+				continue
+			}
+
+			event.Type = ChangeTypeDeleted
+		case !seen:
+			known[entity.id] = &KnownEntity{AdGroupID: entity.adGroupID, ModificationTime: entity.modificationTime}
+			event.Type = ChangeTypeCreated
+		default:
+			known[entity.id] = &KnownEntity{AdGroupID: entity.adGroupID, ModificationTime: entity.modificationTime}
+		}
+
+		events = append(events, event)
+	}
+
+	state.Cursors[key] = cursor
+
+	return events, nil
+}
+
+// findModifiedSince pages through find for the entities modified after since, oldest first.
+func findModifiedSince(ctx context.Context, since time.Time, find watchFinder) ([]*watchedEntity, error) {
+	selector := &Selector{
+		OrderBy: []*Sorting{{Field: "modificationTime", SortOrder: SortingOrderAscending}},
+	}
+
+	if !since.IsZero() {
+		selector.Conditions = []*Condition{{
+			Field:    "modificationTime",
+			Operator: ConditionOperatorGreaterThan,
+			Values:   []string{since.Format(customISO8601Format)},
+		}}
+	}
+
+	var entities []*watchedEntity
+
+	for offset := 0; ; {
+		page, pagination, err := find(ctx, pagedSelector(selector, offset))
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, page...)
+
+		if !hasNextPage(pagination, offset, len(page)) {
+			break
+		}
+
+		offset += len(page)
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].modificationTime.Before(entities[j].modificationTime)
+	})
+
+	return entities, nil
+}
+
+func (w *Watcher) campaignFinder() watchFinder {
+	wanted := make(map[int64]bool, len(w.CampaignIDs))
+	for _, id := range w.CampaignIDs {
+		wanted[id] = true
+	}
+
+	return func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error) {
+		res, _, err := w.client.Campaigns.FindCampaigns(ctx, selector)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entities := make([]*watchedEntity, 0, len(res.Campaigns))
+
+		for _, campaign := range res.Campaigns {
+			if len(wanted) > 0 && !wanted[campaign.ID] {
+				continue
+			}
+
+			campaign := campaign
+			entities = append(entities, &watchedEntity{
+				id:               campaign.ID,
+				campaignID:       campaign.ID,
+				deleted:          campaign.Deleted,
+				modificationTime: campaign.ModificationTime.Time,
+				event:            func(e *ChangeEvent) { e.Campaign = campaign },
+			})
+		}
+
+		return entities, res.Pagination, nil
+	}
+}
+
+func (w *Watcher) finder(entityType WatchEntityType, campaignID int64) watchFinder {
+	switch entityType { // nolint: exhaustive
+	case WatchEntityTypeAdGroup:
+		return w.adGroupFinder(campaignID)
+	case WatchEntityTypeTargetingKeyword:
+		return w.keywordFinder(campaignID)
+	case WatchEntityTypeNegativeKeyword:
+		return w.negativeKeywordFinder(campaignID, w.client.Keywords.FindNegativeKeywords)
+	case WatchEntityTypeAdGroupNegativeKeyword:
+		return w.negativeKeywordFinder(campaignID, w.client.Keywords.FindAdGroupNegativeKeywords)
+	default:
+		return w.creativeSetFinder(campaignID)
+	}
+}
+
+func (w *Watcher) adGroupFinder(campaignID int64) watchFinder {
+	return func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error) {
+		res, _, err := w.client.AdGroups.FindAdGroups(ctx, campaignID, selector)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entities := make([]*watchedEntity, len(res.AdGroups))
+
+		for i, adGroup := range res.AdGroups {
+			adGroup := adGroup
+			entities[i] = &watchedEntity{
+				id:               adGroup.ID,
+				campaignID:       campaignID,
+				adGroupID:        adGroup.ID,
+				deleted:          adGroup.Deleted,
+				modificationTime: adGroup.ModificationTime.Time,
+				event:            func(e *ChangeEvent) { e.AdGroup = adGroup },
+			}
+		}
+
+		return entities, res.Pagination, nil
+	}
+}
+
+func (w *Watcher) keywordFinder(campaignID int64) watchFinder {
+	return func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error) {
+		res, _, err := w.client.Keywords.FindTargetingKeywords(ctx, campaignID, selector)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entities := make([]*watchedEntity, len(res.Keywords))
+
+		for i, keyword := range res.Keywords {
+			keyword := keyword
+			entities[i] = &watchedEntity{
+				id:               keyword.ID,
+				campaignID:       campaignID,
+				adGroupID:        keyword.AdGroupID,
+				deleted:          keyword.Deleted,
+				modificationTime: keyword.ModificationTime.Time,
+				event:            func(e *ChangeEvent) { e.Keyword = keyword },
+			}
+		}
+
+		return entities, res.Pagination, nil
+	}
+}
+
+type negativeKeywordFind func(ctx context.Context, campaignID int64, selector *Selector) (*NegativeKeywordListResponse, *Response, error)
+
+func (w *Watcher) negativeKeywordFinder(campaignID int64, find negativeKeywordFind) watchFinder {
+	return func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error) {
+		res, _, err := find(ctx, campaignID, selector)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entities := make([]*watchedEntity, len(res.Keywords))
+
+		for i, keyword := range res.Keywords {
+			keyword := keyword
+			entities[i] = &watchedEntity{
+				id:               keyword.ID,
+				campaignID:       campaignID,
+				adGroupID:        keyword.AdGroupID,
+				deleted:          keyword.Deleted,
+				modificationTime: keyword.ModificationTime.Time,
+				event:            func(e *ChangeEvent) { e.NegativeKeyword = keyword },
+			}
+		}
+
+		return entities, res.Pagination, nil
+	}
+}
+
+func (w *Watcher) creativeSetFinder(campaignID int64) watchFinder {
+	return func(ctx context.Context, selector *Selector) ([]*watchedEntity, *PageDetail, error) {
+		res, _, err := w.client.CreativeSets.FindAdGroupCreativeSets(ctx, campaignID, &FindAdGroupCreativeSetRequest{Selector: selector})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entities := make([]*watchedEntity, len(res.AdGroupCreativeSets))
+
+		for i, creativeSet := range res.AdGroupCreativeSets {
+			creativeSet := creativeSet
+			entities[i] = &watchedEntity{
+				id:               creativeSet.ID,
+				campaignID:       campaignID,
+				adGroupID:        creativeSet.AdGroupID,
+				deleted:          creativeSet.Deleted,
+				modificationTime: creativeSet.ModificationTime.Time,
+				event:            func(e *ChangeEvent) { e.AdGroupCreativeSet = creativeSet },
+			}
+		}
+
+		return entities, res.Pagination, nil
+	}
+}
+
+// Run polls immediately and then every Interval, calling handler for each event, until ctx is done or handler or a
+// poll returns an error. It returns ctx.Err() when the context ends the loop.
+//
+// The cursor is saved only once handler succeeded for every event of a poll, so delivery is at least once: when
+// handler fails or the process stops mid-poll, the events of that poll are delivered again by the next run.
+func (w *Watcher) Run(ctx context.Context, handler func(event *ChangeEvent) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		events, state, err := w.poll(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := handler(event); err != nil {
+				return err
+			}
+		}
+
+		if err := w.store.Save(ctx, state); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch runs the watcher in a goroutine and delivers its events on the returned channel. The channel is closed when
+// ctx is done or a poll fails; the failure, if any, is then sent on the error channel. As with Run, delivery is at
+// least once.
+func (w *Watcher) Watch(ctx context.Context) (<-chan *ChangeEvent, <-chan error) {
+	events := make(chan *ChangeEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		err := w.Run(ctx, func(event *ChangeEvent) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+
+	return events, errs
+}
@@ -0,0 +1,246 @@
+/**
+Copyright (C) 2021 Mehmet Gungoren.
+This file is part of apple-search-ads-go, a package for working with Apple's
+Search Ads API.
+apple-search-ads-go is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+apple-search-ads-go is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with apple-search-ads-go.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package asa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type watchServer struct {
+	mu     sync.Mutex
+	routes map[string]string
+	bodies map[string]string
+}
+
+func (s *watchServer) set(routes map[string]string) {
+	s.mu.Lock()
+	s.routes = routes
+	s.mu.Unlock()
+}
+
+func (s *watchServer) lastBody(route string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.bodies[route]
+}
+
+func newWatchServer() (*Client, *watchServer, func()) {
+	ws := &watchServer{bodies: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		route := r.Method + " " + r.URL.Path
+
+		ws.mu.Lock()
+		ws.bodies[route] = string(body)
+		raw, ok := ws.routes[route]
+		ws.mu.Unlock()
+
+		if !ok {
+			raw = `{"data":[]}`
+		}
+
+		fmt.Fprintln(w, raw)
+	}))
+
+	client, _ := NewClientWithOptions(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+
+	return client, ws, server.Close
+}
+
+func TestWatcherPoll(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newWatchServer()
+	defer closeServer()
+
+	server.set(map[string]string{
+		"POST /campaigns/find":            `{"data":[{"id":1,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+		"POST /campaigns/1/adgroups/find": `{"data":[{"id":10,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+	})
+
+	store := NewFileCursorStore(filepath.Join(t.TempDir(), "cursor.json"))
+	watcher := NewWatcher(client, store)
+	watcher.EntityTypes = []WatchEntityType{WatchEntityTypeCampaign, WatchEntityTypeAdGroup}
+
+	events, err := watcher.Poll(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	server.set(map[string]string{
+		"POST /campaigns/find": `{"data":[
+			{"id":1,"name":"Renamed","modificationTime":"2021-06-02T10:00:00.000"},
+			{"id":2,"modificationTime":"2021-06-02T11:00:00.000"}
+		]}`,
+		"POST /campaigns/1/adgroups/find": `{"data":[
+			{"id":10,"deleted":true,"modificationTime":"2021-06-02T10:00:00.000"},
+			{"id":11,"modificationTime":"2021-06-02T10:30:00.000"}
+		]}`,
+	})
+
+	events, err = watcher.Poll(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, server.lastBody("POST /campaigns/find"), `"values":["2021-06-01T10:00:00"]`)
+	assert.Contains(t, server.lastBody("POST /campaigns/find"), `"operator":"GREATER_THAN"`)
+
+	assert.Len(t, events, 4)
+	assert.Equal(t, ChangeTypeUpdated, events[0].Type)
+	assert.Equal(t, "Renamed", events[0].Campaign.Name)
+	assert.Equal(t, ChangeTypeCreated, events[1].Type)
+	assert.Equal(t, int64(2), events[1].ID)
+	assert.Equal(t, ChangeTypeDeleted, events[2].Type)
+	assert.Equal(t, WatchEntityTypeAdGroup, events[2].EntityType)
+	assert.Equal(t, int64(10), events[2].AdGroup.ID)
+	assert.Equal(t, ChangeTypeCreated, events[3].Type)
+	assert.Equal(t, int64(1), events[3].CampaignID)
+
+	// a restarted watcher resumes from the stored cursor and ignores entities it already reported
+	restarted := NewWatcher(client, store)
+	restarted.EntityTypes = watcher.EntityTypes
+
+	events, err = restarted.Poll(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Contains(t, server.lastBody("POST /campaigns/2/adgroups/find"), `"modificationTime"`)
+}
+
+func TestWatcherWatch(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newWatchServer()
+	defer closeServer()
+
+	server.set(map[string]string{
+		"POST /campaigns/7/adgroups/targetingkeywords/find": `{"data":[{"id":70,"adGroupId":3,"text":"photo","modificationTime":"2021-06-01T10:00:00.000"}]}`,
+	})
+
+	watcher := NewWatcher(client, NewMemoryCursorStore())
+	watcher.CampaignIDs = []int64{7}
+	watcher.EntityTypes = []WatchEntityType{WatchEntityTypeTargetingKeyword}
+	watcher.EmitInitial = true
+	watcher.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := watcher.Watch(ctx)
+
+	event := <-events
+	assert.Equal(t, ChangeTypeCreated, event.Type)
+	assert.Equal(t, "photo", event.Keyword.Text)
+	assert.Equal(t, int64(3), event.AdGroupID)
+
+	cancel()
+
+	for range events {
+	}
+
+	assert.NoError(t, <-errs)
+}
+
+func TestWatcherRunRedeliversUnhandledEvents(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newWatchServer()
+	defer closeServer()
+
+	server.set(map[string]string{
+		"POST /campaigns/find": `{"data":[{"id":1,"modificationTime":"2021-06-01T10:00:00.000"},{"id":2,"modificationTime":"2021-06-01T11:00:00.000"}]}`,
+	})
+
+	store := NewMemoryCursorStore()
+	watcher := NewWatcher(client, store)
+	watcher.EntityTypes = []WatchEntityType{WatchEntityTypeCampaign}
+	watcher.EmitInitial = true
+	watcher.Interval = time.Hour
+
+	errHandler := errors.New("handler failed")
+	err := watcher.Run(context.Background(), func(event *ChangeEvent) error {
+		if event.ID == 2 {
+			return errHandler
+		}
+
+		return nil
+	})
+	assert.ErrorIs(t, err, errHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ids []int64
+	err = watcher.Run(ctx, func(event *ChangeEvent) error {
+		ids = append(ids, event.ID)
+		if len(ids) == 2 {
+			cancel()
+		}
+
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []int64{1, 2}, ids, "the events of a failed poll are delivered again")
+
+	events, err := watcher.Poll(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, events, "the cursor is saved once every event was handled")
+}
+
+func TestWatcherPrunesState(t *testing.T) {
+	t.Parallel()
+
+	client, server, closeServer := newWatchServer()
+	defer closeServer()
+
+	server.set(map[string]string{
+		"POST /campaigns/find":                              `{"data":[{"id":1,"modificationTime":"2021-06-01T10:00:00.000"},{"id":2,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+		"POST /campaigns/1/adgroups/find":                   `{"data":[{"id":10,"modificationTime":"2021-06-01T10:00:00.000"},{"id":11,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+		"POST /campaigns/1/adgroups/targetingkeywords/find": `{"data":[{"id":100,"adGroupId":10,"modificationTime":"2021-06-01T10:00:00.000"},{"id":110,"adGroupId":11,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+		"POST /campaigns/2/adgroups/find":                   `{"data":[{"id":20,"modificationTime":"2021-06-01T10:00:00.000"}]}`,
+	})
+
+	store := NewMemoryCursorStore()
+	watcher := NewWatcher(client, store)
+	watcher.EntityTypes = []WatchEntityType{WatchEntityTypeCampaign, WatchEntityTypeAdGroup, WatchEntityTypeTargetingKeyword}
+
+	_, err := watcher.Poll(context.Background())
+	assert.NoError(t, err)
+
+	server.set(map[string]string{
+		"POST /campaigns/find":            `{"data":[{"id":2,"deleted":true,"modificationTime":"2021-06-02T10:00:00.000"}]}`,
+		"POST /campaigns/1/adgroups/find": `{"data":[{"id":10,"deleted":true,"modificationTime":"2021-06-02T10:00:00.000"}]}`,
+	})
+
+	events, err := watcher.Poll(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	state, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, state.Known[string(WatchEntityTypeCampaign)], 1)
+	assert.Len(t, state.Known["AD_GROUP/1"], 1)
+	assert.Equal(t, map[int64]*KnownEntity{110: {AdGroupID: 11, ModificationTime: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)}}, state.Known["TARGETING_KEYWORD/1"])
+	assert.NotContains(t, state.Known, "AD_GROUP/2", "the state of deleted campaigns is dropped")
+	assert.NotContains(t, state.Cursors, "AD_GROUP/2")
+}
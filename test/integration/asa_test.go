@@ -20,9 +20,12 @@ package integration
 
 import (
 	"fmt"
-	"github.com/gungoren/apple-search-ads-go/asa"
 	"os"
+	"path/filepath"
+	"testing"
 	"time"
+
+	"github.com/gungoren/apple-search-ads-go/asa"
 )
 
 const (
@@ -32,18 +35,57 @@ const (
 	envClientID       = "ASA_INTEGRATION_CID"
 	envPrivateKey     = "ASA_INTEGRATION_PRIVATE_KEY"
 	envPrivateKeyPath = "ASA_INTEGRATION_PRIVATE_KEY_PATH"
+	envMode           = "ASA_INTEGRATION_MODE"
+	cassetteDir       = "testdata/cassettes"
 )
 
-var (
-	client *asa.Client
-)
+// newTestClient creates the client of a test. ASA_INTEGRATION_MODE selects whether the test talks to the live API
+// (empty), records its interactions into testdata/cassettes (record) or replays them offline (replay).
+func newTestClient(t *testing.T) *asa.Client {
+	t.Helper()
+
+	mode := asa.CassetteMode(os.Getenv(envMode))
+	path := filepath.Join(cassetteDir, t.Name()+".json")
+
+	if mode == asa.CassetteModeReplay {
+		player, err := asa.NewCassetteTransport(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			if unplayed := player.Unplayed(); unplayed > 0 {
+				t.Errorf("%d recorded interactions were not replayed", unplayed)
+			}
+		})
+
+		return asa.NewClient(player.Client())
+	}
 
-func init() {
 	token := tokenConfig()
 	if token == nil {
-		return
+		t.Skip("no credentials provided")
+	}
+
+	if mode == "" {
+		return asa.NewClient(token.Client())
+	}
+
+	recorder, err := asa.NewCassetteTransport(path, mode)
+	if err != nil {
+		t.Fatal(err)
 	}
-	client = asa.NewClient(token.Client())
+
+	recorder.Transport = token.Transport
+	token.Transport = recorder
+
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return asa.NewClient(token.Client())
 }
 
 // TokenConfig creates the auth transport using the required information
@@ -26,6 +26,8 @@ import (
 )
 
 func TestListCampaigns(t *testing.T) {
+	client := newTestClient(t)
+
 	campaignListResponse, _, err := client.Campaigns.GetAllCampaigns(context.Background(), nil)
 	assert.NoError(t, err, "ListCampaigns responded with an error")
 	assert.NotEmpty(t, campaignListResponse.Campaigns, "ListCampaigns returned no campaignListResponse")